@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"simplenotes/cmd/internal/domain/policy"
 	"simplenotes/cmd/internal/domain/sqlite"
@@ -9,13 +10,16 @@ import (
 	"simplenotes/cmd/internal/http/handler"
 	mdlware "simplenotes/cmd/internal/http/middleware"
 	cognitoclient "simplenotes/cmd/internal/infrastructure/aws/cognito"
-	"simplenotes/cmd/internal/infrastructure/aws/storage"
+	"simplenotes/cmd/internal/infrastructure/aws/s3storage"
 	"simplenotes/cmd/internal/infrastructure/aws/websocket"
 	"simplenotes/cmd/internal/infrastructure/minhareceita"
+	"simplenotes/cmd/internal/infrastructure/storage"
 	"simplenotes/cmd/internal/service"
 	"simplenotes/cmd/internal/service/jobs"
 	"simplenotes/cmd/internal/utils"
 	"simplenotes/cmd/internal/utils/validators"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -29,6 +33,9 @@ import (
 
 const envVarsPrefix = "/simplenotes/prod/"
 
+// noteImportBodyLimit leaves room for the multipart form around the archive.
+const noteImportBodyLimit = "510M"
+
 func main() {
 	validate := validator.New()
 	registerValidators(validate)
@@ -61,7 +68,7 @@ func main() {
 	}
 
 	// --- Storage Init ---
-	s3Client, err := storage.NewStorageClient()
+	s3Client, err := initStorage()
 	if err != nil {
 		panic(err)
 	}
@@ -77,15 +84,29 @@ func main() {
 	receitaClient := minhareceita.NewClient()
 
 	// Domain & Service Wiring
-	userPolicy := policy.NewUserPolicy()
-	notePolicy := policy.NewNotePolicy()
-
 	connRepo := repository.NewConnectionRepository(db)
 	noteRepo := repository.NewNoteRepository(db)
+	revisionRepo := repository.NewNoteRevisionRepository(db)
+	textRepo := repository.NewNoteTextRepository(db)
+	fileRepo := repository.NewAttachmentRepository(db)
+	attachmentRepo := repository.NewNoteAttachmentRepository(db)
+	grantRepo := repository.NewNoteGrantRepository(db)
+	folderRepo := repository.NewFolderRepository(db)
+	tagRepo := repository.NewTagRepository(db)
+	linkRepo := repository.NewNoteLinkRepository(db)
+	renderRepo := repository.NewNoteRenderRepository(db)
+	importRepo := repository.NewNoteImportRepository(db)
+	templateRepo := repository.NewNoteTemplateRepository(db)
 	userRepo := repository.NewUserRepository(db)
 	compRepo := repository.NewCompanyRepository(db)
 	auditRepo := repository.NewAuditRepository(db)
 
+	userPolicy := policy.NewUserPolicy()
+	notePolicy := policy.NewNotePolicy(grantRepo)
+	folderPolicy := policy.NewFolderPolicy()
+	tagPolicy := policy.NewTagPolicy()
+	templatePolicy := policy.NewTemplatePolicy()
+
 	auditService, err := service.NewAuditService(db, auditRepo, nil)
 	if err != nil {
 		panic(err)
@@ -93,11 +114,19 @@ func main() {
 
 	connService := service.NewWebSocketService(connRepo, wsClient)
 	userService := service.NewUserService(db, userRepo, validate, connService, cogClient, auditService, userPolicy)
-	noteService := service.NewNoteService(db, noteRepo, userRepo, connService, s3Client, validate, auditService, notePolicy)
+	noteService := service.NewNoteService(db, noteRepo, revisionRepo, textRepo, fileRepo, attachmentRepo, grantRepo, folderRepo, tagRepo, linkRepo, renderRepo, userRepo, connService, s3Client, validate, auditService, notePolicy, folderPolicy)
+	folderService := service.NewFolderService(db, folderRepo, userRepo, noteService, connService, validate, auditService, folderPolicy)
+	tagService := service.NewTagService(db, tagRepo, noteService, validate, auditService, tagPolicy)
+	importService := service.NewNoteImportService(db, importRepo, noteService, validate)
+	templateService := service.NewTemplateService(db, templateRepo, noteService, validate, auditService, templatePolicy)
 	miscService := service.NewMiscService(receitaClient, compRepo, auditService)
 
 	connRoutes := handler.NewWSDefault(connService)
 	noteRoutes := handler.NewNoteDefault(noteService)
+	importRoutes := handler.NewImportDefault(importService)
+	folderRoutes := handler.NewFolderDefault(folderService)
+	tagRoutes := handler.NewTagDefault(tagService)
+	templateRoutes := handler.NewTemplateDefault(templateService)
 	userRoutes := handler.NewUserDefault(userService)
 	miscRoutes := handler.NewMiscRoute(miscService)
 	auditRoutes := handler.NewAuditDefault(auditService)
@@ -105,12 +134,18 @@ func main() {
 	// --- Background Jobs ---
 	connCleaner := jobs.NewConnectionCleaner(connService)
 	companyCleaner := jobs.NewCompanyCacheCleaner(compRepo)
+	trashPurger := jobs.NewNoteTrashPurger(noteService, loadTrashRetention())
+	textExtractor := jobs.NewNoteTextExtractor(noteService)
+	noteImporter := jobs.NewNoteImporter(importService)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	go connCleaner.Start(ctx)
 	go companyCleaner.Start(ctx)
+	go trashPurger.Start(ctx)
+	go textExtractor.Start(ctx)
+	go noteImporter.Start(ctx)
 
 	// --- Middleware Setup ---
 	authMiddleware := mdlware.NewAuthMiddleware(&mdlware.AuthMiddlewareConfig{
@@ -120,12 +155,21 @@ func main() {
 	// --- Server Setup ---
 	e := echo.New()
 
-	e.Use(middleware.CORS())
-	e.Use(middleware.BodyLimit("30M"))
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		// Browsers hide response headers from scripts unless they are exposed
+		ExposeHeaders: []string{"ETag"},
+	}))
+	e.Use(middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
+		Limit: "30M",
+		// Note archives are bigger, their route sets its own limit
+		Skipper: func(c echo.Context) bool {
+			return c.Path() == "/api/notes/imports"
+		},
+	}))
 	e.Use(middleware.Recover())
 
 	// --- Register Routes ---
-	registerRoutes(e, noteRoutes, userRoutes, miscRoutes, auditRoutes, connRoutes, authMiddleware)
+	registerRoutes(e, noteRoutes, importRoutes, folderRoutes, tagRoutes, templateRoutes, userRoutes, miscRoutes, auditRoutes, connRoutes, authMiddleware)
 
 	if err = e.Start(":7070"); err != nil {
 		panic(err)
@@ -136,6 +180,10 @@ func main() {
 func registerRoutes(
 	e *echo.Echo,
 	noteH *handler.DefaultNoteRoute,
+	importH *handler.DefaultImportRoute,
+	folderH *handler.DefaultFolderRoute,
+	tagH *handler.DefaultTagRoute,
+	templateH *handler.DefaultTemplateRoute,
 	userH *handler.DefaultUserRoute,
 	miscH *handler.DefaultMiscRoute,
 	auditH *handler.DefaultAuditRoute,
@@ -160,10 +208,61 @@ func registerRoutes(
 
 	// Notes
 	protected.GET("/notes", noteH.GetNotes)
+	protected.GET("/notes/search", noteH.SearchNotes)
+	protected.GET("/notes/trash", noteH.GetTrashedNotes)
+	protected.GET("/notes/text-extractions", noteH.GetTextExtractions)
+	protected.GET("/notes/export", noteH.ExportNotes)
+	protected.POST("/notes/bulk", noteH.BulkUpdateNotes)
+	protected.POST("/notes/from-template", templateH.CreateNoteFromTemplate)
+	protected.POST("/notes/imports", importH.StartNoteImport, middleware.BodyLimit(noteImportBodyLimit))
+	protected.GET("/notes/imports/:id", importH.GetNoteImport)
+	protected.POST("/notes/trash/:id/restore", noteH.RestoreNote)
+	protected.DELETE("/notes/trash/:id", noteH.PurgeNote)
 	protected.GET("/notes/:id", noteH.GetNote)
 	protected.POST("/notes", noteH.CreateNote)
 	protected.PATCH("/notes/:id", noteH.UpdateNote)
 	protected.DELETE("/notes/:id", noteH.DeleteNote)
+	protected.GET("/notes/:id/file", noteH.GetNoteFile)
+	protected.GET("/notes/:id/thumbnail", noteH.GetNoteThumbnail)
+	protected.PUT("/notes/:id/file", noteH.ReplaceNoteFile)
+	protected.GET("/files/:name", noteH.DownloadFile)
+	protected.GET("/notes/:id/revisions", noteH.GetNoteRevisions)
+	protected.GET("/notes/:id/revisions/diff", noteH.DiffNoteRevisions)
+	protected.GET("/notes/:id/revisions/:rev", noteH.GetNoteRevision)
+	protected.POST("/notes/:id/revisions/:rev/restore", noteH.RestoreNoteRevision)
+	protected.GET("/notes/:id/links", noteH.GetNoteLinks)
+	protected.GET("/notes/:id/backlinks", noteH.GetNoteBacklinks)
+	protected.GET("/notes/:id/render", noteH.RenderNote)
+	protected.GET("/notes/:id/attachments", noteH.GetNoteAttachments)
+	protected.POST("/notes/:id/attachments", noteH.AddNoteAttachment)
+	protected.PUT("/notes/:id/attachments/order", noteH.ReorderNoteAttachments)
+	protected.GET("/notes/:id/attachments/:attachmentId/file", noteH.GetNoteAttachmentFile)
+	protected.DELETE("/notes/:id/attachments/:attachmentId", noteH.DeleteNoteAttachment)
+	protected.GET("/notes/:id/collaborators", noteH.GetNoteCollaborators)
+	protected.PUT("/notes/:id/collaborators/:userId", noteH.PutNoteCollaborator)
+	protected.DELETE("/notes/:id/collaborators/:userId", noteH.DeleteNoteCollaborator)
+	protected.POST("/notes/:id/move", noteH.MoveNote)
+
+	// Folders
+	protected.GET("/folders", folderH.GetFolders)
+	protected.GET("/folders/:id", folderH.GetFolder)
+	protected.POST("/folders", folderH.CreateFolder)
+	protected.PATCH("/folders/:id", folderH.UpdateFolder)
+	protected.DELETE("/folders/:id", folderH.DeleteFolder)
+	protected.POST("/folders/:id/move", folderH.MoveFolder)
+
+	// Tags
+	protected.GET("/tags", tagH.GetTags)
+	protected.PATCH("/tags/:id", tagH.RenameTag)
+	protected.POST("/tags/:id/merge", tagH.MergeTag)
+	protected.DELETE("/tags/:id", tagH.DeleteTag)
+
+	// Templates
+	protected.GET("/templates", templateH.GetTemplates)
+	protected.GET("/templates/:id", templateH.GetTemplate)
+	protected.POST("/templates", templateH.CreateTemplate)
+	protected.PATCH("/templates/:id", templateH.UpdateTemplate)
+	protected.DELETE("/templates/:id", templateH.DeleteTemplate)
 
 	// Users
 	protected.GET("/users", userH.GetUsers)
@@ -221,6 +320,37 @@ func loadProdEnv() {
 	log.Debugf("loaded %d prod environment variables", len(out.Parameters))
 }
 
+// initStorage picks where attachments are stored from STORAGE_BACKEND:
+// "s3" (default) or "local", which keeps them under STORAGE_LOCAL_ROOT.
+func initStorage() (storage.Backend, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "s3":
+		return s3storage.NewStorageClient()
+	case "local":
+		root := os.Getenv("STORAGE_LOCAL_ROOT")
+		if root == "" {
+			root = "/data/storage"
+		}
+		return storage.NewLocalStorage(root)
+	default:
+		return nil, fmt.Errorf("invalid STORAGE_BACKEND: %q", backend)
+	}
+}
+
+// loadTrashRetention reads how many days trashed notes are kept before being purged.
+func loadTrashRetention() time.Duration {
+	raw := os.Getenv("NOTE_TRASH_RETENTION_DAYS")
+	if raw == "" {
+		return jobs.DefaultTrashRetention
+	}
+
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 1 {
+		panic(fmt.Errorf("invalid NOTE_TRASH_RETENTION_DAYS: %q", raw))
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
 func healthCheckRoute(c echo.Context) error {
 	return c.String(200, "OK")
 }
@@ -7,6 +7,7 @@ type AuditLogListRequest struct {
 	SubjectType *string
 	SubjectID   *string
 	ActionType  *string
+	BatchID     *int64
 }
 
 type AuditLogListResponse struct {
@@ -22,6 +23,7 @@ type AuditLogEventResponse struct {
 	SubjectID   string                    `json:"subject_id"`
 	Source      string                    `json:"source"`
 	OccurredAt  string                    `json:"occurred_at"`
+	BatchID     *string                   `json:"batch_id,omitempty"`
 	Changes     []*AuditLogChangeResponse `json:"changes"`
 }
 
@@ -0,0 +1,36 @@
+package contract
+
+type FolderResponse struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	ParentID    *int   `json:"parent_id"`
+	Visibility  string `json:"visibility"`
+	CreatedByID int    `json:"created_by_id"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+type FolderRequest struct {
+	Name       string `json:"name" validate:"required,min=2,max=80"`
+	ParentID   *int   `json:"parent_id" validate:"omitnil,min=1"`
+	Visibility string `json:"visibility" validate:"required,oneof=PUBLIC PRIVATE"`
+}
+
+type UpdateFolderRequest struct {
+	Name       *string `json:"name" validate:"omitnil,min=2,max=80"`
+	Visibility *string `json:"visibility" validate:"omitnil,oneof=PUBLIC PRIVATE"`
+}
+
+func (u *UpdateFolderRequest) IsEmpty() bool {
+	return u.Name == nil && u.Visibility == nil
+}
+
+// MoveFolderRequest moves a folder under another one, or to the root when ParentID is nil.
+type MoveFolderRequest struct {
+	ParentID *int `json:"parent_id" validate:"omitnil,min=1"`
+}
+
+// MoveNoteRequest moves a note into a folder, or out of any folder when FolderID is nil.
+type MoveNoteRequest struct {
+	FolderID *int `json:"folder_id" validate:"omitnil,min=1"`
+}
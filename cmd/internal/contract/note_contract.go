@@ -1,11 +1,185 @@
 package contract
 
-const MaxNoteFileSizeBytes = 30 * 1024 * 1024
+import "io"
+
+const (
+	MaxNoteFileSizeBytes   = 30 * 1024 * 1024
+	MaxNoteImageSizeBytes  = 10 * 1024 * 1024 // Images attached to notes, which markdown notes embed
+	MaxNoteAttachments     = 20
+	MaxNoteImportSizeBytes = 500 * 1024 * 1024
+)
 
 var ValidNoteFileTypes = []string{"pdf", "png", "jpg", "jpeg", "jfif", "webp", "gif", "mp4", "mp3"}
 
+// NoteFile is an uploaded note file, read while it is still being received.
+type NoteFile struct {
+	Filename string
+	Body     io.Reader
+}
+
 type NoteResponse struct {
-	ID          int      `json:"id"`
+	ID           int      `json:"id"`
+	Name         string   `json:"name"`
+	Content      string   `json:"content,omitempty"`
+	Tags         []string `json:"tags"`
+	Visibility   string   `json:"visibility"`
+	NoteType     string   `json:"note_type"`
+	ContentSize  int      `json:"content_size"`
+	ContentType  string   `json:"content_type,omitempty"`
+	ThumbnailURL string   `json:"thumbnail_url,omitempty"`
+	TextStatus   string   `json:"text_status,omitempty"`
+	CreatedByID  int      `json:"created_by_id"`
+	FolderID     *int     `json:"folder_id"`
+	CreatedAt    string   `json:"created_at"`
+	UpdatedAt    string   `json:"updated_at"`
+	DeletedAt    *string  `json:"deleted_at,omitempty"`
+	DeletedByID  *int     `json:"deleted_by_id,omitempty"`
+	Version      int      `json:"version"`
+}
+
+type NoteListRequest struct {
+	Limit         int
+	Before        *string
+	After         *string
+	Sort          *string
+	Order         *string
+	Tags          []string
+	TagsMode      *string
+	NoteType      *string
+	Visibility    *string
+	CreatedByID   *int
+	FolderID      *int
+	CreatedAfter  *int64
+	CreatedBefore *int64
+	UpdatedAfter  *int64
+	UpdatedBefore *int64
+}
+
+// NoteExportRequest selects the notes to export. Folders are exported along
+// with their subfolders.
+type NoteExportRequest struct {
+	Tags     []string
+	TagsMode *string
+	FolderID *int
+}
+
+// NoteExportManifest is the manifest.json of note archives. Paths are relative
+// to the root of the archive.
+type NoteExportManifest struct {
+	Version      int                `json:"version"`
+	ExportedAt   string             `json:"exported_at"`
+	ExportedByID int                `json:"exported_by_id"`
+	Notes        []*NoteExportEntry `json:"notes"`
+}
+
+type NoteExportEntry struct {
+	ID          int                     `json:"id"`
+	Name        string                  `json:"name"`
+	NoteType    string                  `json:"note_type"`
+	Visibility  string                  `json:"visibility"`
+	Tags        []string                `json:"tags"`
+	FolderID    *int                    `json:"folder_id"`
+	AuthorID    int                     `json:"author_id"`
+	AuthorName  string                  `json:"author_name"`
+	ContentType string                  `json:"content_type,omitempty"`
+	Path        string                  `json:"path"`
+	Missing     bool                    `json:"missing,omitempty"` // The file was not found in the storage
+	Attachments []*NoteExportAttachment `json:"attachments,omitempty"`
+	Version     int                     `json:"version"`
+	CreatedAt   string                  `json:"created_at"`
+	UpdatedAt   string                  `json:"updated_at"`
+}
+
+type NoteExportAttachment struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	ContentSize int    `json:"content_size"`
+	Path        string `json:"path"`
+	Missing     bool   `json:"missing,omitempty"`
+}
+
+// NoteImportRequest sets where the notes of an archive are created, and the
+// visibility of those the archive does not set one for.
+type NoteImportRequest struct {
+	Visibility string `json:"visibility" validate:"omitempty,oneof=PUBLIC PRIVATE"`
+	FolderID   *int   `json:"folder_id" validate:"omitnil,min=1"`
+}
+
+type NoteImportResponse struct {
+	ID         int                       `json:"id"`
+	Name       string                    `json:"name"`
+	Status     string                    `json:"status"`
+	Error      string                    `json:"error,omitempty"`
+	FolderID   *int                      `json:"folder_id"`
+	Visibility string                    `json:"visibility"`
+	Total      int                       `json:"total"`
+	Processed  int                       `json:"processed"`
+	Created    int                       `json:"created"`
+	Attached   int                       `json:"attached"`
+	Skipped    int                       `json:"skipped"`
+	Invalid    int                       `json:"invalid"`
+	Files      []*NoteImportFileResponse `json:"files,omitempty"`
+	CreatedAt  string                    `json:"created_at"`
+	UpdatedAt  string                    `json:"updated_at"`
+	FinishedAt *string                   `json:"finished_at,omitempty"`
+}
+
+type NoteImportFileResponse struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	NoteID *int   `json:"note_id,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type NoteListResponse struct {
+	Notes      []*NoteResponse `json:"notes"`
+	NextCursor *string         `json:"next_cursor,omitempty"`
+	PrevCursor *string         `json:"prev_cursor,omitempty"`
+}
+
+type NoteTrashRequest struct {
+	Limit  int
+	Before *string
+	After  *string
+}
+
+type TextExtractionListRequest struct {
+	Status string
+	Limit  int
+}
+
+type TextExtractionResponse struct {
+	NoteID        int     `json:"note_id"`
+	NoteName      string  `json:"note_name"`
+	Status        string  `json:"status"`
+	Attempts      int     `json:"attempts"`
+	LastError     string  `json:"last_error,omitempty"`
+	NextAttemptAt *string `json:"next_attempt_at,omitempty"`
+	UpdatedAt     string  `json:"updated_at"`
+}
+
+type NoteSearchRequest struct {
+	Query string
+	Limit int
+}
+
+type NoteSearchResponse struct {
+	Results []*NoteSearchResult `json:"results"`
+}
+
+// NoteSearchResult holds HTML-escaped fragments, where matched terms
+// are wrapped in <mark> tags.
+type NoteSearchResult struct {
+	*NoteResponse
+	NameHighlight string  `json:"name_highlight"`
+	Snippet       string  `json:"snippet"`
+	Score         float64 `json:"score"`
+}
+
+type NoteRevisionResponse struct {
+	NoteID      int      `json:"note_id"`
+	Revision    int      `json:"revision"`
 	Name        string   `json:"name"`
 	Content     string   `json:"content,omitempty"`
 	Tags        []string `json:"tags"`
@@ -14,13 +188,99 @@ type NoteResponse struct {
 	ContentSize int      `json:"content_size"`
 	CreatedByID int      `json:"created_by_id"`
 	CreatedAt   string   `json:"created_at"`
-	UpdatedAt   string   `json:"updated_at"`
+}
+
+type NoteRevisionDiffResponse struct {
+	NoteID int    `json:"note_id"`
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+	Diff   string `json:"diff"`
+}
+
+type NoteGrantResponse struct {
+	NoteID      int    `json:"note_id"`
+	UserID      int    `json:"user_id"`
+	Role        string `json:"role"`
+	GrantedByID int    `json:"granted_by_id"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+type NoteAttachmentResponse struct {
+	ID          int    `json:"id"`
+	NoteID      int    `json:"note_id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	ContentSize int    `json:"content_size"`
+	Position    int    `json:"position"`
+	URL         string `json:"url"`
+	CreatedByID int    `json:"created_by_id"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// NoteLinkResponse is a reference found in a MARKDOWN note. Links to notes the
+// caller cannot see are reported as broken.
+type NoteLinkResponse struct {
+	Text       string `json:"text"` // The reference as written, [[Note Name]] or /notes/:id
+	TargetID   *int   `json:"target_id"`
+	TargetName string `json:"target_name,omitempty"`
+	Broken     bool   `json:"broken"`
+}
+
+// NoteRenderResponse is the sanitized HTML of a MARKDOWN note at 'version'.
+type NoteRenderResponse struct {
+	NoteID  int                    `json:"note_id"`
+	Version int                    `json:"version"`
+	HTML    string                 `json:"html"`
+	TOC     []*NoteHeadingResponse `json:"toc,omitempty"`
+}
+
+type NoteHeadingResponse struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	ID    string `json:"id"`
+}
+
+// NoteAttachmentOrderRequest lists every attachment of the note in its new order.
+type NoteAttachmentOrderRequest struct {
+	AttachmentIDs []int `json:"attachment_ids" validate:"required,max=20,nodupes,dive,min=1"`
+}
+
+type NoteGrantRequest struct {
+	Role string `json:"role" validate:"required,oneof=VIEWER EDITOR"`
+}
+
+// NoteBulkRequest applies one operation to every note of NoteIDs. Tags are used
+// by ADD_TAGS and REMOVE_TAGS, Visibility by SET_VISIBILITY, and FolderID by
+// MOVE, where a nil FolderID moves the notes out of any folder.
+type NoteBulkRequest struct {
+	NoteIDs    []int    `json:"note_ids" validate:"required,min=1,max=200,nodupes,dive,min=1"`
+	Operation  string   `json:"operation" validate:"required,oneof=ADD_TAGS REMOVE_TAGS SET_VISIBILITY MOVE DELETE"`
+	Tags       []string `json:"tags" validate:"omitempty,max=50,nodupes,dive,required,min=2,max=30,nospaces"`
+	Visibility string   `json:"visibility" validate:"omitempty,oneof=PUBLIC PRIVATE"`
+	FolderID   *int     `json:"folder_id" validate:"omitnil,min=1"`
+}
+
+// NoteBulkResponse holds one result per requested note, in request order.
+// BatchID links the audit events of the operation, and is empty when nothing changed.
+type NoteBulkResponse struct {
+	BatchID string            `json:"batch_id,omitempty"`
+	Results []*NoteBulkResult `json:"results"`
+}
+
+type NoteBulkResult struct {
+	NoteID int           `json:"note_id"`
+	Status string        `json:"status"` // UPDATED, UNCHANGED, DELETED or FAILED
+	Code   int           `json:"code,omitempty"`
+	Error  string        `json:"error,omitempty"`
+	Note   *NoteResponse `json:"note,omitempty"`
 }
 
 type NoteRequest struct {
 	Name       string   `json:"name" validate:"required,min=2,max=80"`
 	Visibility string   `json:"visibility" validate:"required,oneof=PUBLIC PRIVATE"`
 	Tags       []string `json:"tags" validate:"required,max=50,nodupes,dive,required,min=2,max=30,nospaces"`
+	FolderID   *int     `json:"folder_id" validate:"omitnil,min=1"`
 }
 
 type TextNoteRequest struct {
@@ -29,10 +289,12 @@ type TextNoteRequest struct {
 	NoteType   string   `json:"note_type" validate:"required,oneof=MARKDOWN FLOWCHART"`
 	Visibility string   `json:"visibility" validate:"required,oneof=PUBLIC PRIVATE"`
 	Tags       []string `json:"tags" validate:"required,max=50,nodupes,dive,required,min=2,max=30,nospaces"`
+	FolderID   *int     `json:"folder_id" validate:"omitnil,min=1"`
 }
 
 type UpdateNoteRequest struct {
 	Name       *string  `form:"name" validate:"omitempty,min=2,max=80"`
+	Content    *string  `form:"content" validate:"omitnil,min=1,max=1000000"`
 	Visibility *string  `form:"visibility" validate:"omitempty,oneof=PUBLIC PRIVATE"`
 	Tags       []string `form:"tags" validate:"omitempty,max=50,nodupes,dive,required,min=2,max=30,nospaces"`
 }
@@ -0,0 +1,17 @@
+package contract
+
+import "slices"
+
+// Precondition holds the versions listed in the If-Match header of a request.
+// A nil Precondition, sent when the header is missing, always matches.
+type Precondition struct {
+	Any      bool
+	Versions []int
+}
+
+func (p *Precondition) Matches(version int) bool {
+	if p == nil || p.Any {
+		return true
+	}
+	return slices.Contains(p.Versions, version)
+}
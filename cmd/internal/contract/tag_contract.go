@@ -0,0 +1,22 @@
+package contract
+
+type TagResponse struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	NoteCount int    `json:"note_count"`
+}
+
+// TagListRequest lists the tags starting with Prefix, for autocompletion.
+type TagListRequest struct {
+	Prefix string
+	Limit  int
+}
+
+type RenameTagRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=30,nospaces"`
+}
+
+// MergeTagRequest merges a tag into TargetID, which every note of the tag gets instead.
+type MergeTagRequest struct {
+	TargetID int `json:"target_id" validate:"required,min=1"`
+}
@@ -0,0 +1,44 @@
+package contract
+
+// TemplateResponse lists in Variables the placeholders of the content that
+// callers fill in, built-in ones excluded.
+type TemplateResponse struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Content     string   `json:"content"`
+	Tags        []string `json:"tags"`
+	Variables   []string `json:"variables"`
+	CreatedByID int      `json:"created_by_id"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+}
+
+type TemplateRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=80"`
+	Description string   `json:"description" validate:"max=500"`
+	Content     string   `json:"content" validate:"required,max=1000000"`
+	Tags        []string `json:"tags" validate:"omitempty,max=50,nodupes,dive,required,min=2,max=30,nospaces"`
+}
+
+type UpdateTemplateRequest struct {
+	Name        *string  `json:"name" validate:"omitnil,min=2,max=80"`
+	Description *string  `json:"description" validate:"omitnil,max=500"`
+	Content     *string  `json:"content" validate:"omitnil,min=1,max=1000000"`
+	Tags        []string `json:"tags" validate:"omitempty,max=50,nodupes,dive,required,min=2,max=30,nospaces"`
+}
+
+func (u *UpdateTemplateRequest) IsEmpty() bool {
+	return u.Name == nil && u.Description == nil && u.Content == nil && u.Tags == nil
+}
+
+// NoteFromTemplateRequest creates a MARKDOWN note from a template. Notes get
+// the tags of the template when Tags is omitted.
+type NoteFromTemplateRequest struct {
+	TemplateID int               `json:"template_id" validate:"required,min=1"`
+	Name       string            `json:"name" validate:"required,min=2,max=80"`
+	Visibility string            `json:"visibility" validate:"required,oneof=PUBLIC PRIVATE"`
+	Tags       []string          `json:"tags" validate:"omitnil,max=50,nodupes,dive,required,min=2,max=30,nospaces"`
+	FolderID   *int              `json:"folder_id" validate:"omitnil,min=1"`
+	Variables  map[string]string `json:"variables" validate:"max=50,dive,max=1000"`
+}
@@ -64,6 +64,7 @@ type UserResponse struct {
 	Suspended  *bool        `json:"suspended,omitempty"`
 	CreatedAt  string       `json:"created_at"`
 	UpdatedAt  string       `json:"updated_at"`
+	Version    int          `json:"version"`
 }
 
 type UserLoginResponse struct {
@@ -12,6 +12,12 @@ const (
 	EventNoteCreated EventType = "NOTE_CREATED"
 	EventNoteUpdated EventType = "NOTE_UPDATED"
 	EventNoteDeleted EventType = "NOTE_DELETED"
+	EventNoteRevoked EventType = "NOTE_REVOKED"
+	EventNotesBulk   EventType = "NOTES_BULK_CHANGED"
+
+	EventFolderCreated EventType = "FOLDER_CREATED"
+	EventFolderUpdated EventType = "FOLDER_UPDATED"
+	EventFolderDeleted EventType = "FOLDER_DELETED"
 
 	EventUserCreated EventType = "USER_CREATED"
 	EventUserUpdated EventType = "USER_UPDATED"
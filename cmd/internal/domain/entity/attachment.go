@@ -0,0 +1,13 @@
+package entity
+
+// Attachment is a stored file, named after the SHA-256 of its bytes and shared
+// by every note uploading the same bytes. It is deleted along with its object
+// once no note references it anymore.
+type Attachment struct {
+	Hash      string `gorm:"primaryKey"`           // Hex SHA-256 of the file bytes
+	Filename  string `gorm:"not null;uniqueIndex"` // File name under storage.PathAttachments
+	Size      int    `gorm:"not null"`
+	Thumbnail string `gorm:"not null;default:''"` // File name under storage.PathThumbnails, empty when there is none
+	RefCount  int    `gorm:"not null;default:0"`  // How many notes point to the file, trashed ones included
+	CreatedAt int64  `gorm:"not null;autoCreateTime:false"`
+}
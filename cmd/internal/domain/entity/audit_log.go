@@ -4,27 +4,48 @@ type AuditSource string
 
 const (
 	AuditSourceHTTPAPI AuditSource = "HTTP_API"
+	AuditSourceSystem  AuditSource = "SYSTEM"
 )
 
 type AuditSubjectType string
 
 const (
-	AuditSubjectNote    AuditSubjectType = "NOTE"
-	AuditSubjectUser    AuditSubjectType = "USER"
-	AuditSubjectCompany AuditSubjectType = "COMPANY"
+	AuditSubjectNote     AuditSubjectType = "NOTE"
+	AuditSubjectUser     AuditSubjectType = "USER"
+	AuditSubjectCompany  AuditSubjectType = "COMPANY"
+	AuditSubjectFolder   AuditSubjectType = "FOLDER"
+	AuditSubjectTag      AuditSubjectType = "TAG"
+	AuditSubjectTemplate AuditSubjectType = "TEMPLATE"
 )
 
 type AuditActionType string
 
 const (
-	AuditActionNoteCreate    AuditActionType = "NOTE_CREATE"
-	AuditActionNoteUpdate    AuditActionType = "NOTE_UPDATE"
-	AuditActionNoteDelete    AuditActionType = "NOTE_DELETE"
-	AuditActionUserUpdate    AuditActionType = "USER_UPDATE"
-	AuditActionUserSuspend   AuditActionType = "USER_SUSPEND"
-	AuditActionUserUnsuspend AuditActionType = "USER_UNSUSPEND"
-	AuditActionUserDelete    AuditActionType = "USER_DELETE"
-	AuditActionCompanyLookup AuditActionType = "COMPANY_LOOKUP"
+	AuditActionNoteCreate            AuditActionType = "NOTE_CREATE"
+	AuditActionNoteUpdate            AuditActionType = "NOTE_UPDATE"
+	AuditActionNoteDelete            AuditActionType = "NOTE_DELETE"
+	AuditActionNoteRestore           AuditActionType = "NOTE_RESTORE"
+	AuditActionNotePurge             AuditActionType = "NOTE_PURGE"
+	AuditActionNoteRevisionRestore   AuditActionType = "NOTE_REVISION_RESTORE"
+	AuditActionNoteShare             AuditActionType = "NOTE_SHARE"
+	AuditActionNoteUnshare           AuditActionType = "NOTE_UNSHARE"
+	AuditActionNoteAttachmentAdd     AuditActionType = "NOTE_ATTACHMENT_ADD"
+	AuditActionNoteAttachmentReorder AuditActionType = "NOTE_ATTACHMENT_REORDER"
+	AuditActionNoteAttachmentDelete  AuditActionType = "NOTE_ATTACHMENT_DELETE"
+	AuditActionFolderCreate          AuditActionType = "FOLDER_CREATE"
+	AuditActionFolderUpdate          AuditActionType = "FOLDER_UPDATE"
+	AuditActionFolderDelete          AuditActionType = "FOLDER_DELETE"
+	AuditActionTagRename             AuditActionType = "TAG_RENAME"
+	AuditActionTagMerge              AuditActionType = "TAG_MERGE"
+	AuditActionTagDelete             AuditActionType = "TAG_DELETE"
+	AuditActionTemplateCreate        AuditActionType = "TEMPLATE_CREATE"
+	AuditActionTemplateUpdate        AuditActionType = "TEMPLATE_UPDATE"
+	AuditActionTemplateDelete        AuditActionType = "TEMPLATE_DELETE"
+	AuditActionUserUpdate            AuditActionType = "USER_UPDATE"
+	AuditActionUserSuspend           AuditActionType = "USER_SUSPEND"
+	AuditActionUserUnsuspend         AuditActionType = "USER_UNSUSPEND"
+	AuditActionUserDelete            AuditActionType = "USER_DELETE"
+	AuditActionCompanyLookup         AuditActionType = "COMPANY_LOOKUP"
 )
 
 type AuditValueType string
@@ -45,6 +66,7 @@ type AuditLogEvent struct {
 	SubjectID   string           `gorm:"not null;index:idx_audit_events_subject_id,priority:2"`
 	Source      AuditSource      `gorm:"not null"`
 	OccurredAt  int64            `gorm:"not null"`
+	BatchID     *int64           `gorm:"index"` // Shared by the events of one bulk operation
 
 	ActorUser *User             `gorm:"foreignKey:ActorUserID;references:ID"`
 	Changes   []*AuditLogChange `gorm:"foreignKey:EventID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
@@ -0,0 +1,13 @@
+package entity
+
+// Folder groups notes into a tree. Everything inside a PRIVATE folder,
+// subfolders and notes alike, is PRIVATE as well.
+type Folder struct {
+	ID          int            `gorm:"primaryKey"`
+	Name        string         `gorm:"not null"`
+	ParentID    *int           `gorm:"index"` // References: folders(id)
+	Visibility  NoteVisibility `gorm:"not null"`
+	CreatedByID int            `gorm:"not null;index"` // References: users(id)
+	CreatedAt   int64          `gorm:"not null"`
+	UpdatedAt   int64          `gorm:"not null;autoUpdateTime:false"`
+}
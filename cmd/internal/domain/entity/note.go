@@ -15,17 +15,29 @@ const (
 	VisibilityPrivate NoteVisibility = "PRIVATE"
 )
 
+// Note listing sorts and paginates by (column, id). SQLite secondary indexes
+// already end with the rowid, so single-column indexes cover those keysets.
 type Note struct {
-	ID          int            `gorm:"primaryKey"`
-	Name        string         `gorm:"not null"`
-	Content     string         `gorm:"not null"`
-	CreatedByID int            `gorm:"not null"` // References: users(id)
-	Tags        string         `gorm:"not null"`
-	NoteType    NoteType       `gorm:"not null"`
-	ContentSize int            `gorm:"not null"`
-	Visibility  NoteVisibility `gorm:"not null"`
-	CreatedAt   int64          `gorm:"not null"`
-	UpdatedAt   int64          `gorm:"not null;autoUpdateTime:false"`
+	ID          int                  `gorm:"primaryKey"`
+	Name        string               `gorm:"not null;index"`
+	Content     string               `gorm:"not null"`
+	CreatedByID int                  `gorm:"not null;index"` // References: users(id)
+	Tags        string               `gorm:"not null"`       // Space separated copy of note_tags, kept for search and revisions
+	NoteType    NoteType             `gorm:"not null;index"`
+	ContentSize int                  `gorm:"not null"`
+	ContentHash string               `gorm:"not null;default:''"`       // Hex SHA-256 of the text content or attachment bytes
+	ContentType string               `gorm:"not null;default:''"`       // MIME type of the attachment, sniffed from its first bytes
+	Thumbnail   string               `gorm:"not null;default:''"`       // File name under storage.PathThumbnails, empty when there is none
+	TextStatus  TextExtractionStatus `gorm:"not null;default:'';index"` // State of the attachment text extraction, empty when the type has no text
+	Visibility  NoteVisibility       `gorm:"not null;index"`
+	FolderID    *int                 `gorm:"index"` // References: folders(id)
+	CreatedAt   int64                `gorm:"not null;index"`
+	UpdatedAt   int64                `gorm:"not null;autoUpdateTime:false;index"`
+	Version     int                  `gorm:"not null;default:1"` // Bumped on every save, exposed as the ETag
+
+	// Trashed notes keep their row until they are restored or purged
+	DeletedAt   *int64 `gorm:"index"`
+	DeletedByID *int   // References: users(id)
 
 	// Relations
 	CreatedBy User `gorm:"foreignKey:CreatedByID;references:ID"`
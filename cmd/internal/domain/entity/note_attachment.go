@@ -0,0 +1,16 @@
+package entity
+
+// NoteAttachment is a file attached to a note of any type, besides the main
+// file of REFERENCE notes. Notes uploading the same bytes share the stored file.
+type NoteAttachment struct {
+	ID          int    `gorm:"primaryKey"`
+	NoteID      int    `gorm:"not null;index:idx_note_attachments_note_position,priority:1"` // References: notes(id)
+	Position    int    `gorm:"not null;index:idx_note_attachments_note_position,priority:2"`
+	Name        string `gorm:"not null"`       // File name given by the uploader
+	Filename    string `gorm:"not null;index"` // File name under storage.PathAttachments
+	ContentType string `gorm:"not null"`
+	ContentSize int    `gorm:"not null"`
+	ContentHash string `gorm:"not null"`
+	CreatedByID int    `gorm:"not null"` // References: users(id)
+	CreatedAt   int64  `gorm:"not null"`
+}
@@ -0,0 +1,22 @@
+package entity
+
+type NoteGrantRole string
+
+const (
+	// NoteRoleViewer allows seeing a note, even when it is PRIVATE.
+	NoteRoleViewer NoteGrantRole = "VIEWER"
+
+	// NoteRoleEditor allows seeing and updating a note.
+	NoteRoleEditor NoteGrantRole = "EDITOR"
+)
+
+// NoteGrant shares a single note with a single user.
+type NoteGrant struct {
+	ID          int           `gorm:"primaryKey"`
+	NoteID      int           `gorm:"not null;uniqueIndex:idx_note_grants_note_user,priority:1"`       // References: notes(id)
+	UserID      int           `gorm:"not null;uniqueIndex:idx_note_grants_note_user,priority:2;index"` // References: users(id)
+	Role        NoteGrantRole `gorm:"not null"`
+	GrantedByID int           `gorm:"not null"` // References: users(id)
+	CreatedAt   int64         `gorm:"not null"`
+	UpdatedAt   int64         `gorm:"not null;autoUpdateTime:false"`
+}
@@ -0,0 +1,51 @@
+package entity
+
+type NoteImportStatus string
+
+const (
+	NoteImportPending NoteImportStatus = "PENDING"
+	NoteImportRunning NoteImportStatus = "RUNNING"
+	NoteImportDone    NoteImportStatus = "DONE"
+	NoteImportFailed  NoteImportStatus = "FAILED"
+)
+
+type NoteImportFileStatus string
+
+const (
+	NoteImportFileCreated  NoteImportFileStatus = "CREATED"  // Became a note
+	NoteImportFileAttached NoteImportFileStatus = "ATTACHED" // Embedded by a note, and attached to it
+	NoteImportFileSkipped  NoteImportFileStatus = "SKIPPED"
+	NoteImportFileInvalid  NoteImportFileStatus = "INVALID"
+)
+
+// NoteImport is a ZIP archive of notes uploaded by a user, imported in the
+// background. Counters are updated after every file, so clients can follow it.
+type NoteImport struct {
+	ID          int              `gorm:"primaryKey"`
+	CreatedByID int              `gorm:"not null;index"` // References: users(id)
+	Name        string           `gorm:"not null"`       // File name given by the uploader
+	Archive     string           `gorm:"not null"`       // Object key under storage.PathImports, removed once done
+	FolderID    *int             // References: folders(id), where the notes are created
+	Visibility  NoteVisibility   `gorm:"not null"` // Of notes whose archive does not set one
+	Status      NoteImportStatus `gorm:"not null;index"`
+	Error       string           `gorm:"not null;default:''"` // Why the whole import FAILED
+	Total       int              `gorm:"not null;default:0"`  // Files in the archive, known once it is opened
+	Processed   int              `gorm:"not null;default:0"`
+	Created     int              `gorm:"not null;default:0"`
+	Attached    int              `gorm:"not null;default:0"`
+	Skipped     int              `gorm:"not null;default:0"`
+	Invalid     int              `gorm:"not null;default:0"`
+	CreatedAt   int64            `gorm:"not null"`
+	UpdatedAt   int64            `gorm:"not null;autoUpdateTime:false"`
+	FinishedAt  *int64
+}
+
+// NoteImportFile is the outcome of one file of an import archive.
+type NoteImportFile struct {
+	ID       int                  `gorm:"primaryKey"`
+	ImportID int                  `gorm:"not null;index"` // References: note_imports(id)
+	Path     string               `gorm:"not null"`       // Path inside the archive
+	Status   NoteImportFileStatus `gorm:"not null"`
+	NoteID   *int                 // References: notes(id), the note created or attached to
+	Reason   string               `gorm:"not null;default:''"` // Why it was skipped or invalid
+}
@@ -0,0 +1,10 @@
+package entity
+
+// NoteLink is a reference found in the content of a MARKDOWN note, either to
+// a note name ([[Note Name]]) or to a note path (/notes/:id).
+type NoteLink struct {
+	ID         int    `gorm:"primaryKey"`
+	SourceID   int    `gorm:"not null;index"`      // References: notes(id)
+	TargetID   *int   `gorm:"index"`               // References: notes(id), nil while no note has the linked name
+	TargetName string `gorm:"not null;default:''"` // Name as written in [[Note Name]] links, empty for path links
+}
@@ -0,0 +1,11 @@
+package entity
+
+// NoteRender caches the HTML rendering of a MARKDOWN note at one version.
+type NoteRender struct {
+	NoteID    int    `gorm:"primaryKey;autoIncrement:false"` // References: notes(id)
+	Version   int    `gorm:"not null"`                       // Version of the note that was rendered
+	Renderer  int    `gorm:"not null"`                       // markdown.Version used to render it
+	HTML      string `gorm:"not null"`
+	TOC       string `gorm:"not null"` // Headings as a JSON array
+	CreatedAt int64  `gorm:"not null"`
+}
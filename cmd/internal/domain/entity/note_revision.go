@@ -0,0 +1,17 @@
+package entity
+
+// NoteRevision is an immutable snapshot of a note, taken every time the
+// note is created, updated or restored.
+type NoteRevision struct {
+	ID          int            `gorm:"primaryKey"`
+	NoteID      int            `gorm:"not null;uniqueIndex:idx_note_revisions_note_revision,priority:1"` // References: notes(id)
+	Revision    int            `gorm:"not null;uniqueIndex:idx_note_revisions_note_revision,priority:2"`
+	Name        string         `gorm:"not null"`
+	Content     string         `gorm:"not null"`
+	Tags        string         `gorm:"not null"`
+	NoteType    NoteType       `gorm:"not null"`
+	ContentSize int            `gorm:"not null"`
+	Visibility  NoteVisibility `gorm:"not null"`
+	CreatedByID int            `gorm:"not null"` // References: users(id), the author of this revision
+	CreatedAt   int64          `gorm:"not null"`
+}
@@ -0,0 +1,15 @@
+package entity
+
+// NoteTemplate is a markdown skeleton new notes are rendered from. Its content
+// holds {{name}} placeholders, filled in when a note is created from it.
+// Templates are shared by every user.
+type NoteTemplate struct {
+	ID          int    `gorm:"primaryKey"`
+	Name        string `gorm:"not null"`
+	Description string `gorm:"not null;default:''"`
+	Content     string `gorm:"not null"`
+	Tags        string `gorm:"not null;default:''"` // Given to notes created without tags
+	CreatedByID int    `gorm:"not null;index"`      // References: users(id)
+	CreatedAt   int64  `gorm:"not null"`
+	UpdatedAt   int64  `gorm:"not null;autoUpdateTime:false"`
+}
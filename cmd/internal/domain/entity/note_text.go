@@ -0,0 +1,20 @@
+package entity
+
+type TextExtractionStatus string
+
+const (
+	TextExtractionPending TextExtractionStatus = "PENDING"
+	TextExtractionDone    TextExtractionStatus = "DONE"
+	TextExtractionFailed  TextExtractionStatus = "FAILED"
+)
+
+// NoteText holds the text extracted from the attachment of a REFERENCE note,
+// along with the state of the extraction attempts.
+type NoteText struct {
+	NoteID        int    `gorm:"primaryKey;autoIncrement:false"` // References: notes(id)
+	Content       string `gorm:"not null;default:''"`
+	Attempts      int    `gorm:"not null;default:0"`
+	LastError     string `gorm:"not null;default:''"`
+	NextAttemptAt int64  `gorm:"not null;index"`
+	UpdatedAt     int64  `gorm:"not null;autoUpdateTime:false"`
+}
@@ -46,6 +46,10 @@ const (
 	// PermissionPerformLookup allows users to call endpoints outside the
 	// general platform scope. Like CNPJ/places/IP lookups.
 	PermissionPerformLookup
+
+	// PermissionManageTemplates allows creating, editing and deleting note templates.
+	// Using a template only requires PermissionCreateNotes.
+	PermissionManageTemplates
 )
 
 // Has checks if the permission bitmask contains ALL bits
@@ -0,0 +1,14 @@
+package entity
+
+// Tag is a single lowercase tag shared by every note using it.
+type Tag struct {
+	ID        int    `gorm:"primaryKey"`
+	Name      string `gorm:"not null;uniqueIndex"`
+	CreatedAt int64  `gorm:"not null"`
+}
+
+// NoteTag links a note to one of its tags.
+type NoteTag struct {
+	NoteID int `gorm:"primaryKey;autoIncrement:false"`       // References: notes(id)
+	TagID  int `gorm:"primaryKey;autoIncrement:false;index"` // References: tags(id)
+}
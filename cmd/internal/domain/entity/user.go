@@ -12,4 +12,5 @@ type User struct {
 	Suspended     bool       `gorm:"not null;default:false"`
 	CreatedAt     int64      `gorm:"not null"`
 	UpdatedAt     int64      `gorm:"not null;autoUpdateTime:false"`
+	Version       int        `gorm:"not null;default:1"` // Bumped on every save, exposed as the ETag
 }
@@ -45,6 +45,55 @@ func (e *NoteDeleted) GetType() contract.EventType {
 	return contract.EventNoteDeleted
 }
 
+// NoteRevoked tells a user they can no longer see a note, as if it had been deleted for them.
+type NoteRevoked struct {
+	NoteID int `json:"id"`
+}
+
+func (e *NoteRevoked) GetType() contract.EventType {
+	return contract.EventNoteRevoked
+}
+
+// NotesBulkChanged coalesces the changes of one bulk operation. Revoked lists
+// the notes the user could see before the operation, but not anymore.
+type NotesBulkChanged struct {
+	BatchID string                   `json:"batch_id"`
+	Updated []*contract.NoteResponse `json:"updated"`
+	Revoked []int                    `json:"revoked"`
+	Deleted []int                    `json:"deleted"`
+}
+
+func (e *NotesBulkChanged) GetType() contract.EventType {
+	return contract.EventNotesBulk
+}
+
+type FolderCreated struct {
+	*contract.FolderResponse
+}
+
+func (e *FolderCreated) GetType() contract.EventType {
+	return contract.EventFolderCreated
+}
+
+type FolderUpdated struct {
+	*contract.FolderResponse
+}
+
+func (e *FolderUpdated) GetType() contract.EventType {
+	return contract.EventFolderUpdated
+}
+
+// FolderDeleted is also sent to users who can no longer see a folder.
+// The content of a deleted folder moves to its parent, given by ParentID.
+type FolderDeleted struct {
+	FolderID int  `json:"id"`
+	ParentID *int `json:"parent_id"`
+}
+
+func (e *FolderDeleted) GetType() contract.EventType {
+	return contract.EventFolderDeleted
+}
+
 type UserCreated struct {
 	*contract.UserResponse
 }
@@ -0,0 +1,57 @@
+package policy
+
+import (
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils/apierror"
+)
+
+const createNotes = entity.PermissionCreateNotes
+
+// FolderPolicy encapsulates all business rules for folder manipulation.
+// Folders reuse the note permissions, owners can always manage their own folders.
+type FolderPolicy struct{}
+
+func NewFolderPolicy() *FolderPolicy {
+	return &FolderPolicy{}
+}
+
+func (p *FolderPolicy) CanSee(folder *entity.Folder, actor *entity.User) apierror.ErrorResponse {
+	if folder == nil {
+		return apierror.NotFoundError
+	}
+
+	if folder.Visibility == entity.VisibilityPrivate && folder.CreatedByID != actor.ID &&
+		!actor.Permissions.HasEffective(seeHiddenNotes) {
+		return apierror.NotFoundError
+	}
+	return nil
+}
+
+func (p *FolderPolicy) CanCreate(actor *entity.User) apierror.ErrorResponse {
+	if !actor.Permissions.HasEffective(createNotes) {
+		return permError(createNotes)
+	}
+	return nil
+}
+
+func (p *FolderPolicy) CanUpdate(folder *entity.Folder, actor *entity.User) apierror.ErrorResponse {
+	if apierr := p.CanSee(folder, actor); apierr != nil {
+		return apierr
+	}
+
+	if folder.CreatedByID != actor.ID && !actor.Permissions.HasEffective(editNotes) {
+		return permError(editNotes)
+	}
+	return nil
+}
+
+func (p *FolderPolicy) CanDelete(folder *entity.Folder, actor *entity.User) apierror.ErrorResponse {
+	if apierr := p.CanSee(folder, actor); apierr != nil {
+		return apierr
+	}
+
+	if folder.CreatedByID != actor.ID && !actor.Permissions.HasEffective(deleteNotes) {
+		return permError(deleteNotes)
+	}
+	return nil
+}
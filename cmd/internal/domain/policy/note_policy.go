@@ -3,6 +3,8 @@ package policy
 import (
 	"simplenotes/cmd/internal/domain/entity"
 	"simplenotes/cmd/internal/utils/apierror"
+
+	"github.com/labstack/gommon/log"
 )
 
 const (
@@ -11,12 +13,22 @@ const (
 	deleteNotes    = entity.PermissionDeleteNotes
 )
 
+// NoteGrantLookup finds the grant 'userID' holds on a note, or nil if there is none.
+type NoteGrantLookup interface {
+	FindGrant(noteID, userID int) (*entity.NoteGrant, error)
+}
+
 // NotePolicy encapsulates all business rules for note manipulation.
 // It returns apierror.ErrorResponse directly for seamless integration with handlers.
-type NotePolicy struct{}
+//
+// Owners can always see, update and delete their notes. Other users need
+// either the global note permissions or a grant on the note.
+type NotePolicy struct {
+	grants NoteGrantLookup
+}
 
-func NewNotePolicy() *NotePolicy {
-	return &NotePolicy{}
+func NewNotePolicy(grants NoteGrantLookup) *NotePolicy {
+	return &NotePolicy{grants: grants}
 }
 
 func (p *NotePolicy) CanSee(note *entity.Note, actor *entity.User) apierror.ErrorResponse {
@@ -24,23 +36,93 @@ func (p *NotePolicy) CanSee(note *entity.Note, actor *entity.User) apierror.Erro
 		return apierror.NotFoundError
 	}
 
-	if !actor.Permissions.HasEffective(seeHiddenNotes) &&
-		note.Visibility == entity.VisibilityPrivate {
+	if note.Visibility != entity.VisibilityPrivate || isNoteOwner(note, actor) ||
+		actor.Permissions.HasEffective(seeHiddenNotes) {
+		return nil
+	}
+
+	grant, apierr := p.findGrant(note, actor)
+	if apierr != nil {
+		return apierr
+	}
+
+	if grant == nil {
 		return apierror.NotFoundError // ^^
 	}
 	return nil
 }
 
 func (p *NotePolicy) CanUpdate(note *entity.Note, actor *entity.User) apierror.ErrorResponse {
+	if note == nil {
+		return apierror.NotFoundError
+	}
+
+	if isNoteOwner(note, actor) {
+		return nil
+	}
+
+	grant, apierr := p.findGrant(note, actor)
+	if apierr != nil {
+		return apierr
+	}
+
+	if grant == nil && note.Visibility == entity.VisibilityPrivate &&
+		!actor.Permissions.HasEffective(seeHiddenNotes) {
+		return apierror.NotFoundError
+	}
+
+	if grant != nil && grant.Role == entity.NoteRoleEditor {
+		return nil
+	}
+
 	if !actor.Permissions.HasEffective(editNotes) {
 		return permError(editNotes)
 	}
-	return p.CanSee(note, actor)
+	return nil
 }
 
 func (p *NotePolicy) CanDelete(note *entity.Note, actor *entity.User) apierror.ErrorResponse {
-	if !actor.Permissions.HasEffective(deleteNotes) {
+	if apierr := p.CanSee(note, actor); apierr != nil {
+		return apierr
+	}
+
+	// Grants never allow deleting somebody else's note
+	if !isNoteOwner(note, actor) && !actor.Permissions.HasEffective(deleteNotes) {
 		return permError(deleteNotes)
 	}
-	return p.CanSee(note, actor)
+	return nil
+}
+
+// CanManageGrants checks if 'actor' can share 'note' with other users or revoke their access.
+func (p *NotePolicy) CanManageGrants(note *entity.Note, actor *entity.User) apierror.ErrorResponse {
+	if apierr := p.CanSee(note, actor); apierr != nil {
+		return apierr
+	}
+
+	if !isNoteOwner(note, actor) && !actor.Permissions.Has(admin) {
+		return forbiddenError("Only the note owner can manage its collaborators")
+	}
+	return nil
+}
+
+// CanSeeTextExtractions checks if 'actor' can inspect the text extraction
+// state of every note, failures included.
+func (p *NotePolicy) CanSeeTextExtractions(actor *entity.User) apierror.ErrorResponse {
+	if !actor.Permissions.Has(admin) {
+		return permError(admin)
+	}
+	return nil
+}
+
+func (p *NotePolicy) findGrant(note *entity.Note, actor *entity.User) (*entity.NoteGrant, apierror.ErrorResponse) {
+	grant, err := p.grants.FindGrant(note.ID, actor.ID)
+	if err != nil {
+		log.Errorf("failed to fetch grant of user %d on note %d: %v", actor.ID, note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+	return grant, nil
+}
+
+func isNoteOwner(note *entity.Note, actor *entity.User) bool {
+	return note.CreatedByID == actor.ID
 }
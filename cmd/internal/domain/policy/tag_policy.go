@@ -0,0 +1,21 @@
+package policy
+
+import (
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils/apierror"
+)
+
+// TagPolicy encapsulates all business rules for tag manipulation.
+// Tags are shared by every note, so only administrators can change them.
+type TagPolicy struct{}
+
+func NewTagPolicy() *TagPolicy {
+	return &TagPolicy{}
+}
+
+func (p *TagPolicy) CanManage(actor *entity.User) apierror.ErrorResponse {
+	if !actor.Permissions.Has(admin) {
+		return forbiddenError("Only administrators can manage tags")
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package policy
+
+import (
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils/apierror"
+)
+
+const manageTemplates = entity.PermissionManageTemplates
+
+// TemplatePolicy encapsulates all business rules for note templates.
+// Every user can see templates, managing them takes its own permission.
+type TemplatePolicy struct{}
+
+func NewTemplatePolicy() *TemplatePolicy {
+	return &TemplatePolicy{}
+}
+
+func (p *TemplatePolicy) CanManage(actor *entity.User) apierror.ErrorResponse {
+	if !actor.Permissions.HasEffective(manageTemplates) {
+		return permError(manageTemplates)
+	}
+	return nil
+}
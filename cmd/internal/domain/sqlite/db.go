@@ -25,6 +25,19 @@ func Init() (*gorm.DB, error) {
 		&entity.AuditLogEvent{},
 		&entity.AuditLogChange{},
 		&entity.Note{},
+		&entity.NoteRevision{},
+		&entity.NoteGrant{},
+		&entity.Folder{},
+		&entity.Tag{},
+		&entity.NoteTag{},
+		&entity.NoteText{},
+		&entity.Attachment{},
+		&entity.NoteAttachment{},
+		&entity.NoteLink{},
+		&entity.NoteRender{},
+		&entity.NoteImport{},
+		&entity.NoteImportFile{},
+		&entity.NoteTemplate{},
 		&entity.User{},
 		&entity.Connection{},
 		&entity.Company{},
@@ -34,6 +47,22 @@ func Init() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	if err = MigrateNoteSearch(db); err != nil {
+		return nil, err
+	}
+
+	if err = MigrateNoteTags(db); err != nil {
+		return nil, err
+	}
+
+	if err = MigrateNoteTexts(db); err != nil {
+		return nil, err
+	}
+
+	if err = MigrateNoteLinks(db); err != nil {
+		return nil, err
+	}
+
 	sqlDB, _ := db.DB()
 	sqlDB.SetMaxOpenConns(1)
 	sqlDB.SetMaxIdleConns(1)
@@ -0,0 +1,39 @@
+package sqlite
+
+import (
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils/notelinks"
+
+	"gorm.io/gorm"
+)
+
+// MigrateNoteLinks parses the links of the MARKDOWN notes saved before links
+// were tracked. It only runs while no link exists, which is the case right
+// after the note_links table is created.
+func MigrateNoteLinks(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var linkCount int64
+		if err := tx.Model(&entity.NoteLink{}).Count(&linkCount).Error; err != nil {
+			return err
+		}
+
+		if linkCount > 0 {
+			return nil
+		}
+
+		links := repository.NewNoteLinkRepository(tx)
+		var notes []*entity.Note
+		return tx.
+			Select("id", "content", "created_by_id").
+			Where("note_type = ?", string(entity.NoteTypeMarkdown)).
+			FindInBatches(&notes, 100, func(batch *gorm.DB, _ int) error {
+				for _, note := range notes {
+					if err := links.ReplaceNoteLinksWithDB(tx, note, notelinks.Parse(note.Content)); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+	})
+}
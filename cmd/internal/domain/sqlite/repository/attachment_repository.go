@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"simplenotes/cmd/internal/domain/entity"
+)
+
+type DefaultAttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) *DefaultAttachmentRepository {
+	return &DefaultAttachmentRepository{db: db}
+}
+
+func (d *DefaultAttachmentRepository) FindByHash(hash string) (*entity.Attachment, error) {
+	var attachment entity.Attachment
+	err := d.db.Where("hash = ?", hash).First(&attachment).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// AcquireWithDB adds a reference to the attachment, inserting it with a single
+// reference when it is not stored yet.
+func (d *DefaultAttachmentRepository) AcquireWithDB(db *gorm.DB, attachment *entity.Attachment) error {
+	if db == nil {
+		db = d.db
+	}
+
+	attachment.RefCount = 1
+	return db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "hash"}},
+			DoUpdates: clause.Assignments(map[string]any{"ref_count": gorm.Expr("attachments.ref_count + 1")}),
+		}).
+		Create(attachment).Error
+}
+
+// AcquireExistingWithDB adds a reference to the attachment stored under 'hash',
+// and returns false when there is no such attachment anymore.
+func (d *DefaultAttachmentRepository) AcquireExistingWithDB(db *gorm.DB, hash string) (bool, error) {
+	if db == nil {
+		db = d.db
+	}
+
+	result := db.
+		Model(&entity.Attachment{}).
+		Where("hash = ?", hash).
+		Update("ref_count", gorm.Expr("ref_count + 1"))
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseWithDB removes a reference to the attachment named 'filename' and
+// returns how many are left. The attachment row goes with its last reference.
+// Files uploaded before attachments were shared have no row, and are reported
+// as having no reference left.
+func (d *DefaultAttachmentRepository) ReleaseWithDB(db *gorm.DB, filename string) (int, error) {
+	if db == nil {
+		db = d.db
+	}
+
+	var attachment entity.Attachment
+	err := db.Where("filename = ?", filename).First(&attachment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if attachment.RefCount <= 1 {
+		return 0, db.Delete(&attachment).Error
+	}
+
+	err = db.
+		Model(&attachment).
+		Update("ref_count", gorm.Expr("ref_count - 1")).Error
+	return attachment.RefCount - 1, err
+}
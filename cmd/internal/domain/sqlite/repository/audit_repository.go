@@ -12,6 +12,7 @@ type AuditLogFilter struct {
 	SubjectType *entity.AuditSubjectType
 	SubjectID   *string
 	ActionType  *entity.AuditActionType
+	BatchID     *int64
 }
 
 type DefaultAuditRepository struct {
@@ -47,6 +48,9 @@ func (d *DefaultAuditRepository) List(filter *AuditLogFilter) ([]*entity.AuditLo
 	if filter.ActionType != nil {
 		query = query.Where("action_type = ?", *filter.ActionType)
 	}
+	if filter.BatchID != nil {
+		query = query.Where("batch_id = ?", *filter.BatchID)
+	}
 
 	if err := query.Find(&events).Error; err != nil {
 		return nil, err
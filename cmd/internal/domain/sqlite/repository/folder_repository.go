@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"simplenotes/cmd/internal/domain/entity"
+)
+
+type DefaultFolderRepository struct {
+	db *gorm.DB
+}
+
+func NewFolderRepository(db *gorm.DB) *DefaultFolderRepository {
+	return &DefaultFolderRepository{db: db}
+}
+
+// FindAllVisible returns every folder 'viewerID' may see. PRIVATE folders
+// are only included for their creator, unless 'withPrivate' is set.
+func (d *DefaultFolderRepository) FindAllVisible(viewerID int, withPrivate bool) ([]*entity.Folder, error) {
+	var folders []*entity.Folder
+	query := d.db.Order("name ASC").Order("id ASC")
+	if !withPrivate {
+		query = query.Where("visibility != ? OR created_by_id = ?", string(entity.VisibilityPrivate), viewerID)
+	}
+
+	if err := query.Find(&folders).Error; err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (d *DefaultFolderRepository) FindByID(id int) (*entity.Folder, error) {
+	var folder entity.Folder
+	err := d.db.First(&folder, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// FindSubtreeWithDB returns the folder along with all of its descendants.
+func (d *DefaultFolderRepository) FindSubtreeWithDB(db *gorm.DB, id int) ([]*entity.Folder, error) {
+	if db == nil {
+		db = d.db
+	}
+
+	var folders []*entity.Folder
+	err := db.Raw(`
+		WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM folders WHERE id = ?
+			UNION
+			SELECT folders.id FROM folders INNER JOIN subtree ON folders.parent_id = subtree.id
+		)
+		SELECT * FROM folders WHERE id IN (SELECT id FROM subtree)`, id).
+		Scan(&folders).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (d *DefaultFolderRepository) SaveWithDB(db *gorm.DB, folder *entity.Folder) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.Save(folder).Error
+}
+
+func (d *DefaultFolderRepository) DeleteWithDB(db *gorm.DB, folder *entity.Folder) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.Delete(folder).Error
+}
+
+// ReparentChildrenWithDB moves the direct subfolders of 'folderID' under 'parentID'.
+func (d *DefaultFolderRepository) ReparentChildrenWithDB(db *gorm.DB, folderID int, parentID *int) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.
+		Model(&entity.Folder{}).
+		Where("parent_id = ?", folderID).
+		Update("parent_id", parentID).Error
+}
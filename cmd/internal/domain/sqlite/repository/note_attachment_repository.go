@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"simplenotes/cmd/internal/domain/entity"
+)
+
+type DefaultNoteAttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewNoteAttachmentRepository(db *gorm.DB) *DefaultNoteAttachmentRepository {
+	return &DefaultNoteAttachmentRepository{db: db}
+}
+
+func (d *DefaultNoteAttachmentRepository) FindByID(noteID, id int) (*entity.NoteAttachment, error) {
+	var attachment entity.NoteAttachment
+	err := d.db.
+		Where("note_id = ? AND id = ?", noteID, id).
+		First(&attachment).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// FindAllByNoteIDWithDB returns the attachments of the note in their display order.
+func (d *DefaultNoteAttachmentRepository) FindAllByNoteIDWithDB(db *gorm.DB, noteID int) ([]*entity.NoteAttachment, error) {
+	if db == nil {
+		db = d.db
+	}
+
+	var attachments []*entity.NoteAttachment
+	err := db.
+		Where("note_id = ?", noteID).
+		Order("position ASC, id ASC").
+		Find(&attachments).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (d *DefaultNoteAttachmentRepository) SaveWithDB(db *gorm.DB, attachment *entity.NoteAttachment) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.Save(attachment).Error
+}
+
+func (d *DefaultNoteAttachmentRepository) DeleteWithDB(db *gorm.DB, attachment *entity.NoteAttachment) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.Delete(attachment).Error
+}
+
+func (d *DefaultNoteAttachmentRepository) DeleteByNoteIDWithDB(db *gorm.DB, noteID int) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.
+		Where("note_id = ?", noteID).
+		Delete(&entity.NoteAttachment{}).Error
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"simplenotes/cmd/internal/domain/entity"
+)
+
+type DefaultNoteGrantRepository struct {
+	db *gorm.DB
+}
+
+func NewNoteGrantRepository(db *gorm.DB) *DefaultNoteGrantRepository {
+	return &DefaultNoteGrantRepository{db: db}
+}
+
+func (d *DefaultNoteGrantRepository) FindGrant(noteID, userID int) (*entity.NoteGrant, error) {
+	var grant entity.NoteGrant
+	err := d.db.
+		Where("note_id = ? AND user_id = ?", noteID, userID).
+		First(&grant).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+func (d *DefaultNoteGrantRepository) FindAllByNoteID(noteID int) ([]*entity.NoteGrant, error) {
+	var grants []*entity.NoteGrant
+	err := d.db.
+		Where("note_id = ?", noteID).
+		Order("id ASC").
+		Find(&grants).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+func (d *DefaultNoteGrantRepository) SaveWithDB(db *gorm.DB, grant *entity.NoteGrant) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.Save(grant).Error
+}
+
+func (d *DefaultNoteGrantRepository) DeleteWithDB(db *gorm.DB, grant *entity.NoteGrant) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.Delete(grant).Error
+}
+
+func (d *DefaultNoteGrantRepository) DeleteByNoteIDWithDB(db *gorm.DB, noteID int) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.
+		Where("note_id = ?", noteID).
+		Delete(&entity.NoteGrant{}).Error
+}
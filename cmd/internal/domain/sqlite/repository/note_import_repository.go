@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"simplenotes/cmd/internal/domain/entity"
+)
+
+type DefaultNoteImportRepository struct {
+	db *gorm.DB
+}
+
+func NewNoteImportRepository(db *gorm.DB) *DefaultNoteImportRepository {
+	return &DefaultNoteImportRepository{db: db}
+}
+
+func (d *DefaultNoteImportRepository) FindByID(id int) (*entity.NoteImport, error) {
+	var noteImport entity.NoteImport
+	err := d.db.First(&noteImport, id).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &noteImport, nil
+}
+
+// FindNext returns the oldest import waiting to run. Running imports not updated
+// since 'staleBefore' are returned as well, their worker is assumed to be gone.
+func (d *DefaultNoteImportRepository) FindNext(staleBefore int64) (*entity.NoteImport, error) {
+	var noteImport entity.NoteImport
+	err := d.db.
+		Where("status = ?", string(entity.NoteImportPending)).
+		Or("status = ? AND updated_at < ?", string(entity.NoteImportRunning), staleBefore).
+		Order("id ASC").
+		First(&noteImport).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &noteImport, nil
+}
+
+// FindAllFiles returns the outcome of every file processed so far, in processing order.
+func (d *DefaultNoteImportRepository) FindAllFiles(importID int) ([]*entity.NoteImportFile, error) {
+	var files []*entity.NoteImportFile
+	err := d.db.
+		Where("import_id = ?", importID).
+		Order("id ASC").
+		Find(&files).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (d *DefaultNoteImportRepository) SaveWithDB(db *gorm.DB, noteImport *entity.NoteImport) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.Save(noteImport).Error
+}
+
+func (d *DefaultNoteImportRepository) SaveFileWithDB(db *gorm.DB, file *entity.NoteImportFile) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.Save(file).Error
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils/notelinks"
+
+	"gorm.io/gorm"
+)
+
+type DefaultNoteLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewNoteLinkRepository(db *gorm.DB) *DefaultNoteLinkRepository {
+	return &DefaultNoteLinkRepository{db: db}
+}
+
+// FindAllBySourceID returns the links of the note in the order they appear.
+func (d *DefaultNoteLinkRepository) FindAllBySourceID(sourceID int) ([]*entity.NoteLink, error) {
+	var links []*entity.NoteLink
+	err := d.db.
+		Where("source_id = ?", sourceID).
+		Order("id ASC").
+		Find(&links).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// ReplaceNoteLinksWithDB stores exactly the references in 'refs' as links of the note.
+// Names point to the oldest active note having them that the owner of the source
+// can see, ignoring case, links of a note to itself are dropped.
+func (d *DefaultNoteLinkRepository) ReplaceNoteLinksWithDB(db *gorm.DB, source *entity.Note, refs []notelinks.Ref) error {
+	if db == nil {
+		db = d.db
+	}
+
+	sourceID := source.ID
+	err := db.
+		Where("source_id = ?", sourceID).
+		Delete(&entity.NoteLink{}).Error
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		link := &entity.NoteLink{SourceID: sourceID, TargetName: ref.Name}
+		if ref.Name == "" {
+			link.TargetID = &ref.NoteID
+		} else {
+			var ids []int
+			query := db.
+				Model(&entity.Note{}).
+				Where("LOWER(name) = LOWER(?) AND deleted_at IS NULL", ref.Name)
+			err = whereVisibleTo(query, "notes", source.CreatedByID).
+				Order("id ASC").
+				Limit(1).
+				Pluck("id", &ids).Error
+			if err != nil {
+				return err
+			}
+			if len(ids) > 0 {
+				link.TargetID = &ids[0]
+			}
+		}
+
+		if link.TargetID != nil && *link.TargetID == sourceID {
+			continue
+		}
+		if err = db.Create(link).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AdoptWithDB points the broken [[name]] links of other notes to the note, when
+// their owners can see it. Private notes are only seen by their owner and the
+// users holding a grant on them.
+func (d *DefaultNoteLinkRepository) AdoptWithDB(db *gorm.DB, note *entity.Note) error {
+	if db == nil {
+		db = d.db
+	}
+
+	query := db.
+		Model(&entity.NoteLink{}).
+		Where("target_id IS NULL AND target_name != '' AND LOWER(target_name) = LOWER(?)", note.Name).
+		Where("source_id != ?", note.ID)
+
+	if note.Visibility == entity.VisibilityPrivate {
+		query = query.Where(
+			"source_id IN (SELECT id FROM notes WHERE created_by_id = ? OR created_by_id IN (SELECT user_id FROM note_grants WHERE note_id = ?))",
+			note.CreatedByID, note.ID,
+		)
+	}
+	return query.Update("target_id", note.ID).Error
+}
+
+// RenameTargetWithDB sets the name written in the [[name]] links of 'sourceIDs'
+// pointing to the note. The other [[name]] links to it still have the old name
+// in their text, so they are broken instead.
+func (d *DefaultNoteLinkRepository) RenameTargetWithDB(db *gorm.DB, noteID int, name string, sourceIDs []int) error {
+	if db == nil {
+		db = d.db
+	}
+
+	if len(sourceIDs) > 0 {
+		err := db.
+			Model(&entity.NoteLink{}).
+			Where("target_id = ? AND target_name != ''", noteID).
+			Where("source_id IN ?", sourceIDs).
+			Update("target_name", name).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	query := db.
+		Model(&entity.NoteLink{}).
+		Where("target_id = ? AND target_name != ''", noteID)
+	if len(sourceIDs) > 0 {
+		query = query.Where("source_id NOT IN ?", sourceIDs)
+	}
+	return query.Update("target_id", nil).Error
+}
+
+// DeleteByNoteIDWithDB deletes the links of the note, and breaks the [[name]]
+// links pointing to it so another note with that name can take them over.
+// Path links keep pointing to the missing note.
+func (d *DefaultNoteLinkRepository) DeleteByNoteIDWithDB(db *gorm.DB, noteID int) error {
+	if db == nil {
+		db = d.db
+	}
+
+	err := db.
+		Where("source_id = ?", noteID).
+		Delete(&entity.NoteLink{}).Error
+	if err != nil {
+		return err
+	}
+
+	return db.
+		Model(&entity.NoteLink{}).
+		Where("target_id = ? AND target_name != ''", noteID).
+		Update("target_id", nil).Error
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"simplenotes/cmd/internal/domain/entity"
+)
+
+type DefaultNoteRenderRepository struct {
+	db *gorm.DB
+}
+
+func NewNoteRenderRepository(db *gorm.DB) *DefaultNoteRenderRepository {
+	return &DefaultNoteRenderRepository{db: db}
+}
+
+func (d *DefaultNoteRenderRepository) FindByNoteID(noteID int) (*entity.NoteRender, error) {
+	var render entity.NoteRender
+	err := d.db.Where("note_id = ?", noteID).First(&render).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &render, nil
+}
+
+// Save inserts the render, or replaces the one cached for its note.
+func (d *DefaultNoteRenderRepository) Save(render *entity.NoteRender) error {
+	return d.db.
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(render).Error
+}
+
+func (d *DefaultNoteRenderRepository) DeleteByNoteIDWithDB(db *gorm.DB, noteID int) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.
+		Where("note_id = ?", noteID).
+		Delete(&entity.NoteRender{}).Error
+}
@@ -4,8 +4,76 @@ import (
 	"errors"
 	"gorm.io/gorm"
 	"simplenotes/cmd/internal/domain/entity"
+	"slices"
+	"strings"
+	"unicode"
 )
 
+// Delimiters wrapped around matched terms in search highlights and snippets.
+// They are control characters so callers can escape the text before
+// replacing them with actual markup.
+const (
+	SearchMatchStart = "\x02"
+	SearchMatchEnd   = "\x03"
+)
+
+// NoteSearchHit is a note matched by a full-text search, along with its
+// highlighted name, the best matching fragment and its bm25 score.
+type NoteSearchHit struct {
+	entity.Note
+	NameHighlight string
+	Snippet       string
+	Score         float64
+}
+
+type NoteSortField string
+
+const (
+	NoteSortUpdatedAt NoteSortField = "updated_at"
+	NoteSortCreatedAt NoteSortField = "created_at"
+	NoteSortName      NoteSortField = "name"
+	NoteSortDeletedAt NoteSortField = "deleted_at"
+)
+
+// NoteCursor is a keyset position: the sort column value and the note id
+// used as a tiebreaker.
+type NoteCursor struct {
+	Value any
+	ID    int
+}
+
+type NoteListFilter struct {
+	Limit      int
+	Sort       NoteSortField
+	Descending bool
+
+	// ViewerID is the user listing the notes. PRIVATE notes are only
+	// listed when they created them or hold a grant on them, unless
+	// WithPrivate is set.
+	ViewerID    int
+	WithPrivate bool
+
+	// Trashed lists the notes in the trash instead of the active ones
+	Trashed bool
+
+	// After returns notes following the cursor in the sort order,
+	// Before returns the ones preceding it. At most one should be set.
+	After  *NoteCursor
+	Before *NoteCursor
+
+	Tags          []string
+	MatchAllTags  bool
+	NoteType      *entity.NoteType
+	Visibility    *entity.NoteVisibility
+	CreatedByID   *int
+	FolderID      *int
+	FolderIDs     []int // Notes inside any of these folders
+	CreatedAfter  *int64
+	CreatedBefore *int64
+	UpdatedAfter  *int64
+	UpdatedBefore *int64
+}
+
 type DefaultNoteRepository struct {
 	db *gorm.DB
 }
@@ -18,9 +86,10 @@ func (d *DefaultNoteRepository) FindAll(withPrivate bool) ([]*entity.Note, error
 	var notes []*entity.Note
 	var err error
 	if withPrivate {
-		err = d.db.Find(&notes).Error
+		err = d.db.Where("deleted_at IS NULL").Find(&notes).Error
 	} else {
 		err = d.db.
+			Where("deleted_at IS NULL").
 			Where("visibility != ?", string(entity.VisibilityPrivate)).
 			Find(&notes).Error
 	}
@@ -31,9 +100,210 @@ func (d *DefaultNoteRepository) FindAll(withPrivate bool) ([]*entity.Note, error
 	return notes, nil
 }
 
+// List returns a page of notes matching the filter, in the requested sort order.
+func (d *DefaultNoteRepository) List(filter *NoteListFilter) ([]*entity.Note, error) {
+	var notes []*entity.Note
+	sort := string(filter.Sort)
+
+	// Pages before the cursor are read backwards from it, then flipped
+	descending := filter.Descending
+	if filter.Before != nil {
+		descending = !descending
+	}
+
+	direction, comparison := "ASC", ">"
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	query := d.db.
+		Order(sort + " " + direction).
+		Order("id " + direction).
+		Limit(filter.Limit)
+
+	cursor := filter.After
+	if filter.Before != nil {
+		cursor = filter.Before
+	}
+	if cursor != nil {
+		query = query.Where("("+sort+", id) "+comparison+" (?, ?)", cursor.Value, cursor.ID)
+	}
+
+	if filter.Trashed {
+		query = query.Where("deleted_at IS NOT NULL")
+	} else {
+		query = query.Where("deleted_at IS NULL")
+	}
+	if !filter.WithPrivate {
+		query = whereVisibleTo(query, "notes", filter.ViewerID)
+	}
+	if filter.NoteType != nil {
+		query = query.Where("note_type = ?", *filter.NoteType)
+	}
+	if filter.Visibility != nil {
+		query = query.Where("visibility = ?", *filter.Visibility)
+	}
+	if filter.CreatedByID != nil {
+		query = query.Where("created_by_id = ?", *filter.CreatedByID)
+	}
+	if filter.FolderID != nil {
+		query = query.Where("folder_id = ?", *filter.FolderID)
+	}
+	if len(filter.FolderIDs) > 0 {
+		query = query.Where("folder_id IN ?", filter.FolderIDs)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.UpdatedAfter != nil {
+		query = query.Where("updated_at >= ?", *filter.UpdatedAfter)
+	}
+	if filter.UpdatedBefore != nil {
+		query = query.Where("updated_at < ?", *filter.UpdatedBefore)
+	}
+	if len(filter.Tags) > 0 {
+		query = whereTags(query, filter.Tags, filter.MatchAllTags)
+	}
+
+	if err := query.Find(&notes).Error; err != nil {
+		return nil, err
+	}
+
+	if filter.Before != nil {
+		slices.Reverse(notes)
+	}
+	return notes, nil
+}
+
+// whereTags narrows the notes down through the tags column of the FTS index,
+// then checks the exact tags, since the tokenizer may split a single tag
+// into several terms.
+func whereTags(query *gorm.DB, tags []string, matchAll bool) *gorm.DB {
+	joiner := " OR "
+	if matchAll {
+		joiner = " AND "
+	}
+
+	terms := make([]string, 0, len(tags))
+	exact := make([]string, len(tags))
+	args := make([]any, len(tags))
+	for i, tag := range tags {
+		if strings.IndexFunc(tag, isTokenRune) >= 0 {
+			terms = append(terms, `tags : "`+strings.ReplaceAll(tag, `"`, `""`)+`"`)
+		}
+		exact[i] = "(' ' || tags || ' ') LIKE ? ESCAPE '\\'"
+		args[i] = "% " + escapeLike(tag) + " %"
+	}
+
+	// A tag without any letter or digit cannot be searched in the index
+	if len(terms) == len(tags) {
+		query = query.Where("id IN (SELECT rowid FROM notes_fts WHERE notes_fts MATCH ?)", strings.Join(terms, joiner))
+	}
+	return query.Where("("+strings.Join(exact, joiner)+")", args...)
+}
+
+// whereVisibleTo keeps PRIVATE notes only when 'viewerID' created them or was granted access to them.
+func whereVisibleTo(query *gorm.DB, table string, viewerID int) *gorm.DB {
+	return query.Where(
+		"("+table+".visibility != ? OR "+table+".created_by_id = ? OR "+table+".id IN (SELECT note_id FROM note_grants WHERE user_id = ?))",
+		string(entity.VisibilityPrivate), viewerID, viewerID,
+	)
+}
+
+func isTokenRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+func escapeLike(value string) string {
+	return likeReplacer.Replace(value)
+}
+
+var likeReplacer = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Search runs the FTS5 'match' expression against the notes index, best matches
+// first. Name matches weigh more than tag matches, which weigh more than content.
+// PRIVATE notes follow the same rules as NoteListFilter.ViewerID.
+func (d *DefaultNoteRepository) Search(match string, viewerID int, withPrivate bool, limit int) ([]*NoteSearchHit, error) {
+	var hits []*NoteSearchHit
+	query := d.db.
+		Table("notes_fts").
+		Select(
+			"notes.*, highlight(notes_fts, 0, ?, ?) AS name_highlight, snippet(notes_fts, -1, ?, ?, '…', 16) AS snippet, bm25(notes_fts, 10.0, 5.0, 1.0) AS score",
+			SearchMatchStart, SearchMatchEnd, SearchMatchStart, SearchMatchEnd,
+		).
+		Joins("INNER JOIN notes ON notes.id = notes_fts.rowid").
+		Where("notes_fts MATCH ?", match).
+		Where("notes.deleted_at IS NULL").
+		Order("score ASC").
+		Limit(limit)
+
+	if !withPrivate {
+		query = whereVisibleTo(query, "notes", viewerID)
+	}
+
+	if err := query.Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// FindByID finds an active note, trashed notes are ignored.
 func (d *DefaultNoteRepository) FindByID(id int) (*entity.Note, error) {
 	var note entity.Note
-	err := d.db.First(&note, id).Error
+	err := d.db.Where("deleted_at IS NULL").First(&note, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// FindByName finds an active note of the user named 'name', compared
+// case-insensitively, in the folder or at the root when 'folderID' is nil.
+func (d *DefaultNoteRepository) FindByName(createdByID int, folderID *int, name string) (*entity.Note, error) {
+	query := d.db.Where("deleted_at IS NULL AND created_by_id = ? AND LOWER(name) = LOWER(?)", createdByID, name)
+	if folderID != nil {
+		query = query.Where("folder_id = ?", *folderID)
+	} else {
+		query = query.Where("folder_id IS NULL")
+	}
+
+	var note entity.Note
+	err := query.Order("id ASC").First(&note).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// FindAllByAttachment returns the active REFERENCE notes whose attachment is
+// named 'filename'. Notes uploading the same bytes share their attachment.
+func (d *DefaultNoteRepository) FindAllByAttachment(filename string) ([]*entity.Note, error) {
+	var notes []*entity.Note
+	err := d.db.
+		Where("deleted_at IS NULL AND note_type = ? AND content = ?", string(entity.NoteTypeReference), filename).
+		Order("id ASC").
+		Find(&notes).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+func (d *DefaultNoteRepository) FindTrashedByID(id int) (*entity.Note, error) {
+	var note entity.Note
+	err := d.db.Where("deleted_at IS NOT NULL").First(&note, id).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
@@ -44,15 +314,119 @@ func (d *DefaultNoteRepository) FindByID(id int) (*entity.Note, error) {
 	return &note, nil
 }
 
+// FindTrashedBefore returns up to 'limit' notes trashed before the 'cutoff', oldest first.
+func (d *DefaultNoteRepository) FindTrashedBefore(cutoff int64, limit int) ([]*entity.Note, error) {
+	var notes []*entity.Note
+	err := d.db.
+		Where("deleted_at < ?", cutoff).
+		Order("deleted_at ASC").
+		Limit(limit).
+		Find(&notes).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// FindAllByFolderIDsWithDB returns the notes inside any of the folders, including trashed ones.
+func (d *DefaultNoteRepository) FindAllByFolderIDsWithDB(db *gorm.DB, folderIDs []int) ([]*entity.Note, error) {
+	if db == nil {
+		db = d.db
+	}
+
+	var notes []*entity.Note
+	err := db.
+		Where("folder_id IN ?", folderIDs).
+		Find(&notes).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// FindAllByTagIDWithDB returns the notes using the tag, including trashed ones.
+func (d *DefaultNoteRepository) FindAllByTagIDWithDB(db *gorm.DB, tagID int) ([]*entity.Note, error) {
+	if db == nil {
+		db = d.db
+	}
+
+	var notes []*entity.Note
+	err := db.
+		Where("id IN (SELECT note_id FROM note_tags WHERE tag_id = ?)", tagID).
+		Order("id ASC").
+		Find(&notes).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// FindAllByIDs returns the active notes among 'ids'.
+func (d *DefaultNoteRepository) FindAllByIDs(ids []int) ([]*entity.Note, error) {
+	var notes []*entity.Note
+	err := d.db.
+		Where("id IN ? AND deleted_at IS NULL", ids).
+		Find(&notes).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// FindAllLinkingTo returns the active notes linking to the note, last updated first.
+// PRIVATE notes follow the same rules as NoteListFilter.ViewerID.
+func (d *DefaultNoteRepository) FindAllLinkingTo(targetID, viewerID int, withPrivate bool) ([]*entity.Note, error) {
+	query := d.db.
+		Where("id IN (SELECT source_id FROM note_links WHERE target_id = ?)", targetID).
+		Where("deleted_at IS NULL")
+
+	if !withPrivate {
+		query = whereVisibleTo(query, "notes", viewerID)
+	}
+
+	var notes []*entity.Note
+	err := query.
+		Order("updated_at DESC, id DESC").
+		Find(&notes).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// FindAllNamingWithDB returns the notes having [[name]] links to the note, including trashed ones.
+func (d *DefaultNoteRepository) FindAllNamingWithDB(db *gorm.DB, targetID int) ([]*entity.Note, error) {
+	if db == nil {
+		db = d.db
+	}
+
+	var notes []*entity.Note
+	err := db.
+		Where("id IN (SELECT source_id FROM note_links WHERE target_id = ? AND target_name != '')", targetID).
+		Order("id ASC").
+		Find(&notes).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
 func (d *DefaultNoteRepository) Save(note *entity.Note) error {
-	return d.db.Save(note).Error
+	return saveVersioned(d.db, note, note.ID, &note.Version)
 }
 
+// SaveWithDB returns ErrStaleVersion when the note changed since it was loaded.
 func (d *DefaultNoteRepository) SaveWithDB(db *gorm.DB, note *entity.Note) error {
 	if db == nil {
 		db = d.db
 	}
-	return db.Save(note).Error
+	return saveVersioned(db, note, note.ID, &note.Version)
 }
 
 func (d *DefaultNoteRepository) Delete(note *entity.Note) error {
@@ -65,3 +439,24 @@ func (d *DefaultNoteRepository) DeleteWithDB(db *gorm.DB, note *entity.Note) err
 	}
 	return db.Delete(note).Error
 }
+
+// UpdateTextStatusWithDB sets the text extraction status of the note, as long
+// as it still points to 'attachment'. The status is part of the note, so the
+// version is bumped when it changes.
+func (d *DefaultNoteRepository) UpdateTextStatusWithDB(db *gorm.DB, noteID int, attachment string, status entity.TextExtractionStatus) (bool, error) {
+	if db == nil {
+		db = d.db
+	}
+	result := db.
+		Model(&entity.Note{}).
+		Where("id = ? AND content = ?", noteID, attachment).
+		Updates(map[string]any{
+			"text_status": string(status),
+			"version":     gorm.Expr("CASE WHEN text_status = ? THEN version ELSE version + 1 END", string(status)),
+		})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
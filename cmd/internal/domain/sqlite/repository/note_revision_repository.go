@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"simplenotes/cmd/internal/domain/entity"
+)
+
+type DefaultNoteRevisionRepository struct {
+	db *gorm.DB
+}
+
+func NewNoteRevisionRepository(db *gorm.DB) *DefaultNoteRevisionRepository {
+	return &DefaultNoteRevisionRepository{db: db}
+}
+
+// FindAllByNoteID returns all revisions of a note, newest first.
+// The content column is not loaded, as it can get too big for listings.
+func (d *DefaultNoteRevisionRepository) FindAllByNoteID(noteID int) ([]*entity.NoteRevision, error) {
+	var revisions []*entity.NoteRevision
+	err := d.db.
+		Omit("content").
+		Where("note_id = ?", noteID).
+		Order("revision DESC").
+		Find(&revisions).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (d *DefaultNoteRevisionRepository) FindByRevision(noteID, revision int) (*entity.NoteRevision, error) {
+	var rev entity.NoteRevision
+	err := d.db.
+		Where("note_id = ? AND revision = ?", noteID, revision).
+		First(&rev).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func (d *DefaultNoteRevisionRepository) ExistsByNoteIDWithDB(db *gorm.DB, noteID int) (bool, error) {
+	if db == nil {
+		db = d.db
+	}
+
+	var exists bool
+	err := db.
+		Raw("SELECT EXISTS(SELECT 1 FROM note_revisions WHERE note_id = ?)", noteID).
+		Scan(&exists).Error
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// CreateWithDB assigns the next revision number of the note and persists it.
+// It must run inside the same transaction that saved the note itself.
+func (d *DefaultNoteRevisionRepository) CreateWithDB(db *gorm.DB, rev *entity.NoteRevision) error {
+	if db == nil {
+		db = d.db
+	}
+
+	var latest int
+	err := db.Model(&entity.NoteRevision{}).
+		Where("note_id = ?", rev.NoteID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&latest).Error
+	if err != nil {
+		return err
+	}
+
+	rev.Revision = latest + 1
+	return db.Create(rev).Error
+}
+
+func (d *DefaultNoteRevisionRepository) DeleteByNoteIDWithDB(db *gorm.DB, noteID int) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.
+		Where("note_id = ?", noteID).
+		Delete(&entity.NoteRevision{}).Error
+}
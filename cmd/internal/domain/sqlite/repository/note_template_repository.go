@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"simplenotes/cmd/internal/domain/entity"
+)
+
+type DefaultNoteTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewNoteTemplateRepository(db *gorm.DB) *DefaultNoteTemplateRepository {
+	return &DefaultNoteTemplateRepository{db: db}
+}
+
+func (d *DefaultNoteTemplateRepository) FindAll() ([]*entity.NoteTemplate, error) {
+	var templates []*entity.NoteTemplate
+	err := d.db.
+		Order("name ASC").
+		Order("id ASC").
+		Find(&templates).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (d *DefaultNoteTemplateRepository) FindByID(id int) (*entity.NoteTemplate, error) {
+	var template entity.NoteTemplate
+	err := d.db.First(&template, id).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// FindByName matches 'name' ignoring case.
+func (d *DefaultNoteTemplateRepository) FindByName(name string) (*entity.NoteTemplate, error) {
+	var template entity.NoteTemplate
+	err := d.db.
+		Where("LOWER(name) = LOWER(?)", name).
+		First(&template).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (d *DefaultNoteTemplateRepository) SaveWithDB(db *gorm.DB, template *entity.NoteTemplate) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.Save(template).Error
+}
+
+func (d *DefaultNoteTemplateRepository) DeleteWithDB(db *gorm.DB, template *entity.NoteTemplate) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.Delete(template).Error
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"simplenotes/cmd/internal/domain/entity"
+)
+
+// NoteTextExtraction is a text extraction along with the note it belongs to.
+type NoteTextExtraction struct {
+	entity.NoteText
+	NoteName   string
+	TextStatus entity.TextExtractionStatus
+}
+
+type DefaultNoteTextRepository struct {
+	db *gorm.DB
+}
+
+func NewNoteTextRepository(db *gorm.DB) *DefaultNoteTextRepository {
+	return &DefaultNoteTextRepository{db: db}
+}
+
+func (d *DefaultNoteTextRepository) FindByNoteID(noteID int) (*entity.NoteText, error) {
+	var text entity.NoteText
+	err := d.db.Where("note_id = ?", noteID).First(&text).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &text, nil
+}
+
+// FindDue returns up to 'limit' pending extractions whose next attempt is due
+// at 'now', oldest first. Trashed notes are skipped until they are restored.
+func (d *DefaultNoteTextRepository) FindDue(now int64, limit int) ([]*entity.NoteText, error) {
+	var texts []*entity.NoteText
+	err := d.db.
+		Joins("INNER JOIN notes ON notes.id = note_texts.note_id").
+		Where("notes.text_status = ?", string(entity.TextExtractionPending)).
+		Where("notes.deleted_at IS NULL").
+		Where("note_texts.next_attempt_at <= ?", now).
+		Order("note_texts.next_attempt_at ASC").
+		Limit(limit).
+		Find(&texts).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return texts, nil
+}
+
+// FindAllByStatus returns up to 'limit' extractions of notes in 'status',
+// most recently attempted first.
+func (d *DefaultNoteTextRepository) FindAllByStatus(status entity.TextExtractionStatus, limit int) ([]*NoteTextExtraction, error) {
+	var texts []*NoteTextExtraction
+	err := d.db.
+		Table("note_texts").
+		Select("note_texts.*, notes.name AS note_name, notes.text_status").
+		Joins("INNER JOIN notes ON notes.id = note_texts.note_id").
+		Where("notes.text_status = ?", string(status)).
+		Where("notes.deleted_at IS NULL").
+		Order("note_texts.updated_at DESC, note_texts.note_id DESC").
+		Limit(limit).
+		Scan(&texts).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return texts, nil
+}
+
+// SaveWithDB inserts the text, or replaces the one stored for its note.
+func (d *DefaultNoteTextRepository) SaveWithDB(db *gorm.DB, text *entity.NoteText) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(text).Error
+}
+
+func (d *DefaultNoteTextRepository) DeleteByNoteIDWithDB(db *gorm.DB, noteID int) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.
+		Where("note_id = ?", noteID).
+		Delete(&entity.NoteText{}).Error
+}
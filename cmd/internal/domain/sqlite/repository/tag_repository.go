@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"errors"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+type DefaultTagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) *DefaultTagRepository {
+	return &DefaultTagRepository{db: db}
+}
+
+// TagUsage is a tag along with how many notes use it.
+type TagUsage struct {
+	ID        int
+	Name      string
+	NoteCount int
+}
+
+// FindAllWithUsage lists the tags starting with 'prefix', most used first.
+// Only active notes the viewer can see are counted, so tags used by nothing
+// else than hidden or trashed notes are left out.
+func (d *DefaultTagRepository) FindAllWithUsage(prefix string, viewerID int, withPrivate bool, limit int) ([]*TagUsage, error) {
+	query := d.db.
+		Table("tags").
+		Select("tags.id, tags.name, COUNT(notes.id) AS note_count").
+		Joins("JOIN note_tags ON note_tags.tag_id = tags.id").
+		Joins("JOIN notes ON notes.id = note_tags.note_id").
+		Where("notes.deleted_at IS NULL")
+
+	if prefix != "" {
+		query = query.Where("tags.name LIKE ? ESCAPE '\\'", escapeLike(prefix)+"%")
+	}
+
+	if !withPrivate {
+		query = whereVisibleTo(query, "notes", viewerID)
+	}
+
+	var tags []*TagUsage
+	err := query.
+		Group("tags.id").
+		Order("note_count DESC, tags.name ASC").
+		Limit(limit).
+		Scan(&tags).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (d *DefaultTagRepository) FindByID(id int) (*entity.Tag, error) {
+	var tag entity.Tag
+	err := d.db.First(&tag, id).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (d *DefaultTagRepository) FindByName(name string) (*entity.Tag, error) {
+	var tag entity.Tag
+	err := d.db.Where("name = ?", name).First(&tag).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (d *DefaultTagRepository) SaveWithDB(db *gorm.DB, tag *entity.Tag) error {
+	if db == nil {
+		db = d.db
+	}
+	return db.Save(tag).Error
+}
+
+// DeleteWithDB deletes the tag along with its links to notes.
+func (d *DefaultTagRepository) DeleteWithDB(db *gorm.DB, tag *entity.Tag) error {
+	if db == nil {
+		db = d.db
+	}
+
+	err := db.
+		Where("tag_id = ?", tag.ID).
+		Delete(&entity.NoteTag{}).Error
+	if err != nil {
+		return err
+	}
+	return db.Delete(tag).Error
+}
+
+// ReplaceNoteTagsWithDB links the note to exactly the tags in 'names', creating
+// the missing ones. Tags no longer used by any note are deleted.
+func (d *DefaultTagRepository) ReplaceNoteTagsWithDB(db *gorm.DB, noteID int, names []string) error {
+	if db == nil {
+		db = d.db
+	}
+
+	var previousIDs []int
+	err := db.
+		Model(&entity.NoteTag{}).
+		Where("note_id = ?", noteID).
+		Pluck("tag_id", &previousIDs).Error
+	if err != nil {
+		return err
+	}
+
+	err = db.
+		Where("note_id = ?", noteID).
+		Delete(&entity.NoteTag{}).Error
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		tag := entity.Tag{Name: name, CreatedAt: utils.NowUTC()}
+		if err = db.Where("name = ?", name).FirstOrCreate(&tag).Error; err != nil {
+			return err
+		}
+		if err = db.Create(&entity.NoteTag{NoteID: noteID, TagID: tag.ID}).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(previousIDs) == 0 {
+		return nil
+	}
+	return db.
+		Where("id IN ?", previousIDs).
+		Where("NOT EXISTS (SELECT 1 FROM note_tags WHERE note_tags.tag_id = tags.id)").
+		Delete(&entity.Tag{}).Error
+}
@@ -92,16 +92,11 @@ func (u *DefaultUserRepository) ExistsActiveByEmail(email string) (bool, error)
 
 // SoftDelete sets the active flag to false.
 func (u *DefaultUserRepository) SoftDelete(user *entity.User) error {
-	user.Active = false
-	user.Email = ""
-	user.SubUUID = ""
-	user.UpdatedAt = utils.NowUTC()
-
-	return u.db.Model(user).
-		Select("Active", "Email", "SubUUID", "UpdatedAt").
-		Updates(user).Error
+	return u.SoftDeleteWithDB(nil, user)
 }
 
+// SoftDeleteWithDB always applies, whatever the version of the user, since the
+// identity provider account is already gone by the time it is called.
 func (u *DefaultUserRepository) SoftDeleteWithDB(db *gorm.DB, user *entity.User) error {
 	if db == nil {
 		db = u.db
@@ -112,9 +107,20 @@ func (u *DefaultUserRepository) SoftDeleteWithDB(db *gorm.DB, user *entity.User)
 	user.SubUUID = ""
 	user.UpdatedAt = utils.NowUTC()
 
-	return db.Model(user).
-		Select("Active", "Email", "SubUUID", "UpdatedAt").
-		Updates(user).Error
+	err := db.Model(user).
+		Updates(map[string]any{
+			"active":     false,
+			"email":      "",
+			"sub_uuid":   "",
+			"updated_at": user.UpdatedAt,
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	user.Version++
+	return nil
 }
 
 func (u *DefaultUserRepository) FetchAllActiveOnline() ([]*entity.User, error) {
@@ -132,12 +138,13 @@ func (u *DefaultUserRepository) FetchAllActiveOnline() ([]*entity.User, error) {
 }
 
 func (u *DefaultUserRepository) Save(user *entity.User) error {
-	return u.db.Save(user).Error
+	return saveVersioned(u.db, user, user.ID, &user.Version)
 }
 
+// SaveWithDB returns ErrStaleVersion when the user changed since it was loaded.
 func (u *DefaultUserRepository) SaveWithDB(db *gorm.DB, user *entity.User) error {
 	if db == nil {
 		db = u.db
 	}
-	return db.Save(user).Error
+	return saveVersioned(db, user, user.ID, &user.Version)
 }
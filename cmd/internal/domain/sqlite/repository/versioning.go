@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrStaleVersion is returned when a row was changed by someone else
+// since it was loaded.
+var ErrStaleVersion = errors.New("stale row version")
+
+// saveVersioned inserts 'model' at version 1, or updates every column of it
+// as long as the stored row still has the version it was loaded with.
+// The version is bumped by one on success. Rows read from the database
+// always have a version, so a zero version means a new row.
+func saveVersioned(db *gorm.DB, model any, id int, version *int) error {
+	if id == 0 || *version == 0 {
+		*version = 1
+		return db.Create(model).Error
+	}
+
+	loaded := *version
+	*version = loaded + 1
+
+	result := db.
+		Model(model).
+		Omit(clause.Associations).
+		Select("*").
+		Where("version = ?", loaded).
+		Updates(model)
+
+	if result.Error != nil {
+		*version = loaded
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		*version = loaded
+		return ErrStaleVersion
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"gorm.io/gorm"
+)
+
+// noteSearchSchema creates the FTS5 index over notes and the triggers that keep
+// it in sync. REFERENCE notes only store a file name as content, so the text
+// extracted from their attachment is indexed instead, if any.
+var noteSearchSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(
+		name, tags, content,
+		tokenize = 'unicode61 remove_diacritics 2'
+	)`,
+	// Recreated on every start, so databases indexed before text extraction
+	// get the triggers reading from note_texts
+	`DROP TRIGGER IF EXISTS notes_fts_after_insert`,
+	`DROP TRIGGER IF EXISTS notes_fts_after_update`,
+	`CREATE TRIGGER IF NOT EXISTS notes_fts_after_insert AFTER INSERT ON notes BEGIN
+		INSERT INTO notes_fts(rowid, name, tags, content)
+		VALUES (new.id, new.name, new.tags, ` + indexedContent("new") + `);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS notes_fts_after_update AFTER UPDATE OF name, tags, content ON notes
+	WHEN old.name IS NOT new.name OR old.tags IS NOT new.tags OR old.content IS NOT new.content BEGIN
+		DELETE FROM notes_fts WHERE rowid = old.id;
+		INSERT INTO notes_fts(rowid, name, tags, content)
+		VALUES (new.id, new.name, new.tags, ` + indexedContent("new") + `);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS notes_fts_after_delete AFTER DELETE ON notes BEGIN
+		DELETE FROM notes_fts WHERE rowid = old.id;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS note_texts_fts_after_insert AFTER INSERT ON note_texts BEGIN
+		` + reindexNote("new.note_id") + `
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS note_texts_fts_after_update AFTER UPDATE OF content ON note_texts
+	WHEN old.content IS NOT new.content BEGIN
+		` + reindexNote("new.note_id") + `
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS note_texts_fts_after_delete AFTER DELETE ON note_texts BEGIN
+		` + reindexNote("old.note_id") + `
+	END`,
+}
+
+// indexedContent is the SQL expression of the indexed content of the 'row' note.
+func indexedContent(row string) string {
+	return `CASE WHEN ` + row + `.note_type = 'REFERENCE'
+		THEN COALESCE((SELECT content FROM note_texts WHERE note_id = ` + row + `.id), '')
+		ELSE ` + row + `.content END`
+}
+
+// reindexNote refreshes the index row of the note, after its extracted text changed.
+func reindexNote(noteID string) string {
+	return `DELETE FROM notes_fts WHERE rowid = ` + noteID + `;
+		INSERT INTO notes_fts(rowid, name, tags, content)
+		SELECT notes.id, notes.name, notes.tags, ` + indexedContent("notes") + ` FROM notes WHERE notes.id = ` + noteID + `;`
+}
+
+// MigrateNoteSearch creates the full-text search index for notes.
+// When the index is created for the first time, existing notes are backfilled.
+func MigrateNoteSearch(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var exists bool
+		err := tx.
+			Raw("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'notes_fts')").
+			Scan(&exists).Error
+		if err != nil {
+			return err
+		}
+
+		for _, stmt := range noteSearchSchema {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+
+		if exists {
+			return nil
+		}
+		return tx.Exec(`INSERT INTO notes_fts(rowid, name, tags, content)
+			SELECT notes.id, notes.name, notes.tags, ` + indexedContent("notes") + ` FROM notes`).Error
+	})
+}
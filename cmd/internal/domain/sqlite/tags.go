@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MigrateNoteTags fills the tags and note_tags tables from the tags column of
+// notes. It only runs while no tag exists yet, which is the case right after
+// the tables are created.
+func MigrateNoteTags(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var tagCount int64
+		if err := tx.Model(&entity.Tag{}).Count(&tagCount).Error; err != nil {
+			return err
+		}
+
+		if tagCount > 0 {
+			return nil
+		}
+
+		var notes []*entity.Note
+		err := tx.
+			Select("id", "tags").
+			Where("tags != ''").
+			Find(&notes).Error
+		if err != nil {
+			return err
+		}
+
+		now := utils.NowUTC()
+		tagIDs := make(map[string]int)
+		for _, note := range notes {
+			for _, name := range strings.Fields(note.Tags) {
+				id, ok := tagIDs[name]
+				if !ok {
+					tag := &entity.Tag{Name: name, CreatedAt: now}
+					if err = tx.Create(tag).Error; err != nil {
+						return err
+					}
+					id = tag.ID
+					tagIDs[name] = id
+				}
+
+				// Legacy rows may repeat a tag
+				link := &entity.NoteTag{NoteID: note.ID, TagID: id}
+				if err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(link).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
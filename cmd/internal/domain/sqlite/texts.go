@@ -0,0 +1,39 @@
+package sqlite
+
+import (
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// MigrateNoteTexts queues the text extraction of PDF attachments uploaded
+// before extraction existed. Those are the REFERENCE notes without a text
+// status, whose type was sniffed as PDF or, for older ones, named as such.
+func MigrateNoteTexts(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var noteIDs []int
+		err := tx.
+			Model(&entity.Note{}).
+			Where("note_type = ? AND text_status = ''", string(entity.NoteTypeReference)).
+			Where("content_type = ? OR (content_type = '' AND LOWER(content) LIKE ?)", "application/pdf", "%.pdf").
+			Pluck("id", &noteIDs).Error
+		if err != nil || len(noteIDs) == 0 {
+			return err
+		}
+
+		now := utils.NowUTC()
+		texts := make([]*entity.NoteText, len(noteIDs))
+		for i, id := range noteIDs {
+			texts[i] = &entity.NoteText{NoteID: id, NextAttemptAt: now, UpdatedAt: now}
+		}
+
+		if err = tx.CreateInBatches(texts, 100).Error; err != nil {
+			return err
+		}
+		return tx.
+			Model(&entity.Note{}).
+			Where("id IN ?", noteIDs).
+			Update("text_status", string(entity.TextExtractionPending)).Error
+	})
+}
@@ -82,5 +82,13 @@ func bindAuditLogRequest(c echo.Context) (*contract.AuditLogListRequest, apierro
 		req.ActionType = &rawActionType
 	}
 
+	if rawBatchID := strings.TrimSpace(c.QueryParam("batch_id")); rawBatchID != "" {
+		batchID, err := strconv.ParseInt(rawBatchID, 10, 64)
+		if err != nil {
+			return nil, apierror.NewInvalidParamTypeError("batch_id", "int64")
+		}
+		req.BatchID = &batchID
+	}
+
 	return req, nil
 }
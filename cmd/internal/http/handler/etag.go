@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ETags are the version of the resource between quotes, e.g. "3".
+const (
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+func formatETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseETags reads a list of ETags from an If-Match or If-None-Match header.
+// Weak tags are accepted, ETags we did not issue are ignored.
+func parseETags(header string) (versions []int, wildcard bool) {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true
+		}
+
+		tag = strings.TrimPrefix(tag, "W/")
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+
+		if version, err := strconv.Atoi(tag[1 : len(tag)-1]); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return versions, false
+}
+
+// bindPrecondition turns the If-Match header into a precondition for the service.
+// Without the header, the request is applied whatever the current version is.
+func bindPrecondition(c echo.Context) *contract.Precondition {
+	header := strings.TrimSpace(c.Request().Header.Get(headerIfMatch))
+	if header == "" {
+		return nil
+	}
+
+	versions, wildcard := parseETags(header)
+	return &contract.Precondition{Any: wildcard, Versions: versions}
+}
+
+// writeVersioned sends 'body' along with its ETag, or 304 Not Modified
+// when the client already has that version through If-None-Match.
+func writeVersioned(c echo.Context, version int, body any) error {
+	etag := formatETag(version)
+	c.Response().Header().Set(headerETag, etag)
+
+	if header := strings.TrimSpace(c.Request().Header.Get(headerIfNoneMatch)); header != "" {
+		versions, wildcard := parseETags(header)
+		if wildcard || (&contract.Precondition{Versions: versions}).Matches(version) {
+			return c.NoContent(http.StatusNotModified)
+		}
+	}
+	return c.JSON(http.StatusOK, body)
+}
+
+// writeAPIError sends 'apierr', along with the ETag of the current
+// state of the resource when a precondition failed.
+func writeAPIError(c echo.Context, apierr apierror.ErrorResponse) error {
+	if preerr, ok := apierr.(*apierror.PreconditionFailedError); ok {
+		c.Response().Header().Set(headerETag, formatETag(preerr.Version))
+	}
+	return c.JSON(apierr.Code(), apierr)
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"net/http"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type FolderService interface {
+	GetFolders(actor *entity.User) ([]*contract.FolderResponse, apierror.ErrorResponse)
+	GetFolderByID(actor *entity.User, folderId int) (*contract.FolderResponse, apierror.ErrorResponse)
+	CreateFolder(actor *entity.User, req *contract.FolderRequest) (*contract.FolderResponse, apierror.ErrorResponse)
+	UpdateFolder(actor *entity.User, folderId int, req *contract.UpdateFolderRequest) (*contract.FolderResponse, apierror.ErrorResponse)
+	MoveFolder(actor *entity.User, folderId int, req *contract.MoveFolderRequest) (*contract.FolderResponse, apierror.ErrorResponse)
+	DeleteFolder(actor *entity.User, folderId int) apierror.ErrorResponse
+}
+
+type DefaultFolderRoute struct {
+	FolderService FolderService
+}
+
+func NewFolderDefault(folderService FolderService) *DefaultFolderRoute {
+	return &DefaultFolderRoute{FolderService: folderService}
+}
+
+func (f *DefaultFolderRoute) GetFolders(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	folders, apierr := f.FolderService.GetFolders(user)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+
+	resp := echo.Map{"folders": folders}
+	return c.JSON(http.StatusOK, &resp)
+}
+
+func (f *DefaultFolderRoute) GetFolder(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	folder, apierr := f.FolderService.GetFolderByID(user, id)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, folder)
+}
+
+func (f *DefaultFolderRoute) CreateFolder(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	var req contract.FolderRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+	}
+
+	folder, apierr := f.FolderService.CreateFolder(user, &req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusCreated, folder)
+}
+
+func (f *DefaultFolderRoute) UpdateFolder(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	var req contract.UpdateFolderRequest
+	if err = c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+	}
+
+	folder, apierr := f.FolderService.UpdateFolder(user, id, &req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, folder)
+}
+
+func (f *DefaultFolderRoute) MoveFolder(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	var req contract.MoveFolderRequest
+	if err = c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+	}
+
+	folder, apierr := f.FolderService.MoveFolder(user, id, &req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, folder)
+}
+
+func (f *DefaultFolderRoute) DeleteFolder(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	apierr := f.FolderService.DeleteFolder(user, id)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type NoteImportService interface {
+	StartNoteImport(actor *entity.User, req *contract.NoteImportRequest, file *contract.NoteFile) (*contract.NoteImportResponse, apierror.ErrorResponse)
+	GetNoteImport(actor *entity.User, importId int) (*contract.NoteImportResponse, apierror.ErrorResponse)
+}
+
+type DefaultImportRoute struct {
+	ImportService NoteImportService
+}
+
+func NewImportDefault(importService NoteImportService) *DefaultImportRoute {
+	return &DefaultImportRoute{ImportService: importService}
+}
+
+// StartNoteImport receives the archive as the `content` file of a multipart
+// form, optionally preceded by a `json_payload` with the import options.
+func (i *DefaultImportRoute) StartNoteImport(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	return streamNoteForm(c, false, func(payload []byte, file *contract.NoteFile) error {
+		var req contract.NoteImportRequest
+		if len(payload) > 0 {
+			if err := json.Unmarshal(payload, &req); err != nil {
+				return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+			}
+		}
+
+		noteImport, apierr := i.ImportService.StartNoteImport(user, &req, file)
+		if apierr != nil {
+			return c.JSON(apierr.Code(), apierr)
+		}
+		return c.JSON(http.StatusAccepted, noteImport)
+	})
+}
+
+func (i *DefaultImportRoute) GetNoteImport(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	noteImport, apierr := i.ImportService.GetNoteImport(user, id)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, noteImport)
+}
@@ -1,28 +1,64 @@
 package handler
 
 import (
+	"bytes"
 	"encoding/json"
-	"mime/multipart"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"simplenotes/cmd/internal/contract"
 	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/infrastructure/storage"
 	"simplenotes/cmd/internal/utils"
 	"simplenotes/cmd/internal/utils/apierror"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
 )
 
+// maxFormPayloadBytes bounds the `json_payload` field of multipart note forms.
+const maxFormPayloadBytes = 64 * 1024
+
 // NoteService interface updated to accept *entity.User instead of strings.
 // This allows the service to check permissions without hitting the DB again.
 type NoteService interface {
-	GetAllNotes(actor *entity.User) ([]*contract.NoteResponse, apierror.ErrorResponse)
+	GetAllNotes(actor *entity.User, req *contract.NoteListRequest) (*contract.NoteListResponse, apierror.ErrorResponse)
+	SearchNotes(actor *entity.User, req *contract.NoteSearchRequest) (*contract.NoteSearchResponse, apierror.ErrorResponse)
 	GetNoteByID(actor *entity.User, noteId int) (*contract.NoteResponse, apierror.ErrorResponse)
 	CreateTextNote(actor *entity.User, req *contract.TextNoteRequest) (*contract.NoteResponse, apierror.ErrorResponse)
-	CreateFileNote(actor *entity.User, req *contract.NoteRequest, fileHeader *multipart.FileHeader) (*contract.NoteResponse, apierror.ErrorResponse)
-	UpdateNote(actor *entity.User, noteId int, req *contract.UpdateNoteRequest) (*contract.NoteResponse, apierror.ErrorResponse)
-	DeleteNote(actor *entity.User, noteId int) apierror.ErrorResponse
+	CreateFileNote(actor *entity.User, req *contract.NoteRequest, file *contract.NoteFile) (*contract.NoteResponse, apierror.ErrorResponse)
+	UpdateNote(actor *entity.User, noteId int, req *contract.UpdateNoteRequest, pre *contract.Precondition) (*contract.NoteResponse, apierror.ErrorResponse)
+	ReplaceNoteFile(actor *entity.User, noteId int, file *contract.NoteFile) (*contract.NoteResponse, apierror.ErrorResponse)
+	GetNoteFile(actor *entity.User, noteId int, proxy bool) (string, *storage.Object, apierror.ErrorResponse)
+	GetAttachment(actor *entity.User, filename string) (*storage.Object, apierror.ErrorResponse)
+	GetNoteThumbnail(actor *entity.User, noteId int) (*storage.Object, apierror.ErrorResponse)
+	DeleteNote(actor *entity.User, noteId int, pre *contract.Precondition) apierror.ErrorResponse
+	MoveNote(actor *entity.User, noteId int, req *contract.MoveNoteRequest) (*contract.NoteResponse, apierror.ErrorResponse)
+	BulkUpdateNotes(actor *entity.User, req *contract.NoteBulkRequest) (*contract.NoteBulkResponse, apierror.ErrorResponse)
+	GetTrashedNotes(actor *entity.User, req *contract.NoteTrashRequest) (*contract.NoteListResponse, apierror.ErrorResponse)
+	RestoreNote(actor *entity.User, noteId int) (*contract.NoteResponse, apierror.ErrorResponse)
+	GetTextExtractions(actor *entity.User, req *contract.TextExtractionListRequest) ([]*contract.TextExtractionResponse, apierror.ErrorResponse)
+	PurgeNote(actor *entity.User, noteId int) apierror.ErrorResponse
+	GetNoteRevisions(actor *entity.User, noteId int) ([]*contract.NoteRevisionResponse, apierror.ErrorResponse)
+	GetNoteRevision(actor *entity.User, noteId, revision int) (*contract.NoteRevisionResponse, apierror.ErrorResponse)
+	DiffNoteRevisions(actor *entity.User, noteId, from, to int) (*contract.NoteRevisionDiffResponse, apierror.ErrorResponse)
+	RestoreNoteRevision(actor *entity.User, noteId, revision int) (*contract.NoteResponse, apierror.ErrorResponse)
+	ExportNotes(actor *entity.User, req *contract.NoteExportRequest) (func(w io.Writer) error, apierror.ErrorResponse)
+	GetNoteLinks(actor *entity.User, noteId int) ([]*contract.NoteLinkResponse, apierror.ErrorResponse)
+	GetNoteBacklinks(actor *entity.User, noteId int) ([]*contract.NoteResponse, apierror.ErrorResponse)
+	RenderNote(actor *entity.User, noteId int, withTOC bool) (*contract.NoteRenderResponse, apierror.ErrorResponse)
+	GetNoteAttachments(actor *entity.User, noteId int) ([]*contract.NoteAttachmentResponse, apierror.ErrorResponse)
+	AddNoteAttachment(actor *entity.User, noteId int, file *contract.NoteFile) (*contract.NoteAttachmentResponse, apierror.ErrorResponse)
+	ReorderNoteAttachments(actor *entity.User, noteId int, req *contract.NoteAttachmentOrderRequest) ([]*contract.NoteAttachmentResponse, apierror.ErrorResponse)
+	GetNoteAttachmentFile(actor *entity.User, noteId, attachmentId int, proxy bool) (string, *storage.Object, apierror.ErrorResponse)
+	DeleteNoteAttachment(actor *entity.User, noteId, attachmentId int) apierror.ErrorResponse
+	GetNoteCollaborators(actor *entity.User, noteId int) ([]*contract.NoteGrantResponse, apierror.ErrorResponse)
+	PutNoteCollaborator(actor *entity.User, noteId, userId int, req *contract.NoteGrantRequest) (*contract.NoteGrantResponse, apierror.ErrorResponse)
+	DeleteNoteCollaborator(actor *entity.User, noteId, userId int) apierror.ErrorResponse
 }
 
 type DefaultNoteRoute struct {
@@ -39,13 +75,44 @@ func (n *DefaultNoteRoute) GetNotes(c echo.Context) error {
 		return c.JSON(cerr.Code(), cerr)
 	}
 
-	notes, err := n.NoteService.GetAllNotes(user)
+	req, apierr := bindNoteListRequest(c)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+
+	resp, err := n.NoteService.GetAllNotes(user, req)
 	if err != nil {
 		return c.JSON(err.Code(), err)
 	}
+	return c.JSON(http.StatusOK, resp)
+}
 
-	resp := echo.Map{"notes": notes}
-	return c.JSON(http.StatusOK, &resp)
+func (n *DefaultNoteRoute) SearchNotes(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	req := &contract.NoteSearchRequest{
+		Query: strings.TrimSpace(c.QueryParam("q")),
+	}
+	if req.Query == "" {
+		return c.JSON(http.StatusBadRequest, apierror.NewMissingParamError("q"))
+	}
+
+	if rawLimit := strings.TrimSpace(c.QueryParam("limit")); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("limit", "int"))
+		}
+		req.Limit = limit
+	}
+
+	resp, apierr := n.NoteService.SearchNotes(user, req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, resp)
 }
 
 func (n *DefaultNoteRoute) GetNote(c echo.Context) error {
@@ -63,7 +130,7 @@ func (n *DefaultNoteRoute) GetNote(c echo.Context) error {
 	if apierr != nil {
 		return c.JSON(apierr.Code(), apierr)
 	}
-	return c.JSON(http.StatusOK, note)
+	return writeVersioned(c, note.Version, note)
 }
 
 func (n *DefaultNoteRoute) CreateNote(c echo.Context) error {
@@ -97,13 +164,119 @@ func (n *DefaultNoteRoute) UpdateNote(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
 	}
 
-	newNote, apierr := n.NoteService.UpdateNote(user, id, &req)
+	newNote, apierr := n.NoteService.UpdateNote(user, id, &req, bindPrecondition(c))
 	if apierr != nil {
-		return c.JSON(apierr.Code(), apierr)
+		return writeAPIError(c, apierr)
 	}
+
+	c.Response().Header().Set(headerETag, formatETag(newNote.Version))
 	return c.JSON(http.StatusOK, &newNote)
 }
 
+func (n *DefaultNoteRoute) ReplaceNoteFile(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	return streamNoteForm(c, false, func(_ []byte, file *contract.NoteFile) error {
+		note, apierr := n.NoteService.ReplaceNoteFile(user, id, file)
+		if apierr != nil {
+			return c.JSON(apierr.Code(), apierr)
+		}
+		return c.JSON(http.StatusOK, note)
+	})
+}
+
+// DownloadFile streams an attachment by its file name, with support for range requests.
+func (n *DefaultNoteRoute) DownloadFile(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	name := strings.TrimSpace(c.Param("name"))
+	if name == "" {
+		return c.JSON(http.StatusBadRequest, apierror.NewMissingParamError("name"))
+	}
+
+	object, apierr := n.NoteService.GetAttachment(user, name)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return serveObject(c, name, object)
+}
+
+// GetNoteFile redirects to a short-lived presigned URL of the note attachment,
+// or streams it when "proxy=true" is given or the storage cannot presign.
+func (n *DefaultNoteRoute) GetNoteFile(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	proxy := false
+	if raw := strings.TrimSpace(c.QueryParam("proxy")); raw != "" {
+		if proxy, err = strconv.ParseBool(raw); err != nil {
+			return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("proxy", "bool"))
+		}
+	}
+
+	url, object, apierr := n.NoteService.GetNoteFile(user, id, proxy)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+
+	if object == nil {
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+		return c.Redirect(http.StatusTemporaryRedirect, url)
+	}
+	return serveObject(c, "", object)
+}
+
+// GetNoteThumbnail streams the thumbnail of the note. Clients revalidate it on
+// every use, since the note keeps its URL when its file is replaced.
+func (n *DefaultNoteRoute) GetNoteThumbnail(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	object, apierr := n.NoteService.GetNoteThumbnail(user, id)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "private, no-cache")
+	return serveObject(c, "", object)
+}
+
+// serveObject streams the object, answering range and conditional requests.
+func serveObject(c echo.Context, name string, object *storage.Object) error {
+	defer object.Body.Close()
+
+	if object.ContentType != "" {
+		c.Response().Header().Set(echo.HeaderContentType, object.ContentType)
+	}
+	http.ServeContent(c.Response(), c.Request(), name, object.ModTime, object.Body)
+	return nil
+}
+
 func (n *DefaultNoteRoute) DeleteNote(c echo.Context) error {
 	user, cerr := utils.GetUserFromContext(c)
 	if cerr != nil {
@@ -115,9 +288,528 @@ func (n *DefaultNoteRoute) DeleteNote(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
 	}
 
-	serr := n.NoteService.DeleteNote(user, id)
+	serr := n.NoteService.DeleteNote(user, id, bindPrecondition(c))
 	if serr != nil {
-		return c.JSON(serr.Code(), serr)
+		return writeAPIError(c, serr)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (n *DefaultNoteRoute) MoveNote(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	var req contract.MoveNoteRequest
+	if err = c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+	}
+
+	note, apierr := n.NoteService.MoveNote(user, id, &req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, note)
+}
+
+// BulkUpdateNotes always answers 200 once the request is valid, the outcome
+// of each note is in its own result.
+func (n *DefaultNoteRoute) BulkUpdateNotes(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	var req contract.NoteBulkRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+	}
+
+	resp, apierr := n.NoteService.BulkUpdateNotes(user, &req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (n *DefaultNoteRoute) GetTrashedNotes(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	req := &contract.NoteTrashRequest{
+		Before: optionalQueryParam(c, "before"),
+		After:  optionalQueryParam(c, "after"),
+	}
+
+	if rawLimit := strings.TrimSpace(c.QueryParam("limit")); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("limit", "int"))
+		}
+		req.Limit = limit
+	}
+
+	resp, apierr := n.NoteService.GetTrashedNotes(user, req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (n *DefaultNoteRoute) GetTextExtractions(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	req := &contract.TextExtractionListRequest{
+		Status: strings.ToUpper(strings.TrimSpace(c.QueryParam("status"))),
+	}
+
+	if rawLimit := strings.TrimSpace(c.QueryParam("limit")); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("limit", "int"))
+		}
+		req.Limit = limit
+	}
+
+	resp, apierr := n.NoteService.GetTextExtractions(user, req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (n *DefaultNoteRoute) RestoreNote(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	note, apierr := n.NoteService.RestoreNote(user, id)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, note)
+}
+
+func (n *DefaultNoteRoute) PurgeNote(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	apierr := n.NoteService.PurgeNote(user, id)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (n *DefaultNoteRoute) GetNoteRevisions(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	revisions, apierr := n.NoteService.GetNoteRevisions(user, id)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+
+	resp := echo.Map{"revisions": revisions}
+	return c.JSON(http.StatusOK, &resp)
+}
+
+func (n *DefaultNoteRoute) GetNoteRevision(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("rev", "int"))
+	}
+
+	revision, apierr := n.NoteService.GetNoteRevision(user, id, rev)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, revision)
+}
+
+func (n *DefaultNoteRoute) DiffNoteRevisions(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	from, apierr := bindRevisionQuery(c, "from")
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+
+	to, apierr := bindRevisionQuery(c, "to")
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+
+	resp, apierr := n.NoteService.DiffNoteRevisions(user, id, from, to)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (n *DefaultNoteRoute) RestoreNoteRevision(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("rev", "int"))
+	}
+
+	note, apierr := n.NoteService.RestoreNoteRevision(user, id, rev)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, note)
+}
+
+func (n *DefaultNoteRoute) GetNoteCollaborators(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	grants, apierr := n.NoteService.GetNoteCollaborators(user, id)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+
+	resp := echo.Map{"collaborators": grants}
+	return c.JSON(http.StatusOK, &resp)
+}
+
+// ExportNotes streams a ZIP archive of the notes selected by the "tags",
+// "tags_mode" and "folder_id" query parameters.
+func (n *DefaultNoteRoute) ExportNotes(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	req := &contract.NoteExportRequest{
+		TagsMode: optionalQueryParam(c, "tags_mode"),
+	}
+
+	if rawTags := strings.TrimSpace(c.QueryParam("tags")); rawTags != "" {
+		req.Tags = strings.Split(rawTags, ",")
+	}
+
+	if rawFolderID := strings.TrimSpace(c.QueryParam("folder_id")); rawFolderID != "" {
+		folderID, err := strconv.Atoi(rawFolderID)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("folder_id", "int"))
+		}
+		req.FolderID = &folderID
+	}
+
+	writeExport, apierr := n.NoteService.ExportNotes(user, req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+
+	filename := fmt.Sprintf("notes-%s.zip", time.Now().UTC().Format("20060102-150405"))
+	c.Response().Header().Set(echo.HeaderContentType, "application/zip")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	// The status is sent already, a failure can only cut the archive short
+	if err := writeExport(c.Response()); err != nil {
+		log.Errorf("failed to export notes for user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+func (n *DefaultNoteRoute) GetNoteLinks(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	links, apierr := n.NoteService.GetNoteLinks(user, id)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, links)
+}
+
+func (n *DefaultNoteRoute) GetNoteBacklinks(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	notes, apierr := n.NoteService.GetNoteBacklinks(user, id)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, notes)
+}
+
+// RenderNote returns the note as sanitized HTML, with its headings when "toc=true" is given.
+func (n *DefaultNoteRoute) RenderNote(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	withTOC := false
+	if raw := strings.TrimSpace(c.QueryParam("toc")); raw != "" {
+		if withTOC, err = strconv.ParseBool(raw); err != nil {
+			return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("toc", "bool"))
+		}
+	}
+
+	render, apierr := n.NoteService.RenderNote(user, id, withTOC)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, render)
+}
+
+func (n *DefaultNoteRoute) GetNoteAttachments(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	attachments, apierr := n.NoteService.GetNoteAttachments(user, id)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, attachments)
+}
+
+// AddNoteAttachment streams the 'content' file of the multipart form into a new attachment.
+func (n *DefaultNoteRoute) AddNoteAttachment(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	return streamNoteForm(c, false, func(_ []byte, file *contract.NoteFile) error {
+		attachment, apierr := n.NoteService.AddNoteAttachment(user, id, file)
+		if apierr != nil {
+			return c.JSON(apierr.Code(), apierr)
+		}
+		return c.JSON(http.StatusCreated, attachment)
+	})
+}
+
+func (n *DefaultNoteRoute) ReorderNoteAttachments(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	var req contract.NoteAttachmentOrderRequest
+	if err = c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+	}
+
+	attachments, apierr := n.NoteService.ReorderNoteAttachments(user, id, &req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, attachments)
+}
+
+// GetNoteAttachmentFile works like GetNoteFile, so markdown notes can embed
+// their attachments by URL.
+func (n *DefaultNoteRoute) GetNoteAttachmentFile(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	attachmentId, err := strconv.Atoi(c.Param("attachmentId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("attachmentId", "int"))
+	}
+
+	proxy := false
+	if raw := strings.TrimSpace(c.QueryParam("proxy")); raw != "" {
+		if proxy, err = strconv.ParseBool(raw); err != nil {
+			return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("proxy", "bool"))
+		}
+	}
+
+	url, object, apierr := n.NoteService.GetNoteAttachmentFile(user, id, attachmentId, proxy)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+
+	if object == nil {
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+		return c.Redirect(http.StatusTemporaryRedirect, url)
+	}
+	return serveObject(c, "", object)
+}
+
+func (n *DefaultNoteRoute) DeleteNoteAttachment(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	attachmentId, err := strconv.Atoi(c.Param("attachmentId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("attachmentId", "int"))
+	}
+
+	if apierr := n.NoteService.DeleteNoteAttachment(user, id, attachmentId); apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (n *DefaultNoteRoute) PutNoteCollaborator(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	userId, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("userId", "int"))
+	}
+
+	var req contract.NoteGrantRequest
+	if err = c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+	}
+
+	grant, apierr := n.NoteService.PutNoteCollaborator(user, id, userId, &req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, grant)
+}
+
+func (n *DefaultNoteRoute) DeleteNoteCollaborator(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	userId, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("userId", "int"))
+	}
+
+	apierr := n.NoteService.DeleteNoteCollaborator(user, id, userId)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
 	}
 	return c.NoContent(http.StatusOK)
 }
@@ -146,24 +838,150 @@ func (n *DefaultNoteRoute) createFromFile(c echo.Context) error {
 		return c.JSON(cerr.Code(), cerr)
 	}
 
-	jsonPayload := strings.TrimSpace(c.FormValue("json_payload"))
-	if jsonPayload == "" {
+	return streamNoteForm(c, true, func(payload []byte, file *contract.NoteFile) error {
+		var req contract.NoteRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+		}
+
+		note, apierr := n.NoteService.CreateFileNote(user, &req, file)
+		if apierr != nil {
+			return c.JSON(apierr.Code(), apierr)
+		}
+		return c.JSON(http.StatusCreated, &note)
+	})
+}
+
+// streamNoteForm walks a multipart note form without buffering it, and hands
+// the `content` file to 'upload' while it is still being received. When
+// 'withPayload' is set, the `json_payload` field must come before the file.
+func streamNoteForm(c echo.Context, withPayload bool, upload func(payload []byte, file *contract.NoteFile) error) error {
+	reader, err := c.Request().MultipartReader()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+	}
+
+	var payload []byte
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+		}
+
+		switch part.FormName() {
+		case "json_payload":
+			payload, err = io.ReadAll(io.LimitReader(part, maxFormPayloadBytes))
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+			}
+			payload = bytes.TrimSpace(payload)
+		case "content":
+			if withPayload && len(payload) == 0 {
+				return c.JSON(http.StatusBadRequest, apierror.FormJSONRequiredError)
+			}
+			return upload(payload, &contract.NoteFile{Filename: part.FileName(), Body: part})
+		}
+	}
+
+	if withPayload && len(payload) == 0 {
 		return c.JSON(http.StatusBadRequest, apierror.FormJSONRequiredError)
 	}
+	return c.JSON(http.StatusBadRequest, apierror.MissingNoteFileError)
+}
 
-	var req contract.NoteRequest
-	if err := json.Unmarshal([]byte(jsonPayload), &req); err != nil {
-		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+func bindRevisionQuery(c echo.Context, name string) (int, apierror.ErrorResponse) {
+	raw := strings.TrimSpace(c.QueryParam(name))
+	if raw == "" {
+		return 0, apierror.NewMissingParamError(name)
 	}
 
-	fileHeader, err := c.FormFile("content")
+	rev, err := strconv.Atoi(raw)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, apierror.MissingNoteFileError)
+		return 0, apierror.NewInvalidParamTypeError(name, "int")
 	}
+	return rev, nil
+}
 
-	note, apierr := n.NoteService.CreateFileNote(user, &req, fileHeader)
-	if apierr != nil {
-		return c.JSON(apierr.Code(), apierr)
+func bindNoteListRequest(c echo.Context) (*contract.NoteListRequest, apierror.ErrorResponse) {
+	req := &contract.NoteListRequest{}
+
+	if rawLimit := strings.TrimSpace(c.QueryParam("limit")); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			return nil, apierror.NewInvalidParamTypeError("limit", "int")
+		}
+		req.Limit = limit
 	}
-	return c.JSON(http.StatusCreated, &note)
+
+	if rawCreatedByID := strings.TrimSpace(c.QueryParam("created_by_id")); rawCreatedByID != "" {
+		createdByID, err := strconv.Atoi(rawCreatedByID)
+		if err != nil {
+			return nil, apierror.NewInvalidParamTypeError("created_by_id", "int")
+		}
+		req.CreatedByID = &createdByID
+	}
+
+	if rawFolderID := strings.TrimSpace(c.QueryParam("folder_id")); rawFolderID != "" {
+		folderID, err := strconv.Atoi(rawFolderID)
+		if err != nil {
+			return nil, apierror.NewInvalidParamTypeError("folder_id", "int")
+		}
+		req.FolderID = &folderID
+	}
+
+	if rawTags := strings.TrimSpace(c.QueryParam("tags")); rawTags != "" {
+		req.Tags = strings.Split(rawTags, ",")
+	}
+
+	req.Before = optionalQueryParam(c, "before")
+	req.After = optionalQueryParam(c, "after")
+	req.Sort = optionalQueryParam(c, "sort")
+	req.Order = optionalQueryParam(c, "order")
+	req.TagsMode = optionalQueryParam(c, "tags_mode")
+	req.NoteType = optionalQueryParam(c, "note_type")
+	req.Visibility = optionalQueryParam(c, "visibility")
+
+	var apierr apierror.ErrorResponse
+	if req.CreatedAfter, apierr = bindTimeQueryParam(c, "created_after"); apierr != nil {
+		return nil, apierr
+	}
+	if req.CreatedBefore, apierr = bindTimeQueryParam(c, "created_before"); apierr != nil {
+		return nil, apierr
+	}
+	if req.UpdatedAfter, apierr = bindTimeQueryParam(c, "updated_after"); apierr != nil {
+		return nil, apierr
+	}
+	if req.UpdatedBefore, apierr = bindTimeQueryParam(c, "updated_before"); apierr != nil {
+		return nil, apierr
+	}
+	return req, nil
+}
+
+func optionalQueryParam(c echo.Context, name string) *string {
+	raw := strings.TrimSpace(c.QueryParam(name))
+	if raw == "" {
+		return nil
+	}
+	return &raw
+}
+
+// bindTimeQueryParam parses an RFC3339 query parameter into UTC millis,
+// the same format used by all timestamps in our responses.
+func bindTimeQueryParam(c echo.Context, name string) (*int64, apierror.ErrorResponse) {
+	raw := strings.TrimSpace(c.QueryParam(name))
+	if raw == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, apierror.NewInvalidParamTypeError(name, "RFC3339 datetime")
+	}
+
+	millis := parsed.UnixMilli()
+	return &millis, nil
 }
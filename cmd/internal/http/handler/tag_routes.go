@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"net/http"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type TagService interface {
+	GetTags(actor *entity.User, req *contract.TagListRequest) ([]*contract.TagResponse, apierror.ErrorResponse)
+	RenameTag(actor *entity.User, tagId int, req *contract.RenameTagRequest) apierror.ErrorResponse
+	MergeTag(actor *entity.User, tagId int, req *contract.MergeTagRequest) apierror.ErrorResponse
+	DeleteTag(actor *entity.User, tagId int) apierror.ErrorResponse
+}
+
+type DefaultTagRoute struct {
+	TagService TagService
+}
+
+func NewTagDefault(tagService TagService) *DefaultTagRoute {
+	return &DefaultTagRoute{TagService: tagService}
+}
+
+func (t *DefaultTagRoute) GetTags(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	req := &contract.TagListRequest{
+		Prefix: strings.TrimSpace(c.QueryParam("prefix")),
+	}
+
+	if rawLimit := strings.TrimSpace(c.QueryParam("limit")); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("limit", "int"))
+		}
+		req.Limit = limit
+	}
+
+	tags, apierr := t.TagService.GetTags(user, req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+
+	resp := echo.Map{"tags": tags}
+	return c.JSON(http.StatusOK, &resp)
+}
+
+func (t *DefaultTagRoute) RenameTag(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	var req contract.RenameTagRequest
+	if err = c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+	}
+
+	if apierr := t.TagService.RenameTag(user, id, &req); apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (t *DefaultTagRoute) MergeTag(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	var req contract.MergeTagRequest
+	if err = c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+	}
+
+	if apierr := t.TagService.MergeTag(user, id, &req); apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (t *DefaultTagRoute) DeleteTag(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	if apierr := t.TagService.DeleteTag(user, id); apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"net/http"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type TemplateService interface {
+	GetTemplates(actor *entity.User) ([]*contract.TemplateResponse, apierror.ErrorResponse)
+	GetTemplateByID(actor *entity.User, templateId int) (*contract.TemplateResponse, apierror.ErrorResponse)
+	CreateTemplate(actor *entity.User, req *contract.TemplateRequest) (*contract.TemplateResponse, apierror.ErrorResponse)
+	UpdateTemplate(actor *entity.User, templateId int, req *contract.UpdateTemplateRequest) (*contract.TemplateResponse, apierror.ErrorResponse)
+	DeleteTemplate(actor *entity.User, templateId int) apierror.ErrorResponse
+	CreateNoteFromTemplate(actor *entity.User, req *contract.NoteFromTemplateRequest) (*contract.NoteResponse, apierror.ErrorResponse)
+}
+
+type DefaultTemplateRoute struct {
+	TemplateService TemplateService
+}
+
+func NewTemplateDefault(templateService TemplateService) *DefaultTemplateRoute {
+	return &DefaultTemplateRoute{TemplateService: templateService}
+}
+
+func (t *DefaultTemplateRoute) GetTemplates(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	templates, apierr := t.TemplateService.GetTemplates(user)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+
+	resp := echo.Map{"templates": templates}
+	return c.JSON(http.StatusOK, &resp)
+}
+
+func (t *DefaultTemplateRoute) GetTemplate(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	template, apierr := t.TemplateService.GetTemplateByID(user, id)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, template)
+}
+
+func (t *DefaultTemplateRoute) CreateTemplate(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	var req contract.TemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+	}
+
+	template, apierr := t.TemplateService.CreateTemplate(user, &req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusCreated, template)
+}
+
+func (t *DefaultTemplateRoute) UpdateTemplate(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	var req contract.UpdateTemplateRequest
+	if err = c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+	}
+
+	template, apierr := t.TemplateService.UpdateTemplate(user, id, &req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusOK, template)
+}
+
+func (t *DefaultTemplateRoute) DeleteTemplate(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.NewInvalidParamTypeError("id", "int"))
+	}
+
+	apierr := t.TemplateService.DeleteTemplate(user, id)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (t *DefaultTemplateRoute) CreateNoteFromTemplate(c echo.Context) error {
+	user, cerr := utils.GetUserFromContext(c)
+	if cerr != nil {
+		return c.JSON(cerr.Code(), cerr)
+	}
+
+	var req contract.NoteFromTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
+	}
+
+	note, apierr := t.TemplateService.CreateNoteFromTemplate(user, &req)
+	if apierr != nil {
+		return c.JSON(apierr.Code(), apierr)
+	}
+	return c.JSON(http.StatusCreated, note)
+}
@@ -14,8 +14,8 @@ import (
 type UserService interface {
 	GetUsers(requester *entity.User) ([]*contract.UserResponse, apierror.ErrorResponse)
 	GetUser(requester *entity.User, rawId string) (*contract.UserResponse, apierror.ErrorResponse)
-	UpdateUser(requester *entity.User, targetId string, req *contract.UpdateUserRequest) (*contract.UserResponse, apierror.ErrorResponse)
-	DeleteUser(requester *entity.User, targetId string) apierror.ErrorResponse
+	UpdateUser(requester *entity.User, targetId string, req *contract.UpdateUserRequest, pre *contract.Precondition) (*contract.UserResponse, apierror.ErrorResponse)
+	DeleteUser(requester *entity.User, targetId string, pre *contract.Precondition) apierror.ErrorResponse
 	Logout(actor *entity.User, req *contract.LogoutRequest) apierror.ErrorResponse
 	CheckEmail(req *contract.UserStatusRequest) (*contract.EmailStatus, apierror.ErrorResponse)
 	CreateUser(req *contract.CreateUserRequest) apierror.ErrorResponse
@@ -62,7 +62,7 @@ func (u *DefaultUserRoute) GetUser(c echo.Context) error {
 	if apierr != nil {
 		return c.JSON(apierr.Code(), apierr)
 	}
-	return c.JSON(http.StatusOK, resp)
+	return writeVersioned(c, resp.Version, resp)
 }
 
 func (u *DefaultUserRoute) UpdateUser(c echo.Context) error {
@@ -77,10 +77,12 @@ func (u *DefaultUserRoute) UpdateUser(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, apierror.MalformedBodyError)
 	}
 
-	newUser, apierr := u.UserService.UpdateUser(user, targetId, &req)
+	newUser, apierr := u.UserService.UpdateUser(user, targetId, &req, bindPrecondition(c))
 	if apierr != nil {
-		return c.JSON(apierr.Code(), apierr)
+		return writeAPIError(c, apierr)
 	}
+
+	c.Response().Header().Set(headerETag, formatETag(newUser.Version))
 	return c.JSON(http.StatusOK, newUser)
 }
 
@@ -95,9 +97,9 @@ func (u *DefaultUserRoute) DeleteUser(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, apierror.NewMissingParamError("id"))
 	}
 
-	apierr := u.UserService.DeleteUser(user, targetId)
+	apierr := u.UserService.DeleteUser(user, targetId, bindPrecondition(c))
 	if apierr != nil {
-		return c.JSON(apierr.Code(), apierr)
+		return writeAPIError(c, apierr)
 	}
 	return c.NoContent(http.StatusOK)
 }
@@ -0,0 +1,261 @@
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"io"
+	"net/url"
+	"os"
+	"simplenotes/cmd/internal/infrastructure/storage"
+	"sync"
+	"time"
+)
+
+// multipartPartSize is both the size of the parts of a multipart upload and the
+// size above which one is used. S3 requires parts of at least 5 MiB.
+const multipartPartSize = 8 * 1024 * 1024
+
+// partBuffers holds the part buffers of finished uploads, so concurrent uploads
+// reuse a few buffers instead of allocating a new part each.
+var partBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, multipartPartSize)
+		return &buf
+	},
+}
+
+type storageClient struct {
+	bucket string
+	client *s3.Client
+}
+
+func NewStorageClient() (storage.Backend, error) {
+	region := os.Getenv("AWS_S3_REGION")
+	bucket := os.Getenv("S3_BUCKET_NAME")
+	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
+	if err != nil {
+		return nil, err
+	}
+
+	client := s3.NewFromConfig(cfg)
+	return &storageClient{
+		bucket: bucket,
+		client: client,
+	}, nil
+}
+
+// UploadFile streams 'body' to the bucket. Objects fitting in a single part are
+// sent with one PutObject, bigger ones with a multipart upload, so at most one
+// part is held in memory at any time. Part buffers come from partBuffers.
+func (s *storageClient) UploadFile(body io.Reader, key, contentType string) error {
+	if key == "" {
+		return storage.ErrorEmptyKey
+	}
+
+	pooled := partBuffers.Get().(*[]byte)
+	defer partBuffers.Put(pooled)
+
+	buf := *pooled
+	n, err := io.ReadFull(body, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		if contentType == "" {
+			contentType = storage.DetectContentType(key, buf[:n])
+		}
+		return s.putObject(key, buf[:n], contentType)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if contentType == "" {
+		contentType = storage.DetectContentType(key, buf)
+	}
+	return s.uploadMultipart(key, body, buf, contentType)
+}
+
+func (s *storageClient) putObject(key string, data []byte, contentType string) error {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	}
+
+	_, err := s.client.PutObject(context.Background(), input)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// uploadMultipart uploads 'first', then the rest of 'body', as the parts of a
+// multipart upload. The upload is aborted on failure, so S3 does not keep
+// (and bill) the parts already sent.
+func (s *storageClient) uploadMultipart(key string, body io.Reader, first []byte, contentType string) error {
+	ctx := context.Background()
+
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return err
+	}
+
+	parts, err := s.uploadParts(ctx, key, created.UploadId, body, first)
+	if err == nil {
+		_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(s.bucket),
+			Key:             aws.String(key),
+			UploadId:        created.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		})
+	}
+
+	if err != nil {
+		_, _ = s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s.bucket),
+			Key:      aws.String(key),
+			UploadId: created.UploadId,
+		})
+		return err
+	}
+	return nil
+}
+
+// uploadParts sends 'buf' as the first part, then reuses it to read and send
+// the next ones until 'body' is drained.
+func (s *storageClient) uploadParts(ctx context.Context, key string, uploadID *string, body io.Reader, buf []byte) ([]types.CompletedPart, error) {
+	var parts []types.CompletedPart
+	chunk := buf
+
+	for number := int32(1); ; number++ {
+		out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(s.bucket),
+			Key:        aws.String(key),
+			UploadId:   uploadID,
+			PartNumber: aws.Int32(number),
+			Body:       bytes.NewReader(chunk),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, types.CompletedPart{
+			ETag:       out.ETag,
+			PartNumber: aws.Int32(number),
+		})
+
+		n, err := io.ReadFull(body, buf)
+		if errors.Is(err, io.EOF) {
+			return parts, nil
+		}
+
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, err
+		}
+		chunk = buf[:n]
+	}
+}
+
+func (s *storageClient) DeleteFile(key string) error {
+	if key == "" {
+		return storage.ErrorEmptyKey
+	}
+
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+
+	_, err := s.client.DeleteObject(context.Background(), input)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// MoveFile copies the object on the S3 side, then deletes the source.
+// Attachments are well under the 5 GB limit of a single CopyObject.
+func (s *storageClient) MoveFile(src, dst string) error {
+	if src == "" || dst == "" {
+		return storage.ErrorEmptyKey
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(dst),
+		CopySource: aws.String(url.PathEscape(s.bucket + "/" + src)),
+	}
+
+	_, err := s.client.CopyObject(context.Background(), input)
+	if err != nil {
+		return mapNotFound(err)
+	}
+	return s.DeleteFile(src)
+}
+
+func (s *storageClient) GetFile(key string) (*storage.Object, error) {
+	if key == "" {
+		return nil, storage.ErrorEmptyKey
+	}
+
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+
+	head, err := s.client.HeadObject(context.Background(), input)
+	if err != nil {
+		return nil, mapNotFound(err)
+	}
+
+	object := &storage.Object{
+		Body: &s3ObjectReader{
+			client: s.client,
+			bucket: s.bucket,
+			key:    key,
+			size:   aws.ToInt64(head.ContentLength),
+		},
+		ContentType: aws.ToString(head.ContentType),
+		Size:        aws.ToInt64(head.ContentLength),
+	}
+	if head.LastModified != nil {
+		object.ModTime = *head.LastModified
+	}
+	return object, nil
+}
+
+func (s *storageClient) PresignGetFile(key string, ttl time.Duration) (string, error) {
+	if key == "" {
+		return "", storage.ErrorEmptyKey
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+
+	presigned, err := s3.NewPresignClient(s.client).PresignGetObject(context.Background(), input, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", err
+	}
+	return presigned.URL, nil
+}
+
+// mapNotFound turns the S3 missing object errors into storage.ErrorObjectNotFound.
+func mapNotFound(err error) error {
+	var notFound *types.NotFound
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &notFound) || errors.As(err, &noSuchKey) {
+		return storage.ErrorObjectNotFound
+	}
+	return err
+}
@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// metadataSuffix names the sidecar file holding the metadata of an object.
+const metadataSuffix = ".meta.json"
+
+type objectMetadata struct {
+	ContentType string `json:"content_type"`
+}
+
+// LocalStorage keeps objects as plain files under a root directory, with the
+// same keys used on S3. It lets the server run without AWS, offline or in tests.
+type LocalStorage struct {
+	root string
+}
+
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(root, 0o750); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+// UploadFile streams the object to a temporary file first, then renames it,
+// so readers never see a partially written object.
+func (l *LocalStorage) UploadFile(body io.Reader, key, contentType string) error {
+	path, err := l.resolve(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	sniffer := bufio.NewReaderSize(body, SniffLen)
+	if contentType == "" {
+		head, err := sniffer.Peek(SniffLen)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		contentType = DetectContentType(key, head)
+	}
+
+	meta, err := json.Marshal(&objectMetadata{ContentType: contentType})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := writeTemp(filepath.Dir(path), sniffer)
+	if err != nil {
+		return err
+	}
+
+	// Only does something when the rename below did not happen
+	defer os.Remove(tmp)
+
+	if err = writeFileAtomic(path+metadataSuffix, bytes.NewReader(meta)); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// DeleteFile is idempotent, deleting a missing object is not an error.
+func (l *LocalStorage) DeleteFile(key string) error {
+	path, err := l.resolve(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err = os.Remove(path + metadataSuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// MoveFile renames the metadata before the object, so the object never shows
+// up under 'dst' with the metadata of the file it replaces.
+func (l *LocalStorage) MoveFile(src, dst string) error {
+	srcPath, err := l.resolve(src)
+	if err != nil {
+		return err
+	}
+
+	dstPath, err := l.resolve(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err = os.Stat(srcPath); errors.Is(err, fs.ErrNotExist) {
+		return ErrorObjectNotFound
+	}
+
+	if err = os.MkdirAll(filepath.Dir(dstPath), 0o750); err != nil {
+		return err
+	}
+
+	err = os.Rename(srcPath+metadataSuffix, dstPath+metadataSuffix)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.Rename(srcPath, dstPath)
+}
+
+func (l *LocalStorage) GetFile(key string) (*Object, error) {
+	path, err := l.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrorObjectNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &Object{
+		Body:        file,
+		ContentType: l.contentType(path, key),
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+// PresignGetFile is not supported, files on disk are only reachable through the API.
+func (l *LocalStorage) PresignGetFile(string, time.Duration) (string, error) {
+	return "", ErrorPresignUnsupported
+}
+
+// contentType reads the content type from the sidecar metadata. Objects written
+// by hand may not have one, so the key extension is used instead.
+func (l *LocalStorage) contentType(path, key string) string {
+	var meta objectMetadata
+	if raw, err := os.ReadFile(path + metadataSuffix); err == nil && json.Unmarshal(raw, &meta) == nil && meta.ContentType != "" {
+		return meta.ContentType
+	}
+
+	if mimeType := mime.TypeByExtension(filepath.Ext(key)); mimeType != "" {
+		return mimeType
+	}
+	return "application/octet-stream"
+}
+
+// resolve maps a key to its path under the root, rejecting keys that would escape it.
+func (l *LocalStorage) resolve(key string) (string, error) {
+	if key == "" {
+		return "", ErrorEmptyKey
+	}
+
+	path := filepath.Join(l.root, filepath.FromSlash(key))
+	rel, err := filepath.Rel(l.root, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrorInvalidKey
+	}
+
+	if strings.HasSuffix(path, metadataSuffix) {
+		return "", ErrorInvalidKey
+	}
+	return path, nil
+}
+
+func writeFileAtomic(path string, r io.Reader) error {
+	tmp, err := writeTemp(filepath.Dir(path), r)
+	if err != nil {
+		return err
+	}
+
+	// Only does something when the rename below did not happen
+	defer os.Remove(tmp)
+
+	return os.Rename(tmp, path)
+}
+
+// writeTemp copies 'r' into a new temporary file of 'dir', synced to disk, and
+// returns its path. Nothing is left behind on failure.
+func writeTemp(dir string, r io.Reader) (string, error) {
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = io.Copy(tmp, r); err == nil {
+		err = tmp.Sync()
+	}
+
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestLocalStorage(t *testing.T) (*LocalStorage, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	local, err := NewLocalStorage(root)
+	if err != nil {
+		t.Fatalf("create local storage: %v", err)
+	}
+	return local, root
+}
+
+func readObject(t *testing.T, local *LocalStorage, key string) (string, string) {
+	t.Helper()
+
+	object, err := local.GetFile(key)
+	if err != nil {
+		t.Fatalf("get %s: %v", key, err)
+	}
+	defer object.Body.Close()
+
+	data, err := io.ReadAll(object.Body)
+	if err != nil {
+		t.Fatalf("read %s: %v", key, err)
+	}
+	return string(data), object.ContentType
+}
+
+func TestResolveRejectsKeysOutsideTheRoot(t *testing.T) {
+	local, root := newTestLocalStorage(t)
+
+	path, err := local.resolve("attachments/report.pdf")
+	if err != nil || path != filepath.Join(root, "attachments", "report.pdf") {
+		t.Fatalf("expected a path under the root, got %q (%v)", path, err)
+	}
+
+	for _, key := range []string{"..", "../secret", "attachments/../../secret", "/../secret", ".", "attachments/report.pdf.meta.json"} {
+		if _, err = local.resolve(key); err != ErrorInvalidKey {
+			t.Errorf("expected %q to be rejected, got %v", key, err)
+		}
+	}
+
+	if _, err = local.resolve(""); err != ErrorEmptyKey {
+		t.Fatalf("expected ErrorEmptyKey, got %v", err)
+	}
+}
+
+// failingReader gives some data, then fails, like an interrupted upload.
+type failingReader struct {
+	data string
+	done bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, errors.New("connection reset")
+	}
+	r.done = true
+	return copy(p, r.data), nil
+}
+
+func TestUploadFileLeavesTheObjectAloneOnFailure(t *testing.T) {
+	local, root := newTestLocalStorage(t)
+
+	if err := local.UploadFile(strings.NewReader("first version"), "attachments/notes.txt", ""); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	err := local.UploadFile(&failingReader{data: "second"}, "attachments/notes.txt", "")
+	if err == nil {
+		t.Fatal("expected the interrupted upload to fail")
+	}
+
+	if data, _ := readObject(t, local, "attachments/notes.txt"); data != "first version" {
+		t.Fatalf("expected the previous object to be kept, got %q", data)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, "attachments"))
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".upload-") {
+			t.Fatalf("expected no temporary file to be left, found %s", entry.Name())
+		}
+	}
+
+	if err = local.UploadFile(strings.NewReader("second version"), "attachments/notes.txt", ""); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if data, _ := readObject(t, local, "attachments/notes.txt"); data != "second version" {
+		t.Fatalf("expected the object to be replaced, got %q", data)
+	}
+}
+
+func TestUploadFileKeepsTheContentTypeInASidecar(t *testing.T) {
+	local, root := newTestLocalStorage(t)
+
+	if err := local.UploadFile(strings.NewReader("%PDF-1.4 slides"), "uploads/tmp", ""); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if _, contentType := readObject(t, local, "uploads/tmp"); contentType != "application/pdf" {
+		t.Fatalf("expected the sniffed content type, got %q", contentType)
+	}
+
+	if err := local.UploadFile(strings.NewReader("a,b"), "uploads/data.bin", "text/csv"); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if _, contentType := readObject(t, local, "uploads/data.bin"); contentType != "text/csv" {
+		t.Fatalf("expected the given content type, got %q", contentType)
+	}
+
+	// Files written by hand have no sidecar
+	if err := os.WriteFile(filepath.Join(root, "uploads", "hand.txt"), []byte("hi"), 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if _, contentType := readObject(t, local, "uploads/hand.txt"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Fatalf("expected the content type of the extension, got %q", contentType)
+	}
+}
+
+func TestMoveFileCarriesItsContentType(t *testing.T) {
+	local, _ := newTestLocalStorage(t)
+
+	if err := local.UploadFile(strings.NewReader("%PDF-1.4 slides"), "uploads/tmp", "application/pdf"); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if err := local.UploadFile(strings.NewReader("old"), "attachments/slides.bin", "text/plain"); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	if err := local.MoveFile("uploads/tmp", "attachments/slides.bin"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+
+	data, contentType := readObject(t, local, "attachments/slides.bin")
+	if data != "%PDF-1.4 slides" || contentType != "application/pdf" {
+		t.Fatalf("expected the moved object and its content type, got %q (%s)", data, contentType)
+	}
+
+	if _, err := local.GetFile("uploads/tmp"); err != ErrorObjectNotFound {
+		t.Fatalf("expected the source to be gone, got %v", err)
+	}
+	if err := local.MoveFile("uploads/tmp", "attachments/other.pdf"); err != ErrorObjectNotFound {
+		t.Fatalf("expected moving a missing object to fail, got %v", err)
+	}
+	if err := local.MoveFile("attachments/slides.bin", "../slides.bin"); err != ErrorInvalidKey {
+		t.Fatalf("expected moving out of the root to fail, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+const (
+	PathAttachments = "attachments/"
+	PathThumbnails  = "thumbnails/"
+	// PathUploads holds uploads until their hash is known. Objects left there
+	// come from interrupted uploads and can be safely expired.
+	PathUploads = "uploads/"
+	// PathImports holds note archives until their import is done.
+	PathImports = "imports/"
+)
+
+// SniffLen is how many leading bytes content type detection looks at.
+const SniffLen = 512
+
+var (
+	ErrorEmptyKey           = errors.New("key is empty")
+	ErrorInvalidKey         = errors.New("key escapes the storage root")
+	ErrorObjectNotFound     = errors.New("object not found")
+	ErrorPresignUnsupported = errors.New("storage does not support presigned urls")
+)
+
+// Backend stores the note files under keys built from the Path prefixes.
+// It is implemented on S3 and on the local filesystem.
+type Backend interface {
+	// UploadFile streams 'body' into the object, replacing it if it exists.
+	// The content type is guessed from the key and data when empty.
+	UploadFile(body io.Reader, key, contentType string) error
+	DeleteFile(key string) error
+	// MoveFile renames the object 'src' to 'dst', replacing 'dst' if it exists.
+	MoveFile(src, dst string) error
+	// GetFile opens the object for reading, callers must close its Body.
+	GetFile(key string) (*Object, error)
+	// PresignGetFile returns an URL allowing anyone to download the object until 'ttl' elapses.
+	PresignGetFile(key string, ttl time.Duration) (string, error)
+}
+
+// Object is a stored file opened for reading. Callers must close its Body.
+type Object struct {
+	Body        io.ReadSeekCloser
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// DetectContentType guesses the content type of an object from its key
+// extension, then from its first bytes.
+func DetectContentType(key string, data []byte) string {
+	mimeType := mime.TypeByExtension(filepath.Ext(key))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return mimeType
+}
@@ -82,6 +82,25 @@ func appendAuditIntChange(changes *[]*entity.AuditLogChange, field string, oldVa
 	})
 }
 
+// appendAuditOptionalIntChange records a nullable int, where nil is stored as a missing value.
+func appendAuditOptionalIntChange(changes *[]*entity.AuditLogChange, field string, oldValue, newValue *int) {
+	if (oldValue == nil && newValue == nil) || (oldValue != nil && newValue != nil && *oldValue == *newValue) {
+		return
+	}
+
+	change := &entity.AuditLogChange{
+		FieldName: field,
+		ValueType: entity.AuditValueTypeInt,
+	}
+	if oldValue != nil {
+		change.OldValue = auditValuePtr(strconv.Itoa(*oldValue))
+	}
+	if newValue != nil {
+		change.NewValue = auditValuePtr(strconv.Itoa(*newValue))
+	}
+	*changes = append(*changes, change)
+}
+
 func appendAuditStringArrayChange(changes *[]*entity.AuditLogChange, field string, oldValue, newValue []string) {
 	oldJSON := auditJSONString(oldValue)
 	newJSON := auditJSONString(newValue)
@@ -2,9 +2,11 @@ package service
 
 import (
 	"context"
+	"io"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/glebarez/sqlite"
 	"github.com/go-playground/validator/v10"
@@ -13,8 +15,10 @@ import (
 	"simplenotes/cmd/internal/contract"
 	"simplenotes/cmd/internal/domain/entity"
 	"simplenotes/cmd/internal/domain/policy"
+	notesdb "simplenotes/cmd/internal/domain/sqlite"
 	"simplenotes/cmd/internal/domain/sqlite/repository"
 	cognitoclient "simplenotes/cmd/internal/infrastructure/aws/cognito"
+	"simplenotes/cmd/internal/infrastructure/storage"
 	"simplenotes/cmd/internal/utils"
 	"simplenotes/cmd/internal/utils/validators"
 )
@@ -33,10 +37,20 @@ type noopGateway struct{}
 func (noopGateway) PostToConnection(context.Context, string, interface{}) error { return nil }
 func (noopGateway) DeleteConnection(context.Context, string) error              { return nil }
 
-type noopS3 struct{}
+type noopStorage struct{}
 
-func (noopS3) UploadFile([]byte, string) error { return nil }
-func (noopS3) DeleteFile(string) error         { return nil }
+func (noopStorage) UploadFile(body io.Reader, _, _ string) error {
+	_, err := io.Copy(io.Discard, body)
+	return err
+}
+func (noopStorage) DeleteFile(string) error       { return nil }
+func (noopStorage) MoveFile(string, string) error { return nil }
+func (noopStorage) GetFile(string) (*storage.Object, error) {
+	return nil, storage.ErrorObjectNotFound
+}
+func (noopStorage) PresignGetFile(string, time.Duration) (string, error) {
+	return "", storage.ErrorPresignUnsupported
+}
 
 type fakeCognitoClient struct{}
 
@@ -70,9 +84,10 @@ func TestNoteUpdateAuditGroupsMultipleFieldChanges(t *testing.T) {
 	auditSvc := newTestAuditService(t, db, 1000)
 	userRepo := repository.NewUserRepository(db)
 	noteRepo := repository.NewNoteRepository(db)
+	grantRepo := repository.NewNoteGrantRepository(db)
 	connRepo := repository.NewConnectionRepository(db)
 	wsSvc := NewWebSocketService(connRepo, noopGateway{})
-	noteSvc := NewNoteService(db, noteRepo, userRepo, wsSvc, noopS3{}, validate, auditSvc, policy.NewNotePolicy())
+	noteSvc := NewNoteService(db, noteRepo, repository.NewNoteRevisionRepository(db), repository.NewNoteTextRepository(db), repository.NewAttachmentRepository(db), repository.NewNoteAttachmentRepository(db), grantRepo, repository.NewFolderRepository(db), repository.NewTagRepository(db), repository.NewNoteLinkRepository(db), repository.NewNoteRenderRepository(db), userRepo, wsSvc, noopStorage{}, validate, auditSvc, policy.NewNotePolicy(grantRepo), policy.NewFolderPolicy())
 
 	actor := &entity.User{
 		Username:    "editor",
@@ -109,7 +124,7 @@ func TestNoteUpdateAuditGroupsMultipleFieldChanges(t *testing.T) {
 		Tags:       []string{"gamma", "delta"},
 	}
 
-	resp, apierr := noteSvc.UpdateNote(actor, note.ID, req)
+	resp, apierr := noteSvc.UpdateNote(actor, note.ID, req, nil)
 	if apierr != nil {
 		t.Fatalf("update note returned api error: %#v", apierr)
 	}
@@ -182,7 +197,7 @@ func TestDeleteUserCreatesAuditEvent(t *testing.T) {
 		t.Fatalf("save target: %v", err)
 	}
 
-	if apierr := userSvc.DeleteUser(actor, strconv.Itoa(target.ID)); apierr != nil {
+	if apierr := userSvc.DeleteUser(actor, strconv.Itoa(target.ID), nil); apierr != nil {
 		t.Fatalf("delete user returned api error: %#v", apierr)
 	}
 
@@ -346,6 +361,19 @@ func newTestDB(t *testing.T) *gorm.DB {
 		&entity.AuditLogEvent{},
 		&entity.AuditLogChange{},
 		&entity.Note{},
+		&entity.NoteRevision{},
+		&entity.NoteGrant{},
+		&entity.Folder{},
+		&entity.Tag{},
+		&entity.NoteTag{},
+		&entity.NoteText{},
+		&entity.Attachment{},
+		&entity.NoteAttachment{},
+		&entity.NoteLink{},
+		&entity.NoteRender{},
+		&entity.NoteImport{},
+		&entity.NoteImportFile{},
+		&entity.NoteTemplate{},
 		&entity.User{},
 		&entity.Connection{},
 		&entity.Company{},
@@ -354,6 +382,10 @@ func newTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("automigrate: %v", err)
 	}
 
+	if err := notesdb.MigrateNoteSearch(db); err != nil {
+		t.Fatalf("migrate note search: %v", err)
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		t.Fatalf("sql db: %v", err)
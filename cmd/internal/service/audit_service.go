@@ -132,6 +132,11 @@ func (a *AuditService) Record(tx *gorm.DB, event *entity.AuditLogEvent) error {
 	return targetDB.Create(&event.Changes).Error
 }
 
+// NewBatchID returns an id for the events of one bulk operation to share.
+func (a *AuditService) NewBatchID() (int64, error) {
+	return a.IDGen.NextID()
+}
+
 func (a *AuditService) GetAuditLogs(actor *entity.User, req *contract.AuditLogListRequest) (*contract.AuditLogListResponse, apierror.ErrorResponse) {
 	if !actor.Permissions.HasEffective(entity.PermissionManageUsers) {
 		return nil, apierror.NewPermissionError(int64(entity.PermissionManageUsers))
@@ -177,6 +182,7 @@ func (a *AuditService) toAuditFilter(req *contract.AuditLogListRequest) (*reposi
 		}
 		filter.BeforeID = req.BeforeID
 		filter.ActorUserID = req.ActorUserID
+		filter.BatchID = req.BatchID
 
 		if req.SubjectType != nil {
 			subjectType := entity.AuditSubjectType(strings.TrimSpace(*req.SubjectType))
@@ -208,7 +214,8 @@ func (a *AuditService) toAuditFilter(req *contract.AuditLogListRequest) (*reposi
 
 func isAuditSubjectTypeValid(subjectType entity.AuditSubjectType) bool {
 	switch subjectType {
-	case entity.AuditSubjectNote, entity.AuditSubjectUser, entity.AuditSubjectCompany:
+	case entity.AuditSubjectNote, entity.AuditSubjectUser, entity.AuditSubjectCompany, entity.AuditSubjectFolder, entity.AuditSubjectTag,
+		entity.AuditSubjectTemplate:
 		return true
 	default:
 		return false
@@ -220,6 +227,20 @@ func isAuditActionTypeValid(actionType entity.AuditActionType) bool {
 	case entity.AuditActionNoteCreate,
 		entity.AuditActionNoteUpdate,
 		entity.AuditActionNoteDelete,
+		entity.AuditActionNoteRestore,
+		entity.AuditActionNotePurge,
+		entity.AuditActionNoteRevisionRestore,
+		entity.AuditActionNoteShare,
+		entity.AuditActionNoteUnshare,
+		entity.AuditActionFolderCreate,
+		entity.AuditActionFolderUpdate,
+		entity.AuditActionFolderDelete,
+		entity.AuditActionTagRename,
+		entity.AuditActionTagMerge,
+		entity.AuditActionTagDelete,
+		entity.AuditActionTemplateCreate,
+		entity.AuditActionTemplateUpdate,
+		entity.AuditActionTemplateDelete,
 		entity.AuditActionUserUpdate,
 		entity.AuditActionUserSuspend,
 		entity.AuditActionUserUnsuspend,
@@ -243,6 +264,11 @@ func toAuditEventResponse(event *entity.AuditLogEvent) *contract.AuditLogEventRe
 		Changes:     make([]*contract.AuditLogChangeResponse, len(event.Changes)),
 	}
 
+	if event.BatchID != nil {
+		batchID := strconv.FormatInt(*event.BatchID, 10)
+		resp.BatchID = &batchID
+	}
+
 	for i, change := range event.Changes {
 		resp.Changes[i] = &contract.AuditLogChangeResponse{
 			ID:        change.ID,
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/policy"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strconv"
+	"testing"
+)
+
+func TestPrivateFolderPropagatesToContentAndDeleteReparents(t *testing.T) {
+	db := newTestDB(t)
+
+	noteSvc := newTestNoteService(t, db, 12000)
+	folderSvc := NewFolderService(
+		db,
+		repository.NewFolderRepository(db),
+		repository.NewUserRepository(db),
+		noteSvc,
+		noteSvc.WSService,
+		newTestValidator(),
+		noteSvc.Audit,
+		policy.NewFolderPolicy(),
+	)
+	owner := newTestWriter(t, db)
+
+	root, apierr := folderSvc.CreateFolder(owner, &contract.FolderRequest{
+		Name:       "Projects",
+		Visibility: string(entity.VisibilityPublic),
+	})
+	if apierr != nil {
+		t.Fatalf("create root folder returned api error: %#v", apierr)
+	}
+
+	child, apierr := folderSvc.CreateFolder(owner, &contract.FolderRequest{
+		Name:       "Roadmaps",
+		ParentID:   &root.ID,
+		Visibility: string(entity.VisibilityPublic),
+	})
+	if apierr != nil {
+		t.Fatalf("create child folder returned api error: %#v", apierr)
+	}
+
+	note, apierr := noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+		Name:       "Q3",
+		Content:    "ship folders",
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"plan"},
+		FolderID:   &child.ID,
+	})
+	if apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+
+	private := string(entity.VisibilityPrivate)
+	if _, apierr = folderSvc.UpdateFolder(owner, root.ID, &contract.UpdateFolderRequest{Visibility: &private}); apierr != nil {
+		t.Fatalf("update folder returned api error: %#v", apierr)
+	}
+
+	updatedChild, apierr := folderSvc.GetFolderByID(owner, child.ID)
+	if apierr != nil {
+		t.Fatalf("get child folder returned api error: %#v", apierr)
+	}
+	if updatedChild.Visibility != private {
+		t.Fatalf("expected child folder to become private, got %q", updatedChild.Visibility)
+	}
+
+	updatedNote, apierr := noteSvc.GetNoteByID(owner, note.ID)
+	if apierr != nil {
+		t.Fatalf("get note returned api error: %#v", apierr)
+	}
+	if updatedNote.Visibility != private {
+		t.Fatalf("expected note to become private, got %q", updatedNote.Visibility)
+	}
+
+	// The folder change and its cascade share one batch
+	folderEvents, err := repository.NewAuditRepository(db).List(&repository.AuditLogFilter{
+		Limit:      10,
+		ActionType: auditActionPtr(entity.AuditActionFolderUpdate),
+	})
+	if err != nil {
+		t.Fatalf("list audit logs: %v", err)
+	}
+	if len(folderEvents) != 2 || folderEvents[0].BatchID == nil {
+		t.Fatalf("expected 2 batched folder update events, got %#v", folderEvents)
+	}
+
+	batched, err := repository.NewAuditRepository(db).List(&repository.AuditLogFilter{
+		Limit:   10,
+		BatchID: folderEvents[0].BatchID,
+	})
+	if err != nil {
+		t.Fatalf("list audit logs: %v", err)
+	}
+	if len(batched) != 3 {
+		t.Fatalf("expected both folders and the note in the batch, got %d events", len(batched))
+	}
+
+	public := string(entity.VisibilityPublic)
+	if _, apierr = noteSvc.UpdateNote(owner, note.ID, &contract.UpdateNoteRequest{Visibility: &public}, nil); apierr == nil || apierr.Code() != 400 {
+		t.Fatalf("expected public note inside a private folder to be rejected, got %#v", apierr)
+	}
+
+	if _, apierr = folderSvc.MoveFolder(owner, root.ID, &contract.MoveFolderRequest{ParentID: &child.ID}); apierr == nil || apierr.Code() != 400 {
+		t.Fatalf("expected moving a folder into its own subfolder to be rejected, got %#v", apierr)
+	}
+
+	if apierr = folderSvc.DeleteFolder(owner, root.ID); apierr != nil {
+		t.Fatalf("delete folder returned api error: %#v", apierr)
+	}
+
+	orphan, apierr := folderSvc.GetFolderByID(owner, child.ID)
+	if apierr != nil {
+		t.Fatalf("get child folder returned api error: %#v", apierr)
+	}
+	if orphan.ParentID != nil {
+		t.Fatalf("expected child folder to move to the root, got parent %d", *orphan.ParentID)
+	}
+
+	listed, apierr := noteSvc.GetAllNotes(owner, &contract.NoteListRequest{FolderID: &child.ID})
+	if apierr != nil {
+		t.Fatalf("list notes returned api error: %#v", apierr)
+	}
+	if len(listed.Notes) != 1 || listed.Notes[0].ID != note.ID {
+		t.Fatalf("expected the note to stay in its folder, got %#v", listed.Notes)
+	}
+
+	// Notes of a deleted folder move up like a MoveNote would, linked to the deletion
+	if apierr = folderSvc.DeleteFolder(owner, child.ID); apierr != nil {
+		t.Fatalf("delete folder returned api error: %#v", apierr)
+	}
+
+	moved, apierr := noteSvc.GetNoteByID(owner, note.ID)
+	if apierr != nil {
+		t.Fatalf("get note returned api error: %#v", apierr)
+	}
+	if moved.FolderID != nil || moved.Version != updatedNote.Version+1 {
+		t.Fatalf("expected the note moved to the root with a new version, got %#v", moved)
+	}
+
+	subjectType := entity.AuditSubjectFolder
+	subjectID := strconv.Itoa(child.ID)
+	auditRepo := repository.NewAuditRepository(db)
+	deleted, err := auditRepo.List(&repository.AuditLogFilter{
+		Limit:       1,
+		ActionType:  auditActionPtr(entity.AuditActionFolderDelete),
+		SubjectType: &subjectType,
+		SubjectID:   &subjectID,
+	})
+	if err != nil || len(deleted) != 1 || deleted[0].BatchID == nil {
+		t.Fatalf("expected the folder deletion to start a batch, got %#v (%v)", deleted, err)
+	}
+
+	batch, err := auditRepo.List(&repository.AuditLogFilter{Limit: 10, BatchID: deleted[0].BatchID})
+	if err != nil {
+		t.Fatalf("list audit events: %v", err)
+	}
+	if len(batch) != 2 || batch[0].ActionType != entity.AuditActionFolderDelete ||
+		batch[1].ActionType != entity.AuditActionNoteUpdate || batch[1].SubjectID != strconv.Itoa(note.ID) {
+		t.Fatalf("expected the folder deletion and the note move in the batch, got %#v", batch)
+	}
+}
+
+func TestFolderOwnersCannotHideNotesOfOthers(t *testing.T) {
+	db := newTestDB(t)
+
+	noteSvc := newTestNoteService(t, db, 16300)
+	folderSvc := NewFolderService(
+		db,
+		repository.NewFolderRepository(db),
+		repository.NewUserRepository(db),
+		noteSvc,
+		noteSvc.WSService,
+		newTestValidator(),
+		noteSvc.Audit,
+		policy.NewFolderPolicy(),
+	)
+	writer := newTestWriter(t, db)
+
+	owner := &entity.User{
+		Username:    "owner",
+		Email:       "owner@example.com",
+		Permissions: entity.PermissionCreateNotes,
+		Active:      true,
+		CreatedAt:   utils.NowUTC(),
+		UpdatedAt:   utils.NowUTC(),
+	}
+	if err := repository.NewUserRepository(db).Save(owner); err != nil {
+		t.Fatalf("save owner: %v", err)
+	}
+
+	folder, apierr := folderSvc.CreateFolder(owner, &contract.FolderRequest{
+		Name:       "Shared",
+		Visibility: string(entity.VisibilityPublic),
+	})
+	if apierr != nil {
+		t.Fatalf("create folder returned api error: %#v", apierr)
+	}
+
+	note, apierr := noteSvc.CreateTextNote(writer, &contract.TextNoteRequest{
+		Name:       "Guide",
+		Content:    "for everyone",
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{},
+		FolderID:   &folder.ID,
+	})
+	if apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+
+	private := string(entity.VisibilityPrivate)
+	_, apierr = folderSvc.UpdateFolder(owner, folder.ID, &contract.UpdateFolderRequest{Visibility: &private})
+	if apierr != apierror.FolderForeignNotesError {
+		t.Fatalf("expected the owner to be denied, got %#v", apierr)
+	}
+
+	unchanged, apierr := noteSvc.GetNoteByID(owner, note.ID)
+	if apierr != nil {
+		t.Fatalf("get note returned api error: %#v", apierr)
+	}
+	if unchanged.Visibility != string(entity.VisibilityPublic) {
+		t.Fatalf("expected the note to stay public, got %q", unchanged.Visibility)
+	}
+
+	// The writer can edit every note, so the folder can be made private by them
+	if _, apierr = folderSvc.UpdateFolder(writer, folder.ID, &contract.UpdateFolderRequest{Visibility: &private}); apierr != nil {
+		t.Fatalf("update folder returned api error: %#v", apierr)
+	}
+}
@@ -0,0 +1,581 @@
+package service
+
+import (
+	"context"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/events"
+	"simplenotes/cmd/internal/domain/policy"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+type FolderRepository interface {
+	FindAllVisible(viewerID int, withPrivate bool) ([]*entity.Folder, error)
+	FindByID(id int) (*entity.Folder, error)
+	FindSubtreeWithDB(db *gorm.DB, id int) ([]*entity.Folder, error)
+	SaveWithDB(db *gorm.DB, folder *entity.Folder) error
+	DeleteWithDB(db *gorm.DB, folder *entity.Folder) error
+	ReparentChildrenWithDB(db *gorm.DB, folderID int, parentID *int) error
+}
+
+type FolderService struct {
+	DB           *gorm.DB
+	FolderRepo   FolderRepository
+	UserRepo     UserRepository
+	Notes        *NoteService
+	WSService    *WebSocketService
+	Validate     *validator.Validate
+	Audit        *AuditService
+	FolderPolicy *policy.FolderPolicy
+}
+
+func NewFolderService(
+	db *gorm.DB,
+	folderRepo FolderRepository,
+	userRepo UserRepository,
+	noteService *NoteService,
+	wsService *WebSocketService,
+	validate *validator.Validate,
+	auditService *AuditService,
+	folderPolicy *policy.FolderPolicy,
+) *FolderService {
+	return &FolderService{
+		DB:           db,
+		FolderRepo:   folderRepo,
+		UserRepo:     userRepo,
+		Notes:        noteService,
+		WSService:    wsService,
+		Validate:     validate,
+		Audit:        auditService,
+		FolderPolicy: folderPolicy,
+	}
+}
+
+// folderChange and noteChange keep the state before and after a change,
+// so websocket events can be computed once the transaction commits.
+type folderChange struct {
+	before *entity.Folder
+	after  *entity.Folder
+}
+
+type noteChange struct {
+	before *entity.Note
+	after  *entity.Note
+}
+
+// subtreeChanges lists what a visibility change did to the content of a folder.
+type subtreeChanges struct {
+	folders []folderChange
+	notes   []noteChange
+}
+
+// GetFolders returns every visible folder as a flat list, clients build the tree through ParentID.
+func (f *FolderService) GetFolders(actor *entity.User) ([]*contract.FolderResponse, apierror.ErrorResponse) {
+	withPrivate := actor.Permissions.HasEffective(entity.PermissionSeeHiddenNotes)
+	folders, err := f.FolderRepo.FindAllVisible(actor.ID, withPrivate)
+	if err != nil {
+		log.Errorf("failed to fetch folders: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	resp := make([]*contract.FolderResponse, len(folders))
+	for i, folder := range folders {
+		resp[i] = toFolderResponse(folder)
+	}
+	return resp, nil
+}
+
+func (f *FolderService) GetFolderByID(actor *entity.User, folderId int) (*contract.FolderResponse, apierror.ErrorResponse) {
+	folder, apierr := f.fetchFolder(folderId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	if apierr = f.FolderPolicy.CanSee(folder, actor); apierr != nil {
+		return nil, apierr
+	}
+	return toFolderResponse(folder), nil
+}
+
+func (f *FolderService) CreateFolder(actor *entity.User, req *contract.FolderRequest) (*contract.FolderResponse, apierror.ErrorResponse) {
+	if apierr := f.FolderPolicy.CanCreate(actor); apierr != nil {
+		return nil, apierr
+	}
+
+	utils.Sanitize(req)
+	if err := f.Validate.Struct(req); err != nil {
+		return nil, apierror.FromValidationError(err)
+	}
+
+	now := utils.NowUTC()
+	folder := &entity.Folder{
+		Name:        req.Name,
+		ParentID:    req.ParentID,
+		Visibility:  entity.NoteVisibility(req.Visibility),
+		CreatedByID: actor.ID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if req.ParentID != nil {
+		parent, apierr := f.fetchFolder(*req.ParentID)
+		if apierr != nil {
+			return nil, apierr
+		}
+		if apierr = f.FolderPolicy.CanSee(parent, actor); apierr != nil {
+			return nil, apierr
+		}
+		if parent.Visibility == entity.VisibilityPrivate {
+			folder.Visibility = entity.VisibilityPrivate
+		}
+	}
+
+	err := f.DB.Transaction(func(tx *gorm.DB) error {
+		if err := f.FolderRepo.SaveWithDB(tx, folder); err != nil {
+			return err
+		}
+
+		changes := []*entity.AuditLogChange{
+			newAuditCreateValue("name", entity.AuditValueTypeString, folder.Name),
+			newAuditCreateValue("visibility", entity.AuditValueTypeEnum, string(folder.Visibility)),
+		}
+		appendAuditOptionalIntChange(&changes, "parent_id", nil, folder.ParentID)
+		return f.recordFolderAudit(tx, actor, entity.AuditActionFolderCreate, folder, nil, changes)
+	})
+	if err != nil {
+		log.Errorf("failed to create folder: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	resp := toFolderResponse(folder)
+	go f.fanoutFolderEvent(nil, folder, &events.FolderCreated{FolderResponse: resp})
+	return resp, nil
+}
+
+// UpdateFolder renames the folder or changes its visibility. Making a folder
+// PRIVATE makes everything inside it PRIVATE as well.
+func (f *FolderService) UpdateFolder(actor *entity.User, folderId int, req *contract.UpdateFolderRequest) (*contract.FolderResponse, apierror.ErrorResponse) {
+	if req.IsEmpty() {
+		return nil, apierror.EmptyPatchCallError
+	}
+
+	folder, apierr := f.fetchFolder(folderId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	if apierr = f.FolderPolicy.CanUpdate(folder, actor); apierr != nil {
+		return nil, apierr
+	}
+
+	utils.Sanitize(req)
+	if err := f.Validate.Struct(req); err != nil {
+		return nil, apierror.FromValidationError(err)
+	}
+
+	before := *folder
+	if req.Name != nil {
+		folder.Name = *req.Name
+	}
+	if req.Visibility != nil {
+		folder.Visibility = entity.NoteVisibility(*req.Visibility)
+	}
+
+	if before.Visibility == entity.VisibilityPrivate && folder.Visibility == entity.VisibilityPublic {
+		private, apierr := f.Notes.isFolderPrivate(folder.ParentID)
+		if apierr != nil {
+			return nil, apierr
+		}
+		if private {
+			return nil, apierror.PrivateFolderContentError
+		}
+	}
+
+	return f.saveFolderChange(actor, &before, folder)
+}
+
+// MoveFolder moves the folder under another one, or to the root. Folders
+// moved into a PRIVATE folder become PRIVATE, along with their content.
+func (f *FolderService) MoveFolder(actor *entity.User, folderId int, req *contract.MoveFolderRequest) (*contract.FolderResponse, apierror.ErrorResponse) {
+	if err := f.Validate.Struct(req); err != nil {
+		return nil, apierror.FromValidationError(err)
+	}
+
+	folder, apierr := f.fetchFolder(folderId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	if apierr = f.FolderPolicy.CanUpdate(folder, actor); apierr != nil {
+		return nil, apierr
+	}
+
+	before := *folder
+	folder.ParentID = req.ParentID
+
+	if req.ParentID != nil {
+		parent, apierr := f.fetchFolder(*req.ParentID)
+		if apierr != nil {
+			return nil, apierr
+		}
+		if apierr = f.FolderPolicy.CanSee(parent, actor); apierr != nil {
+			return nil, apierr
+		}
+
+		subtree, err := f.FolderRepo.FindSubtreeWithDB(nil, folder.ID)
+		if err != nil {
+			log.Errorf("failed to fetch subtree of folder %d: %v", folder.ID, err)
+			return nil, apierror.InternalServerError
+		}
+		for _, descendant := range subtree {
+			if descendant.ID == parent.ID {
+				return nil, apierror.FolderCycleError
+			}
+		}
+
+		if parent.Visibility == entity.VisibilityPrivate {
+			folder.Visibility = entity.VisibilityPrivate
+		}
+	}
+
+	return f.saveFolderChange(actor, &before, folder)
+}
+
+// DeleteFolder deletes the folder. Its subfolders and notes move up to its parent,
+// each moved note getting a NOTE_UPDATE audit event in the batch of the deletion.
+func (f *FolderService) DeleteFolder(actor *entity.User, folderId int) apierror.ErrorResponse {
+	folder, apierr := f.fetchFolder(folderId)
+	if apierr != nil {
+		return apierr
+	}
+
+	if apierr = f.FolderPolicy.CanDelete(folder, actor); apierr != nil {
+		return apierr
+	}
+
+	batchID, err := f.Audit.NewBatchID()
+	if err != nil {
+		log.Errorf("failed to generate batch id: %v", err)
+		return apierror.InternalServerError
+	}
+
+	var moved []noteChange
+	err = f.DB.Transaction(func(tx *gorm.DB) error {
+		if err := f.FolderRepo.ReparentChildrenWithDB(tx, folder.ID, folder.ParentID); err != nil {
+			return err
+		}
+
+		var err error
+		if moved, err = f.moveFolderNotes(tx, actor, folder, batchID); err != nil {
+			return err
+		}
+		if err := f.FolderRepo.DeleteWithDB(tx, folder); err != nil {
+			return err
+		}
+
+		changes := []*entity.AuditLogChange{
+			newAuditDeleteValue("name", entity.AuditValueTypeString, folder.Name),
+			newAuditDeleteValue("visibility", entity.AuditValueTypeEnum, string(folder.Visibility)),
+		}
+		appendAuditOptionalIntChange(&changes, "parent_id", folder.ParentID, nil)
+		return f.Audit.Record(tx, &entity.AuditLogEvent{
+			ActorUserID: &actor.ID,
+			ActionType:  entity.AuditActionFolderDelete,
+			SubjectType: entity.AuditSubjectFolder,
+			SubjectID:   strconv.Itoa(folder.ID),
+			Source:      entity.AuditSourceHTTPAPI,
+			BatchID:     &batchID,
+			Changes:     changes,
+		})
+	})
+	if err != nil {
+		log.Errorf("failed to delete folder %d: %v", folder.ID, err)
+		return apierror.InternalServerError
+	}
+
+	go func() {
+		f.fanoutFolderEvent(nil, folder, &events.FolderDeleted{
+			FolderID: folder.ID,
+			ParentID: folder.ParentID,
+		})
+		f.Notes.dispatchNoteChanges(moved)
+	}()
+	return nil
+}
+
+// moveFolderNotes moves the notes of the folder, trashed ones included, up to
+// its parent. Only the folder changes, the notes inherited its visibility already.
+func (f *FolderService) moveFolderNotes(tx *gorm.DB, actor *entity.User, folder *entity.Folder, batchID int64) ([]noteChange, error) {
+	notes, err := f.Notes.NoteRepo.FindAllByFolderIDsWithDB(tx, []int{folder.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	now := utils.NowUTC()
+	var changes []noteChange
+	for _, note := range notes {
+		fillLegacyContentHash(note)
+		before := *note
+		note.FolderID = folder.ParentID
+		note.UpdatedAt = now
+
+		if err := f.Notes.NoteRepo.SaveWithDB(tx, note); err != nil {
+			return nil, err
+		}
+		err := f.Audit.Record(tx, &entity.AuditLogEvent{
+			ActorUserID: &actor.ID,
+			ActionType:  entity.AuditActionNoteUpdate,
+			SubjectType: entity.AuditSubjectNote,
+			SubjectID:   strconv.Itoa(note.ID),
+			Source:      entity.AuditSourceHTTPAPI,
+			BatchID:     &batchID,
+			Changes:     buildNoteUpdateAuditChanges(&before, note),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Trashed notes are not known by clients
+		if note.DeletedAt == nil {
+			changes = append(changes, noteChange{before: &before, after: note})
+		}
+	}
+	return changes, nil
+}
+
+// saveFolderChange persists an updated or moved folder, and spreads a new
+// PRIVATE visibility down to everything inside it.
+func (f *FolderService) saveFolderChange(actor *entity.User, before, folder *entity.Folder) (*contract.FolderResponse, apierror.ErrorResponse) {
+	changes := buildFolderUpdateAuditChanges(before, folder)
+	if len(changes) == 0 {
+		return toFolderResponse(folder), nil
+	}
+
+	// Making the folder PRIVATE cascades to its content, all in one batch
+	var batchID *int64
+	if folder.Visibility == entity.VisibilityPrivate {
+		if apierr := f.checkSubtreeNotes(actor, folder); apierr != nil {
+			return nil, apierr
+		}
+
+		id, err := f.Audit.NewBatchID()
+		if err != nil {
+			log.Errorf("failed to generate batch id: %v", err)
+			return nil, apierror.InternalServerError
+		}
+		batchID = &id
+	}
+
+	now := utils.NowUTC()
+	folder.UpdatedAt = now
+
+	var subtree *subtreeChanges
+	err := f.DB.Transaction(func(tx *gorm.DB) error {
+		if err := f.FolderRepo.SaveWithDB(tx, folder); err != nil {
+			return err
+		}
+		if err := f.recordFolderAudit(tx, actor, entity.AuditActionFolderUpdate, folder, batchID, changes); err != nil {
+			return err
+		}
+
+		if folder.Visibility != entity.VisibilityPrivate {
+			return nil
+		}
+
+		var err error
+		subtree, err = f.privatizeSubtree(tx, actor, folder, now, batchID)
+		return err
+	})
+	if err != nil {
+		log.Errorf("failed to update folder %d: %v", folder.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	resp := toFolderResponse(folder)
+	go func() {
+		f.fanoutFolderEvent(before, folder, &events.FolderUpdated{FolderResponse: resp})
+		f.dispatchSubtreeEvents(subtree)
+	}()
+	return resp, nil
+}
+
+// checkSubtreeNotes makes sure the actor can update every note that making
+// 'root' PRIVATE would hide, so folder owners cannot hide the notes of others.
+func (f *FolderService) checkSubtreeNotes(actor *entity.User, root *entity.Folder) apierror.ErrorResponse {
+	folders, err := f.FolderRepo.FindSubtreeWithDB(nil, root.ID)
+	if err != nil {
+		log.Errorf("failed to fetch subtree of folder %d: %v", root.ID, err)
+		return apierror.InternalServerError
+	}
+
+	folderIDs := make([]int, len(folders))
+	for i, folder := range folders {
+		folderIDs[i] = folder.ID
+	}
+
+	notes, err := f.Notes.NoteRepo.FindAllByFolderIDsWithDB(nil, folderIDs)
+	if err != nil {
+		log.Errorf("failed to fetch notes of folder %d: %v", root.ID, err)
+		return apierror.InternalServerError
+	}
+
+	for _, note := range notes {
+		if note.Visibility == entity.VisibilityPrivate {
+			continue
+		}
+		if f.Notes.NotePolicy.CanUpdate(note, actor) != nil {
+			return apierror.FolderForeignNotesError
+		}
+	}
+	return nil
+}
+
+// privatizeSubtree makes every subfolder of 'root' and every note inside
+// them PRIVATE. Each changed folder and note gets its own audit event, in the
+// batch of the change made to 'root'.
+func (f *FolderService) privatizeSubtree(tx *gorm.DB, actor *entity.User, root *entity.Folder, now int64, batchID *int64) (*subtreeChanges, error) {
+	folders, err := f.FolderRepo.FindSubtreeWithDB(tx, root.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &subtreeChanges{}
+	folderIDs := make([]int, len(folders))
+	for i, folder := range folders {
+		folderIDs[i] = folder.ID
+		if folder.ID == root.ID || folder.Visibility == entity.VisibilityPrivate {
+			continue
+		}
+
+		before := *folder
+		folder.Visibility = entity.VisibilityPrivate
+		folder.UpdatedAt = now
+
+		if err := f.FolderRepo.SaveWithDB(tx, folder); err != nil {
+			return nil, err
+		}
+		auditChanges := buildFolderUpdateAuditChanges(&before, folder)
+		if err := f.recordFolderAudit(tx, actor, entity.AuditActionFolderUpdate, folder, batchID, auditChanges); err != nil {
+			return nil, err
+		}
+		changes.folders = append(changes.folders, folderChange{before: &before, after: folder})
+	}
+
+	notes, err := f.Notes.NoteRepo.FindAllByFolderIDsWithDB(tx, folderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, note := range notes {
+		if note.Visibility == entity.VisibilityPrivate {
+			continue
+		}
+
+		fillLegacyContentHash(note)
+		before := *note
+		note.Visibility = entity.VisibilityPrivate
+		note.UpdatedAt = now
+
+		change, err := f.Notes.saveRewrittenNote(tx, actor, &before, note, batchID)
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			changes.notes = append(changes.notes, *change)
+		}
+	}
+	return changes, nil
+}
+
+func (f *FolderService) recordFolderAudit(tx *gorm.DB, actor *entity.User, action entity.AuditActionType, folder *entity.Folder, batchID *int64, changes []*entity.AuditLogChange) error {
+	return f.Audit.Record(tx, &entity.AuditLogEvent{
+		ActorUserID: &actor.ID,
+		ActionType:  action,
+		SubjectType: entity.AuditSubjectFolder,
+		SubjectID:   strconv.Itoa(folder.ID),
+		Source:      entity.AuditSourceHTTPAPI,
+		BatchID:     batchID,
+		Changes:     changes,
+	})
+}
+
+func (f *FolderService) fetchFolder(folderId int) (*entity.Folder, apierror.ErrorResponse) {
+	folder, err := f.FolderRepo.FindByID(folderId)
+	if err != nil {
+		log.Errorf("failed to fetch folder %d: %v", folderId, err)
+		return nil, apierror.InternalServerError
+	}
+
+	if folder == nil {
+		return nil, apierror.NotFoundError
+	}
+	return folder, nil
+}
+
+// fanoutFolderEvent sends 'evt' to every connected user allowed to see 'folder'.
+// Users who could only see its 'before' state get a FolderDeleted event instead.
+func (f *FolderService) fanoutFolderEvent(before, folder *entity.Folder, evt events.SocketEvent) {
+	revoked := &events.FolderDeleted{FolderID: folder.ID}
+	f.WSService.BroadcastPerUser(context.Background(), func(userID int) events.SocketEvent {
+		recipient, err := f.UserRepo.FindActiveByID(userID)
+		if err != nil {
+			log.Errorf("failed to find user (%d) by id: %v", userID, err)
+			return nil
+		}
+
+		if recipient == nil {
+			return nil
+		}
+
+		if f.FolderPolicy.CanSee(folder, recipient) == nil {
+			return evt
+		}
+
+		if before != nil && f.FolderPolicy.CanSee(before, recipient) == nil {
+			return revoked
+		}
+		return nil
+	})
+}
+
+func (f *FolderService) dispatchSubtreeEvents(changes *subtreeChanges) {
+	if changes == nil {
+		return
+	}
+
+	for _, change := range changes.folders {
+		f.fanoutFolderEvent(change.before, change.after, &events.FolderUpdated{
+			FolderResponse: toFolderResponse(change.after),
+		})
+	}
+
+	for _, change := range changes.notes {
+		f.Notes.dispatchNoteUpdateEvent(change.before, change.after, toNoteResponse(change.after, false))
+	}
+}
+
+func buildFolderUpdateAuditChanges(before, after *entity.Folder) []*entity.AuditLogChange {
+	var changes []*entity.AuditLogChange
+	appendAuditStringChange(&changes, "name", before.Name, after.Name)
+	appendAuditEnumChange(&changes, "visibility", string(before.Visibility), string(after.Visibility))
+	appendAuditOptionalIntChange(&changes, "parent_id", before.ParentID, after.ParentID)
+	return changes
+}
+
+func toFolderResponse(folder *entity.Folder) *contract.FolderResponse {
+	return &contract.FolderResponse{
+		ID:          folder.ID,
+		Name:        folder.Name,
+		ParentID:    folder.ParentID,
+		Visibility:  string(folder.Visibility),
+		CreatedByID: folder.CreatedByID,
+		CreatedAt:   utils.FormatEpoch(folder.CreatedAt),
+		UpdatedAt:   utils.FormatEpoch(folder.UpdatedAt),
+	}
+}
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"simplenotes/cmd/internal/utils"
+)
+
+// ImportInterval is short, imports are started by users waiting on them.
+const ImportInterval = 5 * time.Second
+
+type NoteImportRunner interface {
+	RunPendingImports(now int64) (int, error)
+}
+
+type NoteImporter struct {
+	runner NoteImportRunner
+}
+
+func NewNoteImporter(runner NoteImportRunner) *NoteImporter {
+	return &NoteImporter{runner: runner}
+}
+
+func (i *NoteImporter) Start(ctx context.Context) {
+	ticker := time.NewTicker(ImportInterval)
+	defer ticker.Stop()
+
+	log.Info("Note importer cron started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Stopping note importer...")
+			return
+		case <-ticker.C:
+			i.run()
+		}
+	}
+}
+
+func (i *NoteImporter) run() {
+	now := utils.NowUTC()
+
+	ran, err := i.runner.RunPendingImports(now)
+	if err != nil {
+		log.Errorf("Importer: failed to run note imports after %d were done: %v", ran, err)
+		return
+	}
+
+	if ran > 0 {
+		log.Debugf("Importer: successfully ran %d note imports", ran)
+	}
+}
@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"simplenotes/cmd/internal/utils"
+)
+
+const ExtractInterval = 1 * time.Minute
+
+type NoteTextExtraction interface {
+	ExtractPendingTexts(now int64) (int, error)
+}
+
+type NoteTextExtractor struct {
+	extraction NoteTextExtraction
+}
+
+func NewNoteTextExtractor(extraction NoteTextExtraction) *NoteTextExtractor {
+	return &NoteTextExtractor{extraction: extraction}
+}
+
+func (e *NoteTextExtractor) Start(ctx context.Context) {
+	ticker := time.NewTicker(ExtractInterval)
+	defer ticker.Stop()
+
+	log.Info("Note text extractor cron started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Stopping note text extractor...")
+			return
+		case <-ticker.C:
+			e.extract()
+		}
+	}
+}
+
+func (e *NoteTextExtractor) extract() {
+	now := utils.NowUTC()
+
+	attempted, err := e.extraction.ExtractPendingTexts(now)
+	if err != nil {
+		log.Errorf("Extractor: failed to extract note texts after %d attempts: %v", attempted, err)
+		return
+	}
+
+	log.Debugf("Extractor: successfully attempted %d note text extractions", attempted)
+}
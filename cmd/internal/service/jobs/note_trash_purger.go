@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"simplenotes/cmd/internal/utils"
+)
+
+const (
+	DefaultTrashRetention = 30 * 24 * time.Hour // 30 days
+	PurgeInterval         = 1 * time.Hour
+)
+
+type NotePurger interface {
+	PurgeTrashedNotes(before int64) (int, error)
+}
+
+type NoteTrashPurger struct {
+	purger    NotePurger
+	retention time.Duration
+}
+
+func NewNoteTrashPurger(purger NotePurger, retention time.Duration) *NoteTrashPurger {
+	return &NoteTrashPurger{purger: purger, retention: retention}
+}
+
+func (p *NoteTrashPurger) Start(ctx context.Context) {
+	ticker := time.NewTicker(PurgeInterval)
+	defer ticker.Stop()
+
+	log.Info("Note trash purger cron started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Stopping note trash purger...")
+			return
+		case <-ticker.C:
+			p.cleanup()
+		}
+	}
+}
+
+func (p *NoteTrashPurger) cleanup() {
+	now := utils.NowUTC()
+	cutoff := now - p.retention.Milliseconds()
+
+	purged, err := p.purger.PurgeTrashedNotes(cutoff)
+	if err != nil {
+		log.Errorf("Purger: failed to purge trashed notes after %d purged: %v", purged, err)
+		return
+	}
+
+	log.Debugf("Purger: successfully purged %d notes trashed before %d", purged, cutoff)
+}
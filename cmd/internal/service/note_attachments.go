@@ -0,0 +1,446 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/infrastructure/storage"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+// maxAttachmentNameBytes caps the file name kept for display.
+const maxAttachmentNameBytes = 255
+
+// errAttachmentReleased means the stored file an upload was going to reuse lost
+// its last reference before the note could be saved, so it may already be gone.
+var errAttachmentReleased = errors.New("attachment was released while being reused")
+
+// errTooManyAttachments means the note reached contract.MaxNoteAttachments
+// while a file was being uploaded to it.
+var errTooManyAttachments = errors.New("note has too many attachments")
+
+type AttachmentRepository interface {
+	FindByHash(hash string) (*entity.Attachment, error)
+	AcquireWithDB(db *gorm.DB, attachment *entity.Attachment) error
+	AcquireExistingWithDB(db *gorm.DB, hash string) (bool, error)
+	ReleaseWithDB(db *gorm.DB, filename string) (int, error)
+}
+
+type NoteAttachmentRepository interface {
+	FindByID(noteID, id int) (*entity.NoteAttachment, error)
+	FindAllByNoteIDWithDB(db *gorm.DB, noteID int) ([]*entity.NoteAttachment, error)
+	SaveWithDB(db *gorm.DB, attachment *entity.NoteAttachment) error
+	DeleteWithDB(db *gorm.DB, attachment *entity.NoteAttachment) error
+	DeleteByNoteIDWithDB(db *gorm.DB, noteID int) error
+}
+
+func (n *NoteService) GetNoteAttachments(actor *entity.User, noteId int) ([]*contract.NoteAttachmentResponse, apierror.ErrorResponse) {
+	note, apierr := n.fetchVisibleNote(actor, noteId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	attachments, err := n.AttachmentRepo.FindAllByNoteIDWithDB(nil, note.ID)
+	if err != nil {
+		log.Errorf("failed to fetch attachments of note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+	return toNoteAttachmentResponses(attachments), nil
+}
+
+// AddNoteAttachment uploads a file and appends it to the attachments of the note.
+func (n *NoteService) AddNoteAttachment(actor *entity.User, noteId int, file *contract.NoteFile) (*contract.NoteAttachmentResponse, apierror.ErrorResponse) {
+	note, apierr := n.fetchEditableNote(actor, noteId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	if apierr = checkNoteFileName(file.Filename); apierr != nil {
+		return nil, apierr
+	}
+
+	// Checked before uploading, and again once the upload is done
+	attachments, err := n.AttachmentRepo.FindAllByNoteIDWithDB(nil, note.ID)
+	if err != nil {
+		log.Errorf("failed to fetch attachments of note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	if len(attachments) >= contract.MaxNoteAttachments {
+		return nil, tooManyAttachmentsError()
+	}
+
+	upload, apierr := n.handleNoteUpload(file, attachmentSizeLimit)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	attachment := &entity.NoteAttachment{
+		NoteID:      note.ID,
+		Name:        attachmentName(file.Filename),
+		Filename:    upload.filename,
+		ContentType: upload.contentType,
+		ContentSize: upload.size,
+		ContentHash: upload.hash,
+		CreatedByID: actor.ID,
+		CreatedAt:   utils.NowUTC(),
+	}
+
+	err = n.DB.Transaction(func(tx *gorm.DB) error {
+		attachments, err := n.AttachmentRepo.FindAllByNoteIDWithDB(tx, note.ID)
+		if err != nil {
+			return err
+		}
+		if len(attachments) >= contract.MaxNoteAttachments {
+			return errTooManyAttachments
+		}
+		if len(attachments) > 0 {
+			attachment.Position = attachments[len(attachments)-1].Position + 1
+		}
+
+		if err := n.AttachmentRepo.SaveWithDB(tx, attachment); err != nil {
+			return err
+		}
+		if err := n.acquireAttachment(tx, upload); err != nil {
+			return err
+		}
+		return n.Audit.Record(tx, &entity.AuditLogEvent{
+			ActorUserID: &actor.ID,
+			ActionType:  entity.AuditActionNoteAttachmentAdd,
+			SubjectType: entity.AuditSubjectNote,
+			SubjectID:   strconv.Itoa(note.ID),
+			Source:      entity.AuditSourceHTTPAPI,
+			Changes:     buildNoteAttachmentAuditChanges(attachment, newAuditCreateValue),
+		})
+	})
+	if err != nil {
+		n.discardUpload(upload)
+	}
+
+	if errors.Is(err, errTooManyAttachments) {
+		return nil, tooManyAttachmentsError()
+	}
+
+	if err != nil {
+		log.Errorf("failed to attach file to note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+	return toNoteAttachmentResponse(attachment), nil
+}
+
+// ReorderNoteAttachments moves the attachments of the note to the order of the
+// request, which must list each of them exactly once.
+func (n *NoteService) ReorderNoteAttachments(actor *entity.User, noteId int, req *contract.NoteAttachmentOrderRequest) ([]*contract.NoteAttachmentResponse, apierror.ErrorResponse) {
+	if valerr := n.Validate.Struct(req); valerr != nil {
+		return nil, apierror.FromValidationError(valerr)
+	}
+
+	note, apierr := n.fetchEditableNote(actor, noteId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	attachments, err := n.AttachmentRepo.FindAllByNoteIDWithDB(nil, note.ID)
+	if err != nil {
+		log.Errorf("failed to fetch attachments of note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	byID := make(map[int]*entity.NoteAttachment, len(attachments))
+	before := make([]string, len(attachments))
+	for i, attachment := range attachments {
+		byID[attachment.ID] = attachment
+		before[i] = strconv.Itoa(attachment.ID)
+	}
+
+	after := make([]string, len(req.AttachmentIDs))
+	for i, id := range req.AttachmentIDs {
+		if byID[id] == nil || len(req.AttachmentIDs) != len(attachments) {
+			return nil, apierror.NewSimple(400, "The new order must list every attachment of the note exactly once")
+		}
+		after[i] = strconv.Itoa(id)
+	}
+
+	if slices.Equal(before, after) {
+		return toNoteAttachmentResponses(attachments), nil
+	}
+
+	changes := make([]*entity.AuditLogChange, 0, 1)
+	appendAuditStringArrayChange(&changes, "attachment_ids", before, after)
+
+	ordered := make([]*entity.NoteAttachment, len(req.AttachmentIDs))
+	err = n.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range req.AttachmentIDs {
+			ordered[i] = byID[id]
+			ordered[i].Position = i
+			if err := n.AttachmentRepo.SaveWithDB(tx, ordered[i]); err != nil {
+				return err
+			}
+		}
+		return n.Audit.Record(tx, &entity.AuditLogEvent{
+			ActorUserID: &actor.ID,
+			ActionType:  entity.AuditActionNoteAttachmentReorder,
+			SubjectType: entity.AuditSubjectNote,
+			SubjectID:   strconv.Itoa(note.ID),
+			Source:      entity.AuditSourceHTTPAPI,
+			Changes:     changes,
+		})
+	})
+	if err != nil {
+		log.Errorf("failed to reorder attachments of note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+	return toNoteAttachmentResponses(ordered), nil
+}
+
+// DeleteNoteAttachment removes the attachment from the note, and deletes its
+// file when no other note uses it.
+func (n *NoteService) DeleteNoteAttachment(actor *entity.User, noteId, attachmentId int) apierror.ErrorResponse {
+	note, apierr := n.fetchEditableNote(actor, noteId)
+	if apierr != nil {
+		return apierr
+	}
+
+	attachment, apierr := n.fetchNoteAttachment(note, attachmentId)
+	if apierr != nil {
+		return apierr
+	}
+
+	lastReference := false
+	err := n.DB.Transaction(func(tx *gorm.DB) error {
+		if err := n.AttachmentRepo.DeleteWithDB(tx, attachment); err != nil {
+			return err
+		}
+		last, err := n.releaseStoredFile(tx, attachment.Filename)
+		if err != nil {
+			return err
+		}
+		lastReference = last
+		return n.Audit.Record(tx, &entity.AuditLogEvent{
+			ActorUserID: &actor.ID,
+			ActionType:  entity.AuditActionNoteAttachmentDelete,
+			SubjectType: entity.AuditSubjectNote,
+			SubjectID:   strconv.Itoa(note.ID),
+			Source:      entity.AuditSourceHTTPAPI,
+			Changes:     buildNoteAttachmentAuditChanges(attachment, newAuditDeleteValue),
+		})
+	})
+	if err != nil {
+		log.Errorf("failed to delete attachment %d of note %d: %v", attachment.ID, note.ID, err)
+		return apierror.InternalServerError
+	}
+
+	if lastReference {
+		if err = deleteStoredFile(n.Storage, attachment.Filename, thumbnailName(attachment.Filename)); err != nil {
+			log.Errorf("failed to delete file %s of attachment %d: %v", attachment.Filename, attachment.ID, err)
+		}
+	}
+	return nil
+}
+
+// GetNoteAttachmentFile works like GetNoteFile, for one of the attachments of the note.
+// Callers must close the returned object.
+func (n *NoteService) GetNoteAttachmentFile(actor *entity.User, noteId, attachmentId int, proxy bool) (string, *storage.Object, apierror.ErrorResponse) {
+	note, apierr := n.fetchVisibleNote(actor, noteId)
+	if apierr != nil {
+		return "", nil, apierr
+	}
+
+	attachment, apierr := n.fetchNoteAttachment(note, attachmentId)
+	if apierr != nil {
+		return "", nil, apierr
+	}
+	return n.downloadStoredFile(attachment.Filename, attachment.ContentType, proxy)
+}
+
+// releaseNoteAttachments removes every attachment of the note being purged, and
+// returns the files that lost their last reference.
+func (n *NoteService) releaseNoteAttachments(tx *gorm.DB, noteID int) ([]string, error) {
+	attachments, err := n.AttachmentRepo.FindAllByNoteIDWithDB(tx, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	var unused []string
+	for _, attachment := range attachments {
+		last, err := n.releaseStoredFile(tx, attachment.Filename)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			unused = append(unused, attachment.Filename)
+		}
+	}
+	return unused, n.AttachmentRepo.DeleteByNoteIDWithDB(tx, noteID)
+}
+
+func (n *NoteService) fetchEditableNote(actor *entity.User, noteId int) (*entity.Note, apierror.ErrorResponse) {
+	note, err := n.NoteRepo.FindByID(noteId)
+	if err != nil {
+		log.Errorf("failed to fetch note: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	if apierr := n.NotePolicy.CanUpdate(note, actor); apierr != nil {
+		return nil, apierr
+	}
+	return note, nil
+}
+
+func (n *NoteService) fetchNoteAttachment(note *entity.Note, attachmentId int) (*entity.NoteAttachment, apierror.ErrorResponse) {
+	attachment, err := n.AttachmentRepo.FindByID(note.ID, attachmentId)
+	if err != nil {
+		log.Errorf("failed to fetch attachment %d of note %d: %v", attachmentId, note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	if attachment == nil {
+		return nil, apierror.NotFoundError
+	}
+	return attachment, nil
+}
+
+// attachmentSizeLimit caps every file attached to a note. Images are kept
+// smaller, since markdown notes embed them and load them on every view.
+func attachmentSizeLimit(contentType string) int64 {
+	if strings.HasPrefix(contentType, "image/") {
+		return contract.MaxNoteImageSizeBytes
+	}
+	return contract.MaxNoteFileSizeBytes
+}
+
+func tooManyAttachmentsError() apierror.ErrorResponse {
+	return apierror.NewSimple(400, "A note cannot have more than %d attachments", contract.MaxNoteAttachments)
+}
+
+// attachmentName keeps the base name of the uploaded file, for display only.
+func attachmentName(filename string) string {
+	name := filepath.Base(strings.ReplaceAll(filename, "\\", "/"))
+	return truncateUTF8(strings.TrimSpace(name), maxAttachmentNameBytes)
+}
+
+func buildNoteAttachmentAuditChanges(
+	attachment *entity.NoteAttachment,
+	newValue func(field string, valueType entity.AuditValueType, value string) *entity.AuditLogChange,
+) []*entity.AuditLogChange {
+	return []*entity.AuditLogChange{
+		newValue("attachment_id", entity.AuditValueTypeInt, strconv.Itoa(attachment.ID)),
+		newValue("name", entity.AuditValueTypeString, attachment.Name),
+		newValue("content_type", entity.AuditValueTypeString, attachment.ContentType),
+		newValue("content_size", entity.AuditValueTypeInt, strconv.Itoa(attachment.ContentSize)),
+		newValue("content_hash", entity.AuditValueTypeString, attachment.ContentHash),
+	}
+}
+
+func toNoteAttachmentResponses(attachments []*entity.NoteAttachment) []*contract.NoteAttachmentResponse {
+	resp := make([]*contract.NoteAttachmentResponse, len(attachments))
+	for i, attachment := range attachments {
+		resp[i] = toNoteAttachmentResponse(attachment)
+	}
+	return resp
+}
+
+func toNoteAttachmentResponse(attachment *entity.NoteAttachment) *contract.NoteAttachmentResponse {
+	return &contract.NoteAttachmentResponse{
+		ID:          attachment.ID,
+		NoteID:      attachment.NoteID,
+		Name:        attachment.Name,
+		ContentType: attachment.ContentType,
+		ContentSize: attachment.ContentSize,
+		Position:    attachment.Position,
+		URL:         fmt.Sprintf("/api/notes/%d/attachments/%d/file", attachment.NoteID, attachment.ID),
+		CreatedByID: attachment.CreatedByID,
+		CreatedAt:   utils.FormatEpoch(attachment.CreatedAt),
+	}
+}
+
+// storeAttachment moves a finished upload from 'tmp' to its content addressed
+// name. When a file with the same bytes is already stored, the upload is
+// dropped and the note will share that file instead.
+func (n *NoteService) storeAttachment(tmp, ext string, upload *noteUpload) error {
+	existing, err := n.FileRepo.FindByHash(upload.hash)
+	if err != nil {
+		_ = n.Storage.DeleteFile(tmp)
+		return err
+	}
+
+	if existing != nil {
+		if err = n.Storage.DeleteFile(tmp); err != nil {
+			log.Warnf("failed to delete duplicate upload %s: %v", tmp, err)
+		}
+		upload.filename = existing.Filename
+		upload.thumbnail = existing.Thumbnail
+		upload.reused = true
+		return nil
+	}
+
+	upload.filename = upload.hash + ext
+	if err = n.Storage.MoveFile(tmp, storage.PathAttachments+upload.filename); err != nil {
+		_ = n.Storage.DeleteFile(tmp)
+		return err
+	}
+
+	upload.thumbnail = createThumbnail(n.Storage, upload.filename, upload.contentType)
+	return nil
+}
+
+// acquireAttachment adds the reference of a note being saved to its uploaded file.
+func (n *NoteService) acquireAttachment(tx *gorm.DB, upload *noteUpload) error {
+	if !upload.reused {
+		return n.FileRepo.AcquireWithDB(tx, &entity.Attachment{
+			Hash:      upload.hash,
+			Filename:  upload.filename,
+			Size:      upload.size,
+			Thumbnail: upload.thumbnail,
+			CreatedAt: utils.NowUTC(),
+		})
+	}
+
+	ok, err := n.FileRepo.AcquireExistingWithDB(tx, upload.hash)
+	if err == nil && !ok {
+		return errAttachmentReleased
+	}
+	return err
+}
+
+// releaseAttachment drops the reference 'note' holds on its file, and reports
+// whether it was the last one. The file must then be deleted, once the
+// transaction is committed.
+func (n *NoteService) releaseAttachment(tx *gorm.DB, note *entity.Note) (bool, error) {
+	if note.NoteType != entity.NoteTypeReference {
+		return false, nil
+	}
+	return n.releaseStoredFile(tx, note.Content)
+}
+
+func (n *NoteService) releaseStoredFile(tx *gorm.DB, filename string) (bool, error) {
+	remaining, err := n.FileRepo.ReleaseWithDB(tx, filename)
+	if err != nil {
+		return false, err
+	}
+	return remaining == 0, nil
+}
+
+// discardUpload deletes the file of an upload whose note could not be saved,
+// unless it belongs to other notes.
+func (n *NoteService) discardUpload(upload *noteUpload) {
+	if upload.reused {
+		return
+	}
+
+	existing, err := n.FileRepo.FindByHash(upload.hash)
+	if err != nil || existing != nil {
+		return
+	}
+
+	_ = deleteStoredFile(n.Storage, upload.filename, upload.thumbnail)
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/events"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+const (
+	noteBulkAddTags       = "ADD_TAGS"
+	noteBulkRemoveTags    = "REMOVE_TAGS"
+	noteBulkSetVisibility = "SET_VISIBILITY"
+	noteBulkMove          = "MOVE"
+	noteBulkDelete        = "DELETE"
+
+	noteBulkUpdated   = "UPDATED"
+	noteBulkUnchanged = "UNCHANGED"
+	noteBulkDeleted   = "DELETED"
+	noteBulkFailed    = "FAILED"
+
+	maxNoteTags = 50
+)
+
+// BulkUpdateNotes applies one operation to many notes. Every note goes through
+// the same policy checks as its single-note endpoint, and the ones failing them
+// are reported in their result. The rest is saved in a single transaction, with
+// audit events sharing one batch id.
+func (n *NoteService) BulkUpdateNotes(actor *entity.User, req *contract.NoteBulkRequest) (*contract.NoteBulkResponse, apierror.ErrorResponse) {
+	utils.Sanitize(req)
+	if valerr := n.Validate.Struct(req); valerr != nil {
+		return nil, apierror.FromValidationError(valerr)
+	}
+
+	if apierr := checkNoteBulkRequest(req); apierr != nil {
+		return nil, apierr
+	}
+
+	var folder *entity.Folder
+	if req.Operation == noteBulkMove {
+		var apierr apierror.ErrorResponse
+		if folder, apierr = n.fetchNoteFolder(actor, req.FolderID); apierr != nil {
+			return nil, apierr
+		}
+	}
+
+	notes, err := n.NoteRepo.FindAllByIDs(req.NoteIDs)
+	if err != nil {
+		log.Errorf("failed to fetch notes: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	byID := make(map[int]*entity.Note, len(notes))
+	for _, note := range notes {
+		byID[note.ID] = note
+	}
+
+	now := utils.NowUTC()
+	resp := &contract.NoteBulkResponse{Results: make([]*contract.NoteBulkResult, len(req.NoteIDs))}
+	var changes []noteChange
+	var changed []*contract.NoteBulkResult
+
+	for i, id := range req.NoteIDs {
+		result := &contract.NoteBulkResult{NoteID: id}
+		resp.Results[i] = result
+
+		note := byID[id]
+		after, apierr := n.planNoteBulkChange(actor, req, note, folder, now)
+		if apierr != nil {
+			result.Status = noteBulkFailed
+			result.Code = apierr.Code()
+			result.Error = apiErrorReason(apierr)
+			continue
+		}
+
+		if after == nil {
+			result.Status = noteBulkUnchanged
+			result.Note = toNoteResponse(note, false)
+			continue
+		}
+
+		changes = append(changes, noteChange{before: note, after: after})
+		changed = append(changed, result)
+	}
+
+	if len(changes) == 0 {
+		return resp, nil
+	}
+
+	batchID, err := n.Audit.NewBatchID()
+	if err != nil {
+		log.Errorf("failed to generate batch id: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	err = n.DB.Transaction(func(tx *gorm.DB) error {
+		for _, change := range changes {
+			if err := n.applyNoteBulkChange(tx, actor, change, batchID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrStaleVersion) {
+		return nil, apierror.NoteBulkConflictError
+	}
+
+	if err != nil {
+		log.Errorf("failed to apply bulk %s: %v", req.Operation, err)
+		return nil, apierror.InternalServerError
+	}
+
+	for i, change := range changes {
+		if change.after.DeletedAt != nil {
+			changed[i].Status = noteBulkDeleted
+			continue
+		}
+		changed[i].Status = noteBulkUpdated
+		changed[i].Note = toNoteResponse(change.after, false)
+	}
+
+	resp.BatchID = strconv.FormatInt(batchID, 10)
+	go n.dispatchNoteBulkEvent(resp.BatchID, changes)
+	return resp, nil
+}
+
+// checkNoteBulkRequest requires the fields used by the requested operation.
+func checkNoteBulkRequest(req *contract.NoteBulkRequest) apierror.ErrorResponse {
+	switch req.Operation {
+	case noteBulkAddTags, noteBulkRemoveTags:
+		if len(req.Tags) == 0 {
+			apierr := apierror.NewStructured(http.StatusBadRequest)
+			apierr.Add("tags", "This field is required")
+			return apierr
+		}
+	case noteBulkSetVisibility:
+		if req.Visibility == "" {
+			apierr := apierror.NewStructured(http.StatusBadRequest)
+			apierr.Add("visibility", "This field is required")
+			return apierr
+		}
+	}
+	return nil
+}
+
+// planNoteBulkChange checks that the actor may apply the operation to 'note',
+// and returns its state once applied. A nil note means it was not changed.
+func (n *NoteService) planNoteBulkChange(actor *entity.User, req *contract.NoteBulkRequest, note *entity.Note, folder *entity.Folder, now int64) (*entity.Note, apierror.ErrorResponse) {
+	if req.Operation == noteBulkDelete {
+		if apierr := n.NotePolicy.CanDelete(note, actor); apierr != nil {
+			return nil, apierr
+		}
+
+		after := *note
+		after.DeletedAt = &now
+		after.DeletedByID = &actor.ID
+		return &after, nil
+	}
+
+	if apierr := n.NotePolicy.CanUpdate(note, actor); apierr != nil {
+		return nil, apierr
+	}
+
+	fillLegacyContentHash(note)
+	after := *note
+
+	switch req.Operation {
+	case noteBulkAddTags:
+		tags := toTagsArray(note.Tags)
+		for _, tag := range req.Tags {
+			if tag = strings.ToLower(tag); !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+
+		if len(tags) > maxNoteTags {
+			return nil, apierror.NoteTooManyTagsError
+		}
+		after.Tags = strings.Join(tags, " ")
+
+	case noteBulkRemoveTags:
+		tags := slices.DeleteFunc(toTagsArray(note.Tags), func(tag string) bool {
+			return slices.ContainsFunc(req.Tags, func(removed string) bool {
+				return strings.EqualFold(removed, tag)
+			})
+		})
+		after.Tags = strings.Join(tags, " ")
+
+	case noteBulkSetVisibility:
+		after.Visibility = entity.NoteVisibility(req.Visibility)
+		if after.Visibility == entity.VisibilityPublic && note.Visibility != entity.VisibilityPublic {
+			if apierr := n.checkPublicInFolder(note.FolderID); apierr != nil {
+				return nil, apierr
+			}
+		}
+
+	case noteBulkMove:
+		after.FolderID = req.FolderID
+		inheritFolderVisibility(&after, folder)
+	}
+
+	if len(buildNoteUpdateAuditChanges(note, &after)) == 0 {
+		return nil, nil
+	}
+	after.UpdatedAt = now
+	return &after, nil
+}
+
+func (n *NoteService) applyNoteBulkChange(tx *gorm.DB, actor *entity.User, change noteChange, batchID int64) error {
+	before, after := change.before, change.after
+	if after.DeletedAt != nil {
+		if err := n.NoteRepo.SaveWithDB(tx, after); err != nil {
+			return err
+		}
+		return n.Audit.Record(tx, &entity.AuditLogEvent{
+			ActorUserID: &actor.ID,
+			ActionType:  entity.AuditActionNoteDelete,
+			SubjectType: entity.AuditSubjectNote,
+			SubjectID:   strconv.Itoa(after.ID),
+			Source:      entity.AuditSourceHTTPAPI,
+			BatchID:     &batchID,
+			Changes:     buildNoteDeleteAuditChanges(after),
+		})
+	}
+
+	_, err := n.saveRewrittenNote(tx, actor, before, after, &batchID)
+	return err
+}
+
+// dispatchNoteBulkEvent sends a single NotesBulkChanged event to every connected
+// user, holding only the changed notes they could see before or after the operation.
+func (n *NoteService) dispatchNoteBulkEvent(batchID string, changes []noteChange) {
+	responses := make(map[int]*contract.NoteResponse, len(changes))
+	for _, change := range changes {
+		if change.after.DeletedAt == nil {
+			responses[change.after.ID] = toNoteResponse(change.after, false)
+		}
+	}
+
+	n.WSService.BroadcastPerUser(context.Background(), func(userID int) events.SocketEvent {
+		recipient, err := n.UserRepo.FindActiveByID(userID)
+		if err != nil {
+			log.Errorf("failed to find user (%d) by id: %v", userID, err)
+			return nil
+		}
+
+		if recipient == nil {
+			return nil
+		}
+
+		evt := &events.NotesBulkChanged{
+			BatchID: batchID,
+			Updated: []*contract.NoteResponse{},
+			Revoked: []int{},
+			Deleted: []int{},
+		}
+		for _, change := range changes {
+			sawBefore := n.NotePolicy.CanSee(change.before, recipient) == nil
+			switch {
+			case change.after.DeletedAt != nil:
+				if sawBefore {
+					evt.Deleted = append(evt.Deleted, change.after.ID)
+				}
+			case n.NotePolicy.CanSee(change.after, recipient) == nil:
+				evt.Updated = append(evt.Updated, responses[change.after.ID])
+			case sawBefore:
+				evt.Revoked = append(evt.Revoked, change.after.ID)
+			}
+		}
+
+		if len(evt.Updated) == 0 && len(evt.Revoked) == 0 && len(evt.Deleted) == 0 {
+			return nil
+		}
+		return evt
+	})
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"net/http"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strconv"
+	"testing"
+)
+
+func TestBulkNoteOperationsReportPerNoteResultsAndLinkAuditEvents(t *testing.T) {
+	db := newTestDB(t)
+	noteSvc := newTestNoteService(t, db, 14400)
+	writer := newTestWriter(t, db)
+
+	other := &entity.User{
+		Username:    "other",
+		Email:       "other@example.com",
+		Permissions: entity.PermissionCreateNotes,
+		Active:      true,
+		CreatedAt:   utils.NowUTC(),
+		UpdatedAt:   utils.NowUTC(),
+	}
+	if err := repository.NewUserRepository(db).Save(other); err != nil {
+		t.Fatalf("save other: %v", err)
+	}
+
+	createText := func(author *entity.User, name string, visibility entity.NoteVisibility, tags []string) *contract.NoteResponse {
+		t.Helper()
+		note, apierr := noteSvc.CreateTextNote(author, &contract.TextNoteRequest{
+			Name:       name,
+			Content:    "# " + name,
+			NoteType:   string(entity.NoteTypeMarkdown),
+			Visibility: string(visibility),
+			Tags:       tags,
+		})
+		if apierr != nil {
+			t.Fatalf("create note %q returned api error: %#v", name, apierr)
+		}
+		return note
+	}
+
+	plan := createText(writer, "Plan", entity.VisibilityPublic, []string{"draft"})
+	tagged := createText(writer, "Tagged", entity.VisibilityPublic, []string{"review"})
+	shared := createText(other, "Shared", entity.VisibilityPublic, []string{})
+	hidden := createText(other, "Hidden", entity.VisibilityPrivate, []string{})
+
+	// Operations need their own fields
+	if _, apierr := noteSvc.BulkUpdateNotes(writer, &contract.NoteBulkRequest{
+		NoteIDs:   []int{plan.ID},
+		Operation: "ADD_TAGS",
+	}); apierr == nil || apierr.Code() != http.StatusBadRequest {
+		t.Fatalf("expected bad request without tags, got %#v", apierr)
+	}
+
+	resp, apierr := noteSvc.BulkUpdateNotes(writer, &contract.NoteBulkRequest{
+		NoteIDs:   []int{plan.ID, tagged.ID, hidden.ID, 999999},
+		Operation: "ADD_TAGS",
+		Tags:      []string{"Review"},
+	})
+	if apierr != nil {
+		t.Fatalf("bulk add tags returned api error: %#v", apierr)
+	}
+
+	expected := []struct {
+		status string
+		code   int
+	}{{"UPDATED", 0}, {"UNCHANGED", 0}, {"FAILED", http.StatusNotFound}, {"FAILED", http.StatusNotFound}}
+	if len(resp.Results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(resp.Results))
+	}
+	for i, want := range expected {
+		if got := resp.Results[i]; got.Status != want.status || got.Code != want.code {
+			t.Fatalf("result %d: expected %s/%d, got %s/%d", i, want.status, want.code, got.Status, got.Code)
+		}
+	}
+	if tags := resp.Results[0].Note.Tags; len(tags) != 2 || tags[1] != "review" {
+		t.Fatalf("expected review to be added to plan, got %v", tags)
+	}
+
+	auditRepo := repository.NewAuditRepository(db)
+	batchEvents := func(batchID string) []*entity.AuditLogEvent {
+		t.Helper()
+		id, err := strconv.ParseInt(batchID, 10, 64)
+		if err != nil {
+			t.Fatalf("invalid batch id %q: %v", batchID, err)
+		}
+
+		events, err := auditRepo.List(&repository.AuditLogFilter{Limit: 10, BatchID: &id})
+		if err != nil {
+			t.Fatalf("list audit events: %v", err)
+		}
+		return events
+	}
+
+	events := batchEvents(resp.BatchID)
+	if len(events) != 1 || events[0].SubjectID != strconv.Itoa(plan.ID) || events[0].ActionType != entity.AuditActionNoteUpdate {
+		t.Fatalf("expected one update event of plan in the batch, got %#v", events)
+	}
+
+	resp, apierr = noteSvc.BulkUpdateNotes(writer, &contract.NoteBulkRequest{
+		NoteIDs:   []int{tagged.ID, plan.ID},
+		Operation: "REMOVE_TAGS",
+		Tags:      []string{"review"},
+	})
+	if apierr != nil {
+		t.Fatalf("bulk remove tags returned api error: %#v", apierr)
+	}
+	if len(resp.Results[0].Note.Tags) != 0 || len(resp.Results[1].Note.Tags) != 1 {
+		t.Fatalf("expected review to be removed, got %v and %v", resp.Results[0].Note.Tags, resp.Results[1].Note.Tags)
+	}
+	if events = batchEvents(resp.BatchID); len(events) != 2 {
+		t.Fatalf("expected two events in the batch, got %d", len(events))
+	}
+
+	// Grants never allow deleting somebody else's note
+	resp, apierr = noteSvc.BulkUpdateNotes(writer, &contract.NoteBulkRequest{
+		NoteIDs:   []int{plan.ID, shared.ID},
+		Operation: "DELETE",
+	})
+	if apierr != nil {
+		t.Fatalf("bulk delete returned api error: %#v", apierr)
+	}
+	if resp.Results[0].Status != "DELETED" || resp.Results[1].Code != http.StatusForbidden {
+		t.Fatalf("expected plan deleted and shared forbidden, got %#v and %#v", resp.Results[0], resp.Results[1])
+	}
+
+	noteRepo := repository.NewNoteRepository(db)
+	trashed, err := noteRepo.FindTrashedByID(plan.ID)
+	if err != nil || trashed == nil {
+		t.Fatalf("expected plan in the trash, got %v (%v)", trashed, err)
+	}
+	if events = batchEvents(resp.BatchID); len(events) != 1 || events[0].ActionType != entity.AuditActionNoteDelete {
+		t.Fatalf("expected one delete event in the batch, got %#v", events)
+	}
+}
+
+func TestBulkNoteMoveIntoPrivateFolderInheritsVisibility(t *testing.T) {
+	db := newTestDB(t)
+	noteSvc := newTestNoteService(t, db, 14500)
+	writer := newTestWriter(t, db)
+
+	folder := &entity.Folder{Name: "Vault", Visibility: entity.VisibilityPrivate, CreatedByID: writer.ID}
+	if err := repository.NewFolderRepository(db).SaveWithDB(nil, folder); err != nil {
+		t.Fatalf("save folder: %v", err)
+	}
+
+	var ids []int
+	for _, name := range []string{"First", "Second"} {
+		note, apierr := noteSvc.CreateTextNote(writer, &contract.TextNoteRequest{
+			Name:       name,
+			Content:    name,
+			NoteType:   string(entity.NoteTypeMarkdown),
+			Visibility: string(entity.VisibilityPublic),
+			Tags:       []string{},
+		})
+		if apierr != nil {
+			t.Fatalf("create note returned api error: %#v", apierr)
+		}
+		ids = append(ids, note.ID)
+	}
+
+	resp, apierr := noteSvc.BulkUpdateNotes(writer, &contract.NoteBulkRequest{
+		NoteIDs:   ids,
+		Operation: "MOVE",
+		FolderID:  &folder.ID,
+	})
+	if apierr != nil {
+		t.Fatalf("bulk move returned api error: %#v", apierr)
+	}
+	for _, result := range resp.Results {
+		if result.Status != "UPDATED" || *result.Note.FolderID != folder.ID || result.Note.Visibility != string(entity.VisibilityPrivate) {
+			t.Fatalf("expected note moved and made private, got %#v", result)
+		}
+	}
+
+	// Notes of a private folder cannot be made public
+	resp, apierr = noteSvc.BulkUpdateNotes(writer, &contract.NoteBulkRequest{
+		NoteIDs:    ids,
+		Operation:  "SET_VISIBILITY",
+		Visibility: string(entity.VisibilityPublic),
+	})
+	if apierr != nil {
+		t.Fatalf("bulk set visibility returned api error: %#v", apierr)
+	}
+	for _, result := range resp.Results {
+		if result.Status != "FAILED" || result.Error != apierror.PrivateFolderContentError.Message {
+			t.Fatalf("expected private folder error, got %#v", result)
+		}
+	}
+	if resp.BatchID != "" {
+		t.Fatalf("expected no batch when nothing changed, got %s", resp.BatchID)
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+)
+
+func TestUpdateNoteContentAuditsSizeAndHash(t *testing.T) {
+	db := newTestDB(t)
+
+	auditRepo := repository.NewAuditRepository(db)
+	noteSvc := newTestNoteService(t, db, 6000)
+	actor := newTestWriter(t, db)
+
+	created, apierr := noteSvc.CreateTextNote(actor, &contract.TextNoteRequest{
+		Name:       "Typo",
+		Content:    "teh content",
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{},
+	})
+	if apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+
+	content := "the content, fixed"
+	if _, apierr = noteSvc.UpdateNote(actor, created.ID, &contract.UpdateNoteRequest{Content: &content}, nil); apierr != nil {
+		t.Fatalf("update note returned api error: %#v", apierr)
+	}
+
+	note, err := repository.NewNoteRepository(db).FindByID(created.ID)
+	if err != nil {
+		t.Fatalf("find note: %v", err)
+	}
+	if note.Content != content || note.ContentSize != len(content) {
+		t.Fatalf("unexpected content after update: %q (%d)", note.Content, note.ContentSize)
+	}
+
+	events, err := auditRepo.List(&repository.AuditLogFilter{
+		Limit:      10,
+		ActionType: auditActionPtr(entity.AuditActionNoteUpdate),
+	})
+	if err != nil {
+		t.Fatalf("list audit logs: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 note update audit event, got %d", len(events))
+	}
+
+	fields := map[string]bool{}
+	for _, change := range events[0].Changes {
+		fields[change.FieldName] = true
+		if change.NewValue != nil && strings.Contains(*change.NewValue, "fixed") {
+			t.Fatal("audit changes must not contain raw note content")
+		}
+	}
+	if len(fields) != 2 || !fields["content_size"] || !fields["content_hash"] {
+		t.Fatalf("expected content_size and content_hash changes, got %v", fields)
+	}
+
+	empty := ""
+	if _, apierr = noteSvc.UpdateNote(actor, created.ID, &contract.UpdateNoteRequest{Content: &empty}, nil); apierr == nil {
+		t.Fatal("expected empty content to be rejected")
+	}
+}
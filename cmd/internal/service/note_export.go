@@ -0,0 +1,257 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/infrastructure/storage"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	noteExportVersion      = 1
+	noteExportBatchSize    = 100
+	noteExportManifestPath = "manifest.json"
+	maxArchiveNameRunes    = 50
+)
+
+// ExportNotes checks the export request and returns the function writing the
+// ZIP archive of the selected notes the actor can see. Errors past this point
+// can only cut the archive short, since it is streamed as it is written.
+func (n *NoteService) ExportNotes(actor *entity.User, req *contract.NoteExportRequest) (func(w io.Writer) error, apierror.ErrorResponse) {
+	filter, apierr := toNoteListFilter(&contract.NoteListRequest{Tags: req.Tags, TagsMode: req.TagsMode})
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	filter.Limit = noteExportBatchSize
+	filter.Sort = repository.NoteSortCreatedAt
+	filter.Descending = false
+	filter.ViewerID = actor.ID
+	filter.WithPrivate = actor.Permissions.HasEffective(entity.PermissionSeeHiddenNotes)
+
+	if req.FolderID != nil {
+		folder, apierr := n.fetchNoteFolder(actor, req.FolderID)
+		if apierr != nil {
+			return nil, apierr
+		}
+
+		subtree, err := n.FolderRepo.FindSubtreeWithDB(nil, folder.ID)
+		if err != nil {
+			log.Errorf("failed to fetch subtree of folder %d: %v", folder.ID, err)
+			return nil, apierror.InternalServerError
+		}
+
+		for _, descendant := range subtree {
+			filter.FolderIDs = append(filter.FolderIDs, descendant.ID)
+		}
+	}
+
+	return func(w io.Writer) error {
+		return n.writeNoteExport(w, actor, filter)
+	}, nil
+}
+
+// writeNoteExport writes the notes batch by batch, then the manifest describing them.
+func (n *NoteService) writeNoteExport(w io.Writer, actor *entity.User, filter *repository.NoteListFilter) error {
+	archive := zip.NewWriter(w)
+	manifest := &contract.NoteExportManifest{
+		Version:      noteExportVersion,
+		ExportedAt:   utils.FormatEpoch(utils.NowUTC()),
+		ExportedByID: actor.ID,
+		Notes:        []*contract.NoteExportEntry{},
+	}
+
+	authors := make(map[int]string)
+	for {
+		notes, err := n.NoteRepo.List(filter)
+		if err != nil {
+			return fmt.Errorf("list notes: %w", err)
+		}
+
+		for _, note := range notes {
+			if n.NotePolicy.CanSee(note, actor) != nil {
+				continue
+			}
+
+			entry, err := n.exportNote(archive, note, authors)
+			if err != nil {
+				return fmt.Errorf("export note %d: %w", note.ID, err)
+			}
+			manifest.Notes = append(manifest.Notes, entry)
+		}
+
+		if len(notes) < filter.Limit {
+			break
+		}
+		last := notes[len(notes)-1]
+		filter.After = &repository.NoteCursor{Value: last.CreatedAt, ID: last.ID}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = writeArchiveFile(archive, noteExportManifestPath, time.Now(), data); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// exportNote writes the content and the attachments of the note into the archive.
+func (n *NoteService) exportNote(archive *zip.Writer, note *entity.Note, authors map[int]string) (*contract.NoteExportEntry, error) {
+	author, err := n.exportAuthorName(note.CreatedByID, authors)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &contract.NoteExportEntry{
+		ID:          note.ID,
+		Name:        note.Name,
+		NoteType:    string(note.NoteType),
+		Visibility:  string(note.Visibility),
+		Tags:        toTagsArray(note.Tags),
+		FolderID:    note.FolderID,
+		AuthorID:    note.CreatedByID,
+		AuthorName:  author,
+		ContentType: note.ContentType,
+		Version:     note.Version,
+		CreatedAt:   utils.FormatEpoch(note.CreatedAt),
+		UpdatedAt:   utils.FormatEpoch(note.UpdatedAt),
+	}
+
+	base := fmt.Sprintf("%d-%s", note.ID, archiveName(note.Name))
+	modified := time.UnixMilli(note.UpdatedAt)
+
+	switch note.NoteType {
+	case entity.NoteTypeReference:
+		entry.Path = "files/" + base + strings.ToLower(filepath.Ext(note.Content))
+		if entry.Missing, err = exportStoredFile(archive, n.Storage, entry.Path, note.Content, modified); err != nil {
+			return nil, err
+		}
+	case entity.NoteTypeFlowchart:
+		entry.Path = "notes/" + base + ".json"
+		err = writeArchiveFile(archive, entry.Path, modified, []byte(note.Content))
+	default:
+		entry.Path = "notes/" + base + ".md"
+		err = writeArchiveFile(archive, entry.Path, modified, []byte(note.Content))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := n.AttachmentRepo.FindAllByNoteIDWithDB(nil, note.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, att := range attachments {
+		exported := &contract.NoteExportAttachment{
+			ID:          att.ID,
+			Name:        att.Name,
+			ContentType: att.ContentType,
+			ContentSize: att.ContentSize,
+			Path:        fmt.Sprintf("attachments/%d/%d-%s", note.ID, att.ID, archiveName(att.Name)),
+		}
+
+		if exported.Missing, err = exportStoredFile(archive, n.Storage, exported.Path, att.Filename, time.UnixMilli(att.CreatedAt)); err != nil {
+			return nil, err
+		}
+		entry.Attachments = append(entry.Attachments, exported)
+	}
+	return entry, nil
+}
+
+func (n *NoteService) exportAuthorName(userID int, authors map[int]string) (string, error) {
+	if name, ok := authors[userID]; ok {
+		return name, nil
+	}
+
+	user, err := n.UserRepo.FindByID(userID)
+	if err != nil {
+		return "", err
+	}
+
+	name := ""
+	if user != nil {
+		name = user.Username
+	}
+	authors[userID] = name
+	return name, nil
+}
+
+// exportStoredFile copies a stored file into the archive, and reports whether
+// it was missing from the storage instead.
+func exportStoredFile(archive *zip.Writer, bucket storage.Backend, path, filename string, modified time.Time) (bool, error) {
+	object, err := bucket.GetFile(storage.PathAttachments + filename)
+	if errors.Is(err, storage.ErrorObjectNotFound) {
+		log.Warnf("skipping missing file %s of note export", filename)
+		return true, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+	defer object.Body.Close()
+
+	// Most attachments are compressed already
+	fw, err := archive.CreateHeader(&zip.FileHeader{Name: path, Method: zip.Store, Modified: modified})
+	if err != nil {
+		return false, err
+	}
+
+	_, err = io.Copy(fw, object.Body)
+	return false, err
+}
+
+func writeArchiveFile(archive *zip.Writer, path string, modified time.Time, data []byte) error {
+	fw, err := archive.CreateHeader(&zip.FileHeader{Name: path, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+
+	_, err = fw.Write(data)
+	return err
+}
+
+// archiveName turns a note or file name into a portable file name, keeping
+// letters, digits, dots, dashes and underscores.
+func archiveName(name string) string {
+	var b strings.Builder
+	runes := 0
+	dash := false
+	for _, r := range strings.TrimSpace(name) {
+		if runes == maxArchiveNameRunes {
+			break
+		}
+
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-' {
+			b.WriteRune(r)
+			dash = false
+			runes++
+		} else if !dash && runes > 0 {
+			b.WriteByte('-')
+			dash = true
+			runes++
+		}
+	}
+
+	cleaned := strings.Trim(b.String(), "-.")
+	if cleaned == "" {
+		return "note"
+	}
+	return cleaned
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/infrastructure/storage"
+	"simplenotes/cmd/internal/utils"
+	"sort"
+	"testing"
+)
+
+func TestExportStreamsVisibleNotesWithAManifest(t *testing.T) {
+	db := newTestDB(t)
+
+	local, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create local storage: %v", err)
+	}
+
+	noteSvc := newTestNoteService(t, db, 13800)
+	noteSvc.Storage = local
+	owner := newTestWriter(t, db)
+
+	reader := &entity.User{
+		Username:  "reader",
+		Email:     "reader@example.com",
+		Active:    true,
+		CreatedAt: utils.NowUTC(),
+		UpdatedAt: utils.NowUTC(),
+	}
+	if err = repository.NewUserRepository(db).Save(reader); err != nil {
+		t.Fatalf("save reader: %v", err)
+	}
+
+	folderRepo := repository.NewFolderRepository(db)
+	projects := &entity.Folder{Name: "Projects", Visibility: entity.VisibilityPublic, CreatedByID: owner.ID}
+	if err = folderRepo.SaveWithDB(nil, projects); err != nil {
+		t.Fatalf("save folder: %v", err)
+	}
+	launch := &entity.Folder{Name: "Launch", ParentID: &projects.ID, Visibility: entity.VisibilityPublic, CreatedByID: owner.ID}
+	if err = folderRepo.SaveWithDB(nil, launch); err != nil {
+		t.Fatalf("save folder: %v", err)
+	}
+
+	createText := func(name, content string, noteType entity.NoteType, visibility entity.NoteVisibility, folderID *int) *contract.NoteResponse {
+		t.Helper()
+		note, apierr := noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+			Name:       name,
+			Content:    content,
+			NoteType:   string(noteType),
+			Visibility: string(visibility),
+			Tags:       []string{"ops"},
+			FolderID:   folderID,
+		})
+		if apierr != nil {
+			t.Fatalf("create note %q returned api error: %#v", name, apierr)
+		}
+		return note
+	}
+
+	runbook := createText("Release runbook", "# Release", entity.NoteTypeMarkdown, entity.VisibilityPublic, &launch.ID)
+	pipeline := createText("Pipeline", `{"nodes": []}`, entity.NoteTypeFlowchart, entity.VisibilityPublic, nil)
+	private := createText("Private ops", "secret", entity.NoteTypeMarkdown, entity.VisibilityPrivate, nil)
+
+	report, apierr := noteSvc.CreateFileNote(owner, &contract.NoteRequest{
+		Name:       "Report",
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"finance"},
+	}, newTestNoteFile("report.pdf", []byte("%PDF-1.4 report")))
+	if apierr != nil {
+		t.Fatalf("create file note returned api error: %#v", apierr)
+	}
+
+	if _, apierr = noteSvc.AddNoteAttachment(owner, runbook.ID, newTestNoteFile("checklist.pdf", []byte("%PDF-1.4 checklist"))); apierr != nil {
+		t.Fatalf("add attachment returned api error: %#v", apierr)
+	}
+
+	export := func(actor *entity.User, req *contract.NoteExportRequest) (*contract.NoteExportManifest, map[string]string) {
+		t.Helper()
+		writeExport, apierr := noteSvc.ExportNotes(actor, req)
+		if apierr != nil {
+			t.Fatalf("export returned api error: %#v", apierr)
+		}
+
+		var buf bytes.Buffer
+		if err := writeExport(&buf); err != nil {
+			t.Fatalf("write export: %v", err)
+		}
+
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("open archive: %v", err)
+		}
+
+		files := make(map[string]string)
+		for _, file := range archive.File {
+			r, err := file.Open()
+			if err != nil {
+				t.Fatalf("open %s: %v", file.Name, err)
+			}
+			data, _ := io.ReadAll(r)
+			r.Close()
+			files[file.Name] = string(data)
+		}
+
+		var manifest contract.NoteExportManifest
+		if err = json.Unmarshal([]byte(files["manifest.json"]), &manifest); err != nil {
+			t.Fatalf("decode manifest: %v", err)
+		}
+		return &manifest, files
+	}
+
+	manifest, files := export(owner, &contract.NoteExportRequest{})
+	if len(manifest.Notes) != 4 || manifest.ExportedByID != owner.ID {
+		t.Fatalf("expected the owner to export every note, got %#v", manifest)
+	}
+
+	entry := manifest.Notes[0]
+	if entry.ID != runbook.ID || entry.AuthorName != "writer" || entry.FolderID == nil || *entry.FolderID != launch.ID || entry.Tags[0] != "ops" {
+		t.Fatalf("unexpected manifest entry: %#v", entry)
+	}
+	if files[entry.Path] != "# Release" || entry.Path != fmt.Sprintf("notes/%d-Release-runbook.md", runbook.ID) {
+		t.Fatalf("expected the markdown body at %q, got %q", entry.Path, files[entry.Path])
+	}
+	if len(entry.Attachments) != 1 || files[entry.Attachments[0].Path] != "%PDF-1.4 checklist" {
+		t.Fatalf("expected the attachment in the archive, got %#v", entry.Attachments)
+	}
+	if fileEntry := manifest.Notes[3]; fileEntry.ID != report.ID || files[fileEntry.Path] != "%PDF-1.4 report" || fileEntry.ContentType != "application/pdf" {
+		t.Fatalf("expected the reference file in the archive, got %#v", fileEntry)
+	}
+
+	manifest, _ = export(reader, &contract.NoteExportRequest{Tags: []string{"ops"}})
+	var ids []int
+	for _, entry := range manifest.Notes {
+		ids = append(ids, entry.ID)
+	}
+	sort.Ints(ids)
+	if len(ids) != 2 || ids[0] != runbook.ID || ids[1] != pipeline.ID {
+		t.Fatalf("expected the reader to export the public ops notes only, got %v (private note %d)", ids, private.ID)
+	}
+
+	manifest, _ = export(owner, &contract.NoteExportRequest{FolderID: &projects.ID})
+	if len(manifest.Notes) != 1 || manifest.Notes[0].ID != runbook.ID {
+		t.Fatalf("expected the folder export to include subfolders, got %#v", manifest.Notes)
+	}
+
+	missing := 9999
+	if _, apierr = noteSvc.ExportNotes(owner, &contract.NoteExportRequest{FolderID: &missing}); apierr == nil || apierr.Code() != 404 {
+		t.Fatalf("expected an unknown folder to be rejected, got %#v", apierr)
+	}
+}
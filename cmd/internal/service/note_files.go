@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/infrastructure/storage"
+	"simplenotes/cmd/internal/utils/apierror"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+// presignedFileTTL is how long a presigned download URL stays valid. It only has
+// to outlive the redirect, permissions are checked again on the next request.
+const presignedFileTTL = 5 * time.Minute
+
+// GetNoteFile returns either a short-lived presigned URL of the note attachment,
+// or the opened attachment when 'proxy' is set or the storage cannot presign.
+// Callers must close the returned object.
+func (n *NoteService) GetNoteFile(actor *entity.User, noteId int, proxy bool) (string, *storage.Object, apierror.ErrorResponse) {
+	note, err := n.NoteRepo.FindByID(noteId)
+	if err != nil {
+		log.Errorf("failed to fetch note: %v", err)
+		return "", nil, apierror.InternalServerError
+	}
+
+	if apierr := n.NotePolicy.CanSee(note, actor); apierr != nil {
+		return "", nil, apierr
+	}
+
+	if note.NoteType != entity.NoteTypeReference {
+		return "", nil, apierror.NoteWithoutFileError
+	}
+	return n.downloadStoredFile(note.Content, note.ContentType, proxy)
+}
+
+// downloadStoredFile presigns the stored file, or opens it when 'proxy' is set
+// or the storage cannot presign.
+func (n *NoteService) downloadStoredFile(filename, contentType string, proxy bool) (string, *storage.Object, apierror.ErrorResponse) {
+	if !proxy {
+		url, err := n.Storage.PresignGetFile(storage.PathAttachments+filename, presignedFileTTL)
+		if err == nil {
+			return url, nil, nil
+		}
+
+		if !errors.Is(err, storage.ErrorPresignUnsupported) {
+			log.Errorf("failed to presign attachment %s: %v", filename, err)
+			return "", nil, apierror.InternalServerError
+		}
+	}
+
+	object, apierr := n.openStoredFile(filename, contentType)
+	if apierr != nil {
+		return "", nil, apierr
+	}
+	return "", object, nil
+}
+
+// GetAttachment opens the attachment named 'filename', as long as the actor can
+// see one of the notes sharing it. Callers must close the returned object.
+func (n *NoteService) GetAttachment(actor *entity.User, filename string) (*storage.Object, apierror.ErrorResponse) {
+	notes, err := n.NoteRepo.FindAllByAttachment(filename)
+	if err != nil {
+		log.Errorf("failed to fetch notes of attachment %s: %v", filename, err)
+		return nil, apierror.InternalServerError
+	}
+
+	if len(notes) == 0 {
+		return nil, apierror.NotFoundError
+	}
+
+	var apierr apierror.ErrorResponse
+	for _, note := range notes {
+		if apierr = n.NotePolicy.CanSee(note, actor); apierr == nil {
+			return n.openAttachment(note)
+		}
+	}
+	return nil, apierr
+}
+
+func (n *NoteService) openAttachment(note *entity.Note) (*storage.Object, apierror.ErrorResponse) {
+	return n.openStoredFile(note.Content, note.ContentType)
+}
+
+func (n *NoteService) openStoredFile(filename, contentType string) (*storage.Object, apierror.ErrorResponse) {
+	object, err := n.Storage.GetFile(storage.PathAttachments + filename)
+	if errors.Is(err, storage.ErrorObjectNotFound) {
+		return nil, apierror.NotFoundError
+	}
+
+	if err != nil {
+		log.Errorf("failed to open attachment %s: %v", filename, err)
+		return nil, apierror.InternalServerError
+	}
+
+	// Attachments uploaded before sniffing only have the type guessed by the storage
+	if contentType != "" {
+		object.ContentType = contentType
+	}
+	return object, nil
+}
@@ -0,0 +1,428 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/infrastructure/storage"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strconv"
+	"testing"
+)
+
+func TestLocalStorageServesAttachmentsToAllowedViewers(t *testing.T) {
+	db := newTestDB(t)
+
+	root := t.TempDir()
+	local, err := storage.NewLocalStorage(root)
+	if err != nil {
+		t.Fatalf("failed to create local storage: %v", err)
+	}
+
+	noteSvc := newTestNoteService(t, db, 13000)
+	noteSvc.Storage = local
+	owner := newTestWriter(t, db)
+
+	data := []byte("%PDF-1.4 quarterly report")
+	note, apierr := noteSvc.CreateFileNote(owner, &contract.NoteRequest{
+		Name:       "Report",
+		Visibility: string(entity.VisibilityPrivate),
+		Tags:       []string{"finance"},
+	}, newTestNoteFile("report.pdf", data))
+	if apierr != nil {
+		t.Fatalf("create file note returned api error: %#v", apierr)
+	}
+
+	object, apierr := noteSvc.GetAttachment(owner, note.Content)
+	if apierr != nil {
+		t.Fatalf("get attachment returned api error: %#v", apierr)
+	}
+	got, err := io.ReadAll(object.Body)
+	object.Body.Close()
+	if err != nil {
+		t.Fatalf("failed to read attachment: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("expected attachment bytes %q, got %q", data, got)
+	}
+	if object.ContentType != "application/pdf" {
+		t.Fatalf("expected application/pdf content type, got %q", object.ContentType)
+	}
+	if note.ContentType != "application/pdf" {
+		t.Fatalf("expected sniffed content type on the note, got %q", note.ContentType)
+	}
+
+	_, apierr = noteSvc.CreateFileNote(owner, &contract.NoteRequest{
+		Name:       "Invoice",
+		Visibility: string(entity.VisibilityPrivate),
+		Tags:       []string{"finance"},
+	}, newTestNoteFile("invoice.pdf", []byte("MZ\x90\x00 this is an executable")))
+	if apierr == nil || apierr.Code() != 400 {
+		t.Fatalf("expected renamed executable to be rejected, got %#v", apierr)
+	}
+
+	url, object, apierr := noteSvc.GetNoteFile(owner, note.ID, false)
+	if apierr != nil {
+		t.Fatalf("get note file returned api error: %#v", apierr)
+	}
+	if url != "" || object == nil {
+		t.Fatalf("expected local storage to stream instead of presigning, got url %q", url)
+	}
+	object.Body.Close()
+	if object.Size != int64(len(data)) {
+		t.Fatalf("expected note file size %d, got %d", len(data), object.Size)
+	}
+
+	textNote, apierr := noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+		Name:       "Summary",
+		Content:    "no file here",
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"finance"},
+	})
+	if apierr != nil {
+		t.Fatalf("create text note returned api error: %#v", apierr)
+	}
+	if _, _, apierr = noteSvc.GetNoteFile(owner, textNote.ID, true); apierr == nil || apierr.Code() != 400 {
+		t.Fatalf("expected text notes to have no file, got %#v", apierr)
+	}
+
+	stranger := &entity.User{ID: owner.ID + 1000, Active: true}
+	if _, apierr = noteSvc.GetAttachment(stranger, note.Content); apierr == nil || apierr.Code() != 404 {
+		t.Fatalf("expected private attachment to be hidden from others, got %#v", apierr)
+	}
+	if _, _, apierr = noteSvc.GetNoteFile(stranger, note.ID, false); apierr == nil || apierr.Code() != 404 {
+		t.Fatalf("expected private note file to be hidden from others, got %#v", apierr)
+	}
+
+	if _, err = local.GetFile("../" + storage.PathAttachments + note.Content); err != storage.ErrorInvalidKey {
+		t.Fatalf("expected traversal keys to be rejected, got %v", err)
+	}
+}
+
+// newTestNoteFile wraps 'data' as if it was streamed from a multipart form.
+func newTestNoteFile(filename string, data []byte) *contract.NoteFile {
+	return &contract.NoteFile{Filename: filename, Body: bytes.NewReader(data)}
+}
+
+func TestOversizeUploadsAreRejectedWithoutLeftovers(t *testing.T) {
+	db := newTestDB(t)
+
+	root := t.TempDir()
+	local, err := storage.NewLocalStorage(root)
+	if err != nil {
+		t.Fatalf("failed to create local storage: %v", err)
+	}
+
+	noteSvc := newTestNoteService(t, db, 13100)
+	noteSvc.Storage = local
+	owner := newTestWriter(t, db)
+
+	oversize := &contract.NoteFile{
+		Filename: "movie.mp4",
+		Body:     io.LimitReader(io.MultiReader(bytes.NewReader(mp4Header), zeroReader{}), contract.MaxNoteFileSizeBytes+1),
+	}
+	_, apierr := noteSvc.CreateFileNote(owner, &contract.NoteRequest{
+		Name:       "Movie",
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"media"},
+	}, oversize)
+	tooLarge := apierror.NewNoteContentTooLargeError(contract.MaxNoteFileSizeBytes)
+	if apiError, ok := apierr.(*apierror.APIError); !ok || *apiError != *tooLarge {
+		t.Fatalf("expected oversize upload to be rejected, got %#v", apierr)
+	}
+
+	entries, err := os.ReadDir(root + "/" + storage.PathAttachments)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("failed to list attachments: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no leftover files, got %d", len(entries))
+	}
+
+	data := append(mp4Header, bytes.Repeat([]byte("frame"), 1000)...)
+	note, apierr := noteSvc.CreateFileNote(owner, &contract.NoteRequest{
+		Name:       "Clip",
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"media"},
+	}, newTestNoteFile("clip.mp4", data))
+	if apierr != nil {
+		t.Fatalf("create file note returned api error: %#v", apierr)
+	}
+	if note.ContentSize != len(data) {
+		t.Fatalf("expected content size %d, got %d", len(data), note.ContentSize)
+	}
+
+	stored, err := noteSvc.NoteRepo.FindByID(note.ID)
+	if err != nil {
+		t.Fatalf("failed to fetch note: %v", err)
+	}
+	if stored.ContentHash != contentHash(data) {
+		t.Fatalf("expected streamed hash %s, got %s", contentHash(data), stored.ContentHash)
+	}
+}
+
+// mp4Header is the start of the `ftyp` box of an MP4 file.
+var mp4Header = []byte("\x00\x00\x00\x18ftypmp42")
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestImageUploadsGetThumbnailsDeletedWithTheirFile(t *testing.T) {
+	db := newTestDB(t)
+
+	root := t.TempDir()
+	local, err := storage.NewLocalStorage(root)
+	if err != nil {
+		t.Fatalf("failed to create local storage: %v", err)
+	}
+
+	noteSvc := newTestNoteService(t, db, 13200)
+	noteSvc.Storage = local
+	owner := newTestWriter(t, db)
+
+	var photo bytes.Buffer
+	if err = png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 800, 400))); err != nil {
+		t.Fatalf("failed to encode photo: %v", err)
+	}
+
+	note, apierr := noteSvc.CreateFileNote(owner, &contract.NoteRequest{
+		Name:       "Photo",
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"media"},
+	}, newTestNoteFile("photo.png", photo.Bytes()))
+	if apierr != nil {
+		t.Fatalf("create file note returned api error: %#v", apierr)
+	}
+	if note.ThumbnailURL != fmt.Sprintf("/api/notes/%d/thumbnail", note.ID) {
+		t.Fatalf("expected a thumbnail url, got %q", note.ThumbnailURL)
+	}
+
+	object, apierr := noteSvc.GetNoteThumbnail(owner, note.ID)
+	if apierr != nil {
+		t.Fatalf("get thumbnail returned api error: %#v", apierr)
+	}
+	cfg, err := jpeg.DecodeConfig(object.Body)
+	object.Body.Close()
+	if err != nil {
+		t.Fatalf("thumbnail is not a jpeg: %v", err)
+	}
+	if cfg.Width != 256 || cfg.Height != 128 {
+		t.Fatalf("expected 256x128 thumbnail, got %dx%d", cfg.Width, cfg.Height)
+	}
+
+	stored, err := noteSvc.NoteRepo.FindByID(note.ID)
+	if err != nil {
+		t.Fatalf("failed to fetch note: %v", err)
+	}
+	previous := stored.Thumbnail
+
+	if _, apierr = noteSvc.ReplaceNoteFile(owner, note.ID, newTestNoteFile("scan.pdf", []byte("%PDF-1.7"))); apierr != nil {
+		t.Fatalf("replace note file returned api error: %#v", apierr)
+	}
+
+	if _, err = local.GetFile(storage.PathThumbnails + previous); err != storage.ErrorObjectNotFound {
+		t.Fatalf("expected previous thumbnail to be deleted, got %v", err)
+	}
+
+	object, apierr = noteSvc.GetNoteThumbnail(owner, note.ID)
+	if apierr != nil {
+		t.Fatalf("expected pdf placeholder thumbnail, got api error: %#v", apierr)
+	}
+	object.Body.Close()
+}
+
+func TestDuplicateUploadsShareOneStoredFile(t *testing.T) {
+	db := newTestDB(t)
+
+	root := t.TempDir()
+	local, err := storage.NewLocalStorage(root)
+	if err != nil {
+		t.Fatalf("failed to create local storage: %v", err)
+	}
+
+	noteSvc := newTestNoteService(t, db, 13300)
+	noteSvc.Storage = local
+	owner := newTestWriter(t, db)
+
+	data := []byte("%PDF-1.4 the same handbook, uploaded twice")
+	first, apierr := noteSvc.CreateFileNote(owner, &contract.NoteRequest{
+		Name:       "Handbook",
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"hr"},
+	}, newTestNoteFile("handbook.pdf", data))
+	if apierr != nil {
+		t.Fatalf("create file note returned api error: %#v", apierr)
+	}
+
+	second, apierr := noteSvc.CreateFileNote(owner, &contract.NoteRequest{
+		Name:       "Handbook copy",
+		Visibility: string(entity.VisibilityPrivate),
+		Tags:       []string{"hr"},
+	}, newTestNoteFile("handbook-copy.pdf", data))
+	if apierr != nil {
+		t.Fatalf("create file note returned api error: %#v", apierr)
+	}
+
+	if want := contentHash(data) + ".pdf"; first.Content != want || second.Content != want {
+		t.Fatalf("expected both notes to point to %q, got %q and %q", want, first.Content, second.Content)
+	}
+	if files, _ := os.ReadDir(filepath.Join(root, storage.PathUploads)); len(files) != 0 {
+		t.Fatalf("expected no leftover uploads, got %d", len(files))
+	}
+
+	if apierr = noteSvc.DeleteNote(owner, first.ID, nil); apierr != nil {
+		t.Fatalf("delete note returned api error: %#v", apierr)
+	}
+	if apierr = noteSvc.PurgeNote(owner, first.ID); apierr != nil {
+		t.Fatalf("purge note returned api error: %#v", apierr)
+	}
+
+	object, apierr := noteSvc.GetAttachment(owner, second.Content)
+	if apierr != nil {
+		t.Fatalf("expected the shared file to outlive the purged note, got api error: %#v", apierr)
+	}
+	object.Body.Close()
+
+	if _, apierr = noteSvc.ReplaceNoteFile(owner, second.ID, newTestNoteFile("policy.pdf", []byte("%PDF-1.4 another file"))); apierr != nil {
+		t.Fatalf("replace note file returned api error: %#v", apierr)
+	}
+
+	if _, err = local.GetFile(storage.PathAttachments + second.Content); err != storage.ErrorObjectNotFound {
+		t.Fatalf("expected the file to be deleted with its last reference, got %v", err)
+	}
+
+	shared, err := noteSvc.FileRepo.FindByHash(contentHash(data))
+	if err != nil {
+		t.Fatalf("find attachment: %v", err)
+	}
+	if shared != nil {
+		t.Fatalf("expected the attachment row to go with its last reference, got %#v", shared)
+	}
+}
+
+func TestMarkdownNotesCarryOrderedAttachments(t *testing.T) {
+	db := newTestDB(t)
+
+	root := t.TempDir()
+	local, err := storage.NewLocalStorage(root)
+	if err != nil {
+		t.Fatalf("failed to create local storage: %v", err)
+	}
+
+	noteSvc := newTestNoteService(t, db, 13400)
+	noteSvc.Storage = local
+	auditRepo := repository.NewAuditRepository(db)
+	owner := newTestWriter(t, db)
+
+	note, apierr := noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+		Name:       "Kickoff",
+		Content:    "![diagram](attachment)",
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"meeting"},
+	})
+	if apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+
+	var diagram bytes.Buffer
+	if err = png.Encode(&diagram, image.NewRGBA(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatalf("failed to encode diagram: %v", err)
+	}
+
+	slides, apierr := noteSvc.AddNoteAttachment(owner, note.ID, newTestNoteFile("slides.pdf", []byte("%PDF-1.4 kickoff slides")))
+	if apierr != nil {
+		t.Fatalf("add attachment returned api error: %#v", apierr)
+	}
+	picture, apierr := noteSvc.AddNoteAttachment(owner, note.ID, newTestNoteFile("C:\\Users\\me\\diagram.png", diagram.Bytes()))
+	if apierr != nil {
+		t.Fatalf("add attachment returned api error: %#v", apierr)
+	}
+	if picture.Name != "diagram.png" || picture.ContentType != "image/png" || picture.Position != slides.Position+1 {
+		t.Fatalf("unexpected image attachment: %#v", picture)
+	}
+	if picture.URL != fmt.Sprintf("/api/notes/%d/attachments/%d/file", note.ID, picture.ID) {
+		t.Fatalf("unexpected attachment url %q", picture.URL)
+	}
+
+	hugeImage := &contract.NoteFile{
+		Filename: "poster.png",
+		Body:     io.LimitReader(io.MultiReader(bytes.NewReader(diagram.Bytes()[:8]), zeroReader{}), contract.MaxNoteImageSizeBytes+1),
+	}
+	_, apierr = noteSvc.AddNoteAttachment(owner, note.ID, hugeImage)
+	tooLarge := apierror.NewNoteContentTooLargeError(contract.MaxNoteImageSizeBytes)
+	if apiError, ok := apierr.(*apierror.APIError); !ok || *apiError != *tooLarge {
+		t.Fatalf("expected oversize image to be rejected, got %#v", apierr)
+	}
+
+	if _, apierr = noteSvc.ReorderNoteAttachments(owner, note.ID, &contract.NoteAttachmentOrderRequest{
+		AttachmentIDs: []int{picture.ID},
+	}); apierr == nil || apierr.Code() != 400 {
+		t.Fatalf("expected partial order to be rejected, got %#v", apierr)
+	}
+
+	ordered, apierr := noteSvc.ReorderNoteAttachments(owner, note.ID, &contract.NoteAttachmentOrderRequest{
+		AttachmentIDs: []int{picture.ID, slides.ID},
+	})
+	if apierr != nil {
+		t.Fatalf("reorder attachments returned api error: %#v", apierr)
+	}
+
+	listed, apierr := noteSvc.GetNoteAttachments(owner, note.ID)
+	if apierr != nil {
+		t.Fatalf("list attachments returned api error: %#v", apierr)
+	}
+	if len(listed) != 2 || listed[0].ID != picture.ID || listed[1].ID != slides.ID || ordered[0].ID != picture.ID {
+		t.Fatalf("expected the image first, got %#v", listed)
+	}
+
+	url, object, apierr := noteSvc.GetNoteAttachmentFile(owner, note.ID, picture.ID, false)
+	if apierr != nil {
+		t.Fatalf("get attachment file returned api error: %#v", apierr)
+	}
+	object.Body.Close()
+	if url != "" || object.ContentType != "image/png" || object.Size != int64(diagram.Len()) {
+		t.Fatalf("expected the image to be streamed, got url %q and object %#v", url, object)
+	}
+
+	if apierr = noteSvc.DeleteNoteAttachment(owner, note.ID, slides.ID); apierr != nil {
+		t.Fatalf("delete attachment returned api error: %#v", apierr)
+	}
+	if _, _, apierr = noteSvc.GetNoteAttachmentFile(owner, note.ID, slides.ID, true); apierr == nil || apierr.Code() != 404 {
+		t.Fatalf("expected deleted attachment to be gone, got %#v", apierr)
+	}
+	if _, err = local.GetFile(storage.PathAttachments + contentHash([]byte("%PDF-1.4 kickoff slides")) + ".pdf"); err != storage.ErrorObjectNotFound {
+		t.Fatalf("expected the unshared file to be deleted, got %v", err)
+	}
+
+	for _, action := range []entity.AuditActionType{
+		entity.AuditActionNoteAttachmentAdd,
+		entity.AuditActionNoteAttachmentReorder,
+		entity.AuditActionNoteAttachmentDelete,
+	} {
+		events, err := auditRepo.List(&repository.AuditLogFilter{Limit: 10, ActionType: auditActionPtr(action)})
+		if err != nil {
+			t.Fatalf("list audit logs: %v", err)
+		}
+		want := 1
+		if action == entity.AuditActionNoteAttachmentAdd {
+			want = 2
+		}
+		if len(events) != want || events[0].SubjectID != strconv.Itoa(note.ID) {
+			t.Fatalf("expected %d %s audit events on the note, got %#v", want, action, events)
+		}
+	}
+}
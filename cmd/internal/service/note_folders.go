@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strconv"
+
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+// MoveNote moves the note into another folder, or out of any folder.
+// Notes moved into a PRIVATE folder become PRIVATE as well.
+func (n *NoteService) MoveNote(actor *entity.User, noteId int, req *contract.MoveNoteRequest) (*contract.NoteResponse, apierror.ErrorResponse) {
+	if err := n.Validate.Struct(req); err != nil {
+		return nil, apierror.FromValidationError(err)
+	}
+
+	note, err := n.NoteRepo.FindByID(noteId)
+	if err != nil {
+		log.Errorf("failed to fetch note: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	apierr := n.NotePolicy.CanUpdate(note, actor)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	folder, apierr := n.fetchNoteFolder(actor, req.FolderID)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	fillLegacyContentHash(note)
+	before := *note
+	note.FolderID = req.FolderID
+	inheritFolderVisibility(note, folder)
+
+	changes := buildNoteUpdateAuditChanges(&before, note)
+	if len(changes) == 0 {
+		return toNoteResponse(note, false), nil
+	}
+	note.UpdatedAt = utils.NowUTC()
+
+	err = n.DB.Transaction(func(tx *gorm.DB) error {
+		if err := n.NoteRepo.SaveWithDB(tx, note); err != nil {
+			return err
+		}
+		// Folders are not part of revisions, only the inherited visibility is
+		if before.Visibility != note.Visibility {
+			if err := n.recordNoteRevision(tx, &before, note, actor.ID); err != nil {
+				return err
+			}
+		}
+		return n.Audit.Record(tx, &entity.AuditLogEvent{
+			ActorUserID: &actor.ID,
+			ActionType:  entity.AuditActionNoteUpdate,
+			SubjectType: entity.AuditSubjectNote,
+			SubjectID:   strconv.Itoa(note.ID),
+			Source:      entity.AuditSourceHTTPAPI,
+			Changes:     changes,
+		})
+	})
+	if errors.Is(err, repository.ErrStaleVersion) {
+		return nil, n.notePreconditionFailed(note.ID)
+	}
+
+	if err != nil {
+		log.Errorf("failed to move note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	resp := toNoteResponse(note, false)
+	go n.dispatchNoteUpdateEvent(&before, note, resp)
+	return resp, nil
+}
+
+// fetchNoteFolder finds the folder a note is being placed into, if any,
+// and makes sure the actor is allowed to see it.
+func (n *NoteService) fetchNoteFolder(actor *entity.User, folderID *int) (*entity.Folder, apierror.ErrorResponse) {
+	if folderID == nil {
+		return nil, nil
+	}
+
+	folder, err := n.FolderRepo.FindByID(*folderID)
+	if err != nil {
+		log.Errorf("failed to fetch folder %d: %v", *folderID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	if apierr := n.FolderPolicy.CanSee(folder, actor); apierr != nil {
+		return nil, apierr
+	}
+	return folder, nil
+}
+
+func (n *NoteService) isFolderPrivate(folderID *int) (bool, apierror.ErrorResponse) {
+	if folderID == nil {
+		return false, nil
+	}
+
+	folder, err := n.FolderRepo.FindByID(*folderID)
+	if err != nil {
+		log.Errorf("failed to fetch folder %d: %v", *folderID, err)
+		return false, apierror.InternalServerError
+	}
+	return folder != nil && folder.Visibility == entity.VisibilityPrivate, nil
+}
+
+// checkPublicInFolder rejects making a note PUBLIC while it sits in a PRIVATE folder.
+func (n *NoteService) checkPublicInFolder(folderID *int) apierror.ErrorResponse {
+	private, apierr := n.isFolderPrivate(folderID)
+	if apierr != nil {
+		return apierr
+	}
+
+	if private {
+		return apierror.PrivateFolderContentError
+	}
+	return nil
+}
+
+func inheritFolderVisibility(note *entity.Note, folder *entity.Folder) {
+	if folder != nil && folder.Visibility == entity.VisibilityPrivate {
+		note.Visibility = entity.VisibilityPrivate
+	}
+}
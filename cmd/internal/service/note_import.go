@@ -0,0 +1,749 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/infrastructure/storage"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"simplenotes/cmd/internal/utils/vault"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// noteImportStaleAfter is how long a running import can go without progress
+	// before another run picks it up, resuming after its last recorded file.
+	noteImportStaleAfter   = 10 * time.Minute
+	maxImportedNoteBytes   = 1_000_000 // Matches the content limit of text notes
+	maxImportManifestBytes = 10_000_000
+)
+
+var (
+	zipSignature      = []byte("PK\x03\x04")
+	emptyZipSignature = []byte("PK\x05\x06")
+)
+
+type NoteImportRepository interface {
+	FindByID(id int) (*entity.NoteImport, error)
+	FindNext(staleBefore int64) (*entity.NoteImport, error)
+	FindAllFiles(importID int) ([]*entity.NoteImportFile, error)
+	SaveWithDB(db *gorm.DB, noteImport *entity.NoteImport) error
+	SaveFileWithDB(db *gorm.DB, file *entity.NoteImportFile) error
+}
+
+// NoteImportService turns ZIP archives into notes: markdown vaults, where the
+// front matter of each file gives the name and tags of its note, and archives
+// made by ExportNotes, whose manifest is used instead.
+type NoteImportService struct {
+	DB         *gorm.DB
+	ImportRepo NoteImportRepository
+	Notes      *NoteService
+	Validate   *validator.Validate
+}
+
+func NewNoteImportService(
+	db *gorm.DB,
+	importRepo NoteImportRepository,
+	noteService *NoteService,
+	validate *validator.Validate,
+) *NoteImportService {
+	return &NoteImportService{
+		DB:         db,
+		ImportRepo: importRepo,
+		Notes:      noteService,
+		Validate:   validate,
+	}
+}
+
+// StartNoteImport stores the uploaded archive and queues its import, which the
+// NoteImporter job runs in the background. The archive is not opened here.
+func (i *NoteImportService) StartNoteImport(actor *entity.User, req *contract.NoteImportRequest, file *contract.NoteFile) (*contract.NoteImportResponse, apierror.ErrorResponse) {
+	if !actor.Permissions.HasEffective(entity.PermissionCreateNotes) {
+		return nil, apierror.UserMissingPermsError
+	}
+
+	if valerr := i.Validate.Struct(req); valerr != nil {
+		return nil, apierror.FromValidationError(valerr)
+	}
+
+	if _, apierr := i.Notes.fetchNoteFolder(actor, req.FolderID); apierr != nil {
+		return nil, apierr
+	}
+
+	if strings.TrimSpace(file.Filename) == "" {
+		return nil, apierror.MissingFileNameError
+	}
+
+	if ext, ok := utils.CheckFileExt(strings.ToLower(file.Filename), []string{"zip"}); !ok {
+		return nil, apierror.NewInvalidFileExtError(ext)
+	}
+
+	sniffer := bufio.NewReader(file.Body)
+	head, err := sniffer.Peek(len(zipSignature))
+	if err != nil && !errors.Is(err, io.EOF) {
+		log.Errorf("failed to read archive: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	if !bytes.Equal(head, zipSignature) && !bytes.Equal(head, emptyZipSignature) {
+		return nil, apierror.NewFileContentMismatchError(".zip")
+	}
+
+	archive := uuid.NewString() + ".zip"
+	body := &noteFileReader{
+		body:  sniffer,
+		hash:  sha256.New(),
+		limit: contract.MaxNoteImportSizeBytes,
+	}
+
+	err = i.Notes.Storage.UploadFile(body, storage.PathImports+archive, "application/zip")
+	if errors.Is(err, errNoteFileTooLarge) {
+		return nil, apierror.NewNoteContentTooLargeError(contract.MaxNoteImportSizeBytes)
+	}
+
+	if err != nil {
+		log.Errorf("failed to upload archive: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	visibility := entity.NoteVisibility(req.Visibility)
+	if visibility == "" {
+		visibility = entity.VisibilityPrivate
+	}
+
+	now := utils.NowUTC()
+	noteImport := &entity.NoteImport{
+		CreatedByID: actor.ID,
+		Name:        attachmentName(file.Filename),
+		Archive:     archive,
+		FolderID:    req.FolderID,
+		Visibility:  visibility,
+		Status:      entity.NoteImportPending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err = i.ImportRepo.SaveWithDB(nil, noteImport); err != nil {
+		log.Errorf("failed to save note import: %v", err)
+		i.deleteArchive(noteImport)
+		return nil, apierror.InternalServerError
+	}
+	return toNoteImportResponse(noteImport, nil), nil
+}
+
+// GetNoteImport returns the progress of an import, along with the outcome of
+// each file processed so far. Only the user who started it can see it.
+func (i *NoteImportService) GetNoteImport(actor *entity.User, importId int) (*contract.NoteImportResponse, apierror.ErrorResponse) {
+	noteImport, err := i.ImportRepo.FindByID(importId)
+	if err != nil {
+		log.Errorf("failed to fetch note import %d: %v", importId, err)
+		return nil, apierror.InternalServerError
+	}
+
+	if noteImport == nil || noteImport.CreatedByID != actor.ID {
+		return nil, apierror.NotFoundError
+	}
+
+	files, err := i.ImportRepo.FindAllFiles(noteImport.ID)
+	if err != nil {
+		log.Errorf("failed to fetch files of note import %d: %v", noteImport.ID, err)
+		return nil, apierror.InternalServerError
+	}
+	return toNoteImportResponse(noteImport, files), nil
+}
+
+// RunPendingImports runs every import waiting at 'now', one at a time, and
+// returns how many were run. Imports stopped by an error stay RUNNING and are
+// resumed once stale.
+func (i *NoteImportService) RunPendingImports(now int64) (int, error) {
+	staleBefore := now - noteImportStaleAfter.Milliseconds()
+
+	ran := 0
+	for {
+		noteImport, err := i.ImportRepo.FindNext(staleBefore)
+		if err != nil {
+			return ran, err
+		}
+
+		if noteImport == nil {
+			return ran, nil
+		}
+
+		if err = i.runImport(noteImport); err != nil {
+			return ran, fmt.Errorf("note import %d: %w", noteImport.ID, err)
+		}
+		ran++
+	}
+}
+
+func (i *NoteImportService) runImport(noteImport *entity.NoteImport) error {
+	noteImport.Status = entity.NoteImportRunning
+	noteImport.UpdatedAt = utils.NowUTC()
+	if err := i.ImportRepo.SaveWithDB(nil, noteImport); err != nil {
+		return err
+	}
+
+	actor, err := i.Notes.UserRepo.FindByID(noteImport.CreatedByID)
+	if err != nil {
+		return err
+	}
+
+	if actor == nil || !actor.Active || actor.Suspended || !actor.Permissions.HasEffective(entity.PermissionCreateNotes) {
+		return i.finishImport(noteImport, "The user who uploaded the archive can no longer create notes")
+	}
+
+	if _, apierr := i.Notes.fetchNoteFolder(actor, noteImport.FolderID); apierr != nil {
+		if apierr.Code() == http.StatusInternalServerError {
+			return errors.New("failed to fetch the destination folder")
+		}
+		return i.finishImport(noteImport, "The destination folder is no longer available")
+	}
+
+	object, err := i.Notes.Storage.GetFile(storage.PathImports + noteImport.Archive)
+	if errors.Is(err, storage.ErrorObjectNotFound) {
+		return i.finishImport(noteImport, "The archive is no longer available")
+	}
+
+	if err != nil {
+		return err
+	}
+	defer object.Body.Close()
+
+	archive, err := zip.NewReader(&archiveReaderAt{body: object.Body}, object.Size)
+	if err != nil {
+		return i.finishImport(noteImport, "The file is not a valid ZIP archive")
+	}
+
+	recorded, err := i.ImportRepo.FindAllFiles(noteImport.ID)
+	if err != nil {
+		return err
+	}
+
+	run := newNoteImportRun(i, noteImport, actor, archive, recorded)
+	if err = run.importArchive(); err != nil {
+		return err
+	}
+	return i.finishImport(noteImport, "")
+}
+
+// finishImport marks the import as DONE, or FAILED when 'failure' is set, and
+// removes its archive from the storage.
+func (i *NoteImportService) finishImport(noteImport *entity.NoteImport, failure string) error {
+	now := utils.NowUTC()
+	noteImport.Status = entity.NoteImportDone
+	if failure != "" {
+		noteImport.Status = entity.NoteImportFailed
+		noteImport.Error = failure
+	}
+	noteImport.UpdatedAt = now
+	noteImport.FinishedAt = &now
+
+	if err := i.ImportRepo.SaveWithDB(nil, noteImport); err != nil {
+		return err
+	}
+
+	i.deleteArchive(noteImport)
+	return nil
+}
+
+func (i *NoteImportService) deleteArchive(noteImport *entity.NoteImport) {
+	if err := i.Notes.Storage.DeleteFile(storage.PathImports + noteImport.Archive); err != nil {
+		log.Errorf("failed to delete archive of note import %d: %v", noteImport.ID, err)
+	}
+}
+
+// noteImportItem is a file of the archive to be turned into a note.
+type noteImportItem struct {
+	path       string
+	noteType   entity.NoteType
+	name       string // Read from the front matter of vault files
+	tags       []string
+	visibility string
+	media      []*noteImportMedia // Listed by the manifest, vault files embed theirs
+}
+
+type noteImportMedia struct {
+	path string
+	name string
+}
+
+// noteImportRun is a single pass over the archive of an import. Files whose
+// outcome was recorded by an earlier run are left as they are.
+type noteImportRun struct {
+	service    *NoteImportService
+	noteImport *entity.NoteImport
+	actor      *entity.User
+	files      map[string]*zip.File
+	paths      []string            // Paths of 'files', sorted
+	byName     map[string][]string // Paths by lowercased base name, for vault embeds
+	manifest   *contract.NoteExportManifest
+	recorded   map[string]bool
+	embedded   map[string]bool // Paths of the media of every note, imported or not
+}
+
+func newNoteImportRun(service *NoteImportService, noteImport *entity.NoteImport, actor *entity.User, archive *zip.Reader, recorded []*entity.NoteImportFile) *noteImportRun {
+	run := &noteImportRun{
+		service:    service,
+		noteImport: noteImport,
+		actor:      actor,
+		files:      make(map[string]*zip.File),
+		byName:     make(map[string][]string),
+		recorded:   make(map[string]bool),
+		embedded:   make(map[string]bool),
+	}
+
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(strings.ReplaceAll(file.Name, "\\", "/"), "/"))
+		if _, ok := run.files[name]; ok {
+			continue
+		}
+
+		run.files[name] = file
+		run.paths = append(run.paths, name)
+	}
+	sort.Strings(run.paths)
+
+	for _, name := range run.paths {
+		base := strings.ToLower(path.Base(name))
+		run.byName[base] = append(run.byName[base], name)
+	}
+
+	if file, ok := run.files[noteExportManifestPath]; ok {
+		run.manifest = readImportManifest(file)
+	}
+
+	for _, file := range recorded {
+		run.recorded[file.Path] = true
+	}
+	return run
+}
+
+// readImportManifest decodes the manifest of an exported archive, archives
+// without a valid one are read as vaults.
+func readImportManifest(file *zip.File) *contract.NoteExportManifest {
+	data, err := readImportFile(file, maxImportManifestBytes)
+	if err != nil {
+		return nil
+	}
+
+	var manifest contract.NoteExportManifest
+	if err = json.Unmarshal(data, &manifest); err != nil || manifest.Version == 0 {
+		return nil
+	}
+	return &manifest
+}
+
+func (r *noteImportRun) importArchive() error {
+	total := len(r.paths)
+	if r.manifest != nil {
+		total-- // The manifest itself
+	}
+
+	if r.noteImport.Total != total {
+		r.noteImport.Total = total
+		r.noteImport.UpdatedAt = utils.NowUTC()
+		if err := r.service.ImportRepo.SaveWithDB(nil, r.noteImport); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range r.items() {
+		if r.recorded[item.path] {
+			continue
+		}
+
+		if err := r.importItem(item); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range r.paths {
+		if r.recorded[name] || (r.manifest != nil && name == noteExportManifestPath) {
+			continue
+		}
+
+		reason := r.skipReason(name)
+		if err := r.record(name, entity.NoteImportFileSkipped, nil, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// items lists the notes of the archive in path order: the entries of the
+// manifest, or the markdown files of a vault.
+func (r *noteImportRun) items() []*noteImportItem {
+	var items []*noteImportItem
+	if r.manifest != nil {
+		for _, entry := range r.manifest.Notes {
+			if _, ok := r.files[entry.Path]; !ok {
+				continue
+			}
+
+			item := &noteImportItem{
+				path:       entry.Path,
+				noteType:   entity.NoteType(entry.NoteType),
+				name:       entry.Name,
+				tags:       entry.Tags,
+				visibility: entry.Visibility,
+			}
+
+			for _, attachment := range entry.Attachments {
+				if _, ok := r.files[attachment.Path]; ok {
+					r.embedded[attachment.Path] = true
+					item.media = append(item.media, &noteImportMedia{path: attachment.Path, name: attachment.Name})
+				}
+			}
+			items = append(items, item)
+		}
+
+		sort.Slice(items, func(a, b int) bool {
+			return items[a].path < items[b].path
+		})
+		return items
+	}
+
+	for _, name := range r.paths {
+		if isMarkdownPath(name) && !isHiddenPath(name) {
+			items = append(items, &noteImportItem{path: name, noteType: entity.NoteTypeMarkdown})
+		}
+	}
+	return items
+}
+
+func (r *noteImportRun) importItem(item *noteImportItem) error {
+	file := r.files[item.path]
+
+	var content string
+	var missing []string
+	if item.noteType != entity.NoteTypeReference {
+		data, err := readImportFile(file, maxImportedNoteBytes)
+		if errors.Is(err, errNoteFileTooLarge) {
+			return r.record(item.path, entity.NoteImportFileInvalid, nil, fmt.Sprintf("Note content is too large, max: %d", maxImportedNoteBytes))
+		}
+
+		if err != nil || !utf8.Valid(data) {
+			return r.record(item.path, entity.NoteImportFileInvalid, nil, "The file could not be read as text")
+		}
+
+		content = string(data)
+		if r.manifest == nil {
+			doc := vault.Parse(content)
+			content = doc.Body
+			item.name = doc.Title
+			item.tags = doc.Tags
+			item.media, missing = r.resolveEmbeds(item.path, doc.Body)
+		}
+	}
+
+	if item.name == "" {
+		item.name = strings.TrimSuffix(path.Base(item.path), path.Ext(item.path))
+	}
+
+	if item.visibility == "" {
+		item.visibility = string(r.noteImport.Visibility)
+	}
+
+	if item.tags == nil {
+		item.tags = []string{}
+	}
+
+	existing, err := r.service.Notes.NoteRepo.FindByName(r.actor.ID, r.noteImport.FolderID, item.name)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		return r.record(item.path, entity.NoteImportFileSkipped, &existing.ID, "A note with this name already exists")
+	}
+
+	note, apierr := r.createNote(item, content)
+	if apierr != nil {
+		return r.record(item.path, entity.NoteImportFileInvalid, nil, apiErrorReason(apierr))
+	}
+
+	var reason string
+	if len(missing) > 0 {
+		reason = "Embedded files not found in the archive: " + strings.Join(missing, ", ")
+	}
+
+	if err = r.record(item.path, entity.NoteImportFileCreated, &note.ID, reason); err != nil {
+		return err
+	}
+
+	for _, media := range item.media {
+		if err = r.attachMedia(note.ID, media); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createNote creates the note through NoteService, so imported notes are
+// checked and audited just like the ones created one by one.
+func (r *noteImportRun) createNote(item *noteImportItem, content string) (*contract.NoteResponse, apierror.ErrorResponse) {
+	if item.noteType != entity.NoteTypeReference {
+		return r.service.Notes.CreateTextNote(r.actor, &contract.TextNoteRequest{
+			Name:       item.name,
+			Content:    content,
+			NoteType:   string(item.noteType),
+			Visibility: item.visibility,
+			Tags:       item.tags,
+			FolderID:   r.noteImport.FolderID,
+		})
+	}
+
+	body, err := r.files[item.path].Open()
+	if err != nil {
+		return nil, apierror.NewSimple(http.StatusBadRequest, "The file could not be read from the archive")
+	}
+	defer body.Close()
+
+	return r.service.Notes.CreateFileNote(r.actor, &contract.NoteRequest{
+		Name:       item.name,
+		Visibility: item.visibility,
+		Tags:       item.tags,
+		FolderID:   r.noteImport.FolderID,
+	}, &contract.NoteFile{Filename: path.Base(item.path), Body: body})
+}
+
+// attachMedia attaches the file to the note. Files embedded by several notes
+// are attached to each of them, but only the first attachment is recorded.
+func (r *noteImportRun) attachMedia(noteID int, media *noteImportMedia) error {
+	var apierr apierror.ErrorResponse
+	if body, err := r.files[media.path].Open(); err != nil {
+		apierr = apierror.NewSimple(http.StatusBadRequest, "The file could not be read from the archive")
+	} else {
+		_, apierr = r.service.Notes.AddNoteAttachment(r.actor, noteID, &contract.NoteFile{Filename: media.name, Body: body})
+		body.Close()
+	}
+
+	if r.recorded[media.path] {
+		return nil
+	}
+
+	if apierr != nil {
+		return r.record(media.path, entity.NoteImportFileInvalid, &noteID, apiErrorReason(apierr))
+	}
+	return r.record(media.path, entity.NoteImportFileAttached, &noteID, "")
+}
+
+// resolveEmbeds finds the files embedded by a vault note, the way vault apps
+// do: next to the note, from the root of the vault, then by name anywhere.
+// Embedded notes are not files to attach, and are left out.
+func (r *noteImportRun) resolveEmbeds(notePath, content string) ([]*noteImportMedia, []string) {
+	var media []*noteImportMedia
+	var missing []string
+	for _, ref := range vault.Embeds(content) {
+		ref = strings.ReplaceAll(ref, "\\", "/")
+		if ext := path.Ext(ref); ext == "" || isMarkdownPath(ref) {
+			continue
+		}
+
+		found := ""
+		for _, candidate := range []string{path.Join(path.Dir(notePath), ref), path.Clean(strings.TrimPrefix(ref, "/"))} {
+			if _, ok := r.files[candidate]; ok {
+				found = candidate
+				break
+			}
+		}
+
+		if named := r.byName[strings.ToLower(path.Base(ref))]; found == "" && len(named) > 0 {
+			found = named[0]
+		}
+
+		if found == "" {
+			missing = append(missing, ref)
+			continue
+		}
+		r.embedded[found] = true
+		media = append(media, &noteImportMedia{path: found, name: path.Base(found)})
+	}
+	return media, missing
+}
+
+func (r *noteImportRun) skipReason(name string) string {
+	switch {
+	case isHiddenPath(name):
+		return "Hidden file"
+	case r.embedded[name]:
+		return "The note embedding it was not imported"
+	case r.manifest != nil:
+		return "Not listed in the manifest"
+	}
+
+	if ext := strings.ToLower(path.Ext(name)); ext != "" && slices.Contains(contract.ValidNoteFileTypes, ext[1:]) {
+		return "Not embedded by any note"
+	}
+	return "Unsupported file type"
+}
+
+// record saves the outcome of a file along with the progress of the import.
+func (r *noteImportRun) record(name string, status entity.NoteImportFileStatus, noteID *int, reason string) error {
+	switch status {
+	case entity.NoteImportFileCreated:
+		r.noteImport.Created++
+	case entity.NoteImportFileAttached:
+		r.noteImport.Attached++
+	case entity.NoteImportFileSkipped:
+		r.noteImport.Skipped++
+	case entity.NoteImportFileInvalid:
+		r.noteImport.Invalid++
+	}
+	r.noteImport.Processed++
+	r.noteImport.UpdatedAt = utils.NowUTC()
+
+	err := r.service.DB.Transaction(func(tx *gorm.DB) error {
+		if err := r.service.ImportRepo.SaveFileWithDB(tx, &entity.NoteImportFile{
+			ImportID: r.noteImport.ID,
+			Path:     name,
+			Status:   status,
+			NoteID:   noteID,
+			Reason:   reason,
+		}); err != nil {
+			return err
+		}
+		return r.service.ImportRepo.SaveWithDB(tx, r.noteImport)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.recorded[name] = true
+	return nil
+}
+
+// readImportFile reads a whole file of the archive, up to 'limit' bytes.
+func readImportFile(file *zip.File, limit int64) ([]byte, error) {
+	if file.UncompressedSize64 > uint64(limit) {
+		return nil, errNoteFileTooLarge
+	}
+
+	body, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > limit {
+		return nil, errNoteFileTooLarge
+	}
+	return data, nil
+}
+
+func isMarkdownPath(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// isHiddenPath reports files of hidden folders, like the settings of vault
+// apps (.obsidian/), and the metadata macOS adds to archives.
+func isHiddenPath(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") || segment == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+// apiErrorReason flattens an error into one line, for outcomes reported per item.
+func apiErrorReason(apierr apierror.ErrorResponse) string {
+	switch e := apierr.(type) {
+	case *apierror.APIError:
+		return e.Message
+	case *apierror.StructuredError:
+		fields := make([]string, 0, len(e.Errors))
+		for field := range e.Errors {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		problems := make([]string, 0, len(fields))
+		for _, field := range fields {
+			problems = append(problems, field+": "+strings.Join(e.Errors[field], ", "))
+		}
+		return strings.Join(problems, "; ")
+	}
+	return http.StatusText(apierr.Code())
+}
+
+// archiveReaderAt reads a stored archive at the offsets zip asks for. Reading
+// on from the last offset keeps using the same download.
+type archiveReaderAt struct {
+	body io.ReadSeeker
+}
+
+func (a *archiveReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := a.body.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err := io.ReadFull(a.body, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func toNoteImportResponse(noteImport *entity.NoteImport, files []*entity.NoteImportFile) *contract.NoteImportResponse {
+	resp := &contract.NoteImportResponse{
+		ID:         noteImport.ID,
+		Name:       noteImport.Name,
+		Status:     string(noteImport.Status),
+		Error:      noteImport.Error,
+		FolderID:   noteImport.FolderID,
+		Visibility: string(noteImport.Visibility),
+		Total:      noteImport.Total,
+		Processed:  noteImport.Processed,
+		Created:    noteImport.Created,
+		Attached:   noteImport.Attached,
+		Skipped:    noteImport.Skipped,
+		Invalid:    noteImport.Invalid,
+		CreatedAt:  utils.FormatEpoch(noteImport.CreatedAt),
+		UpdatedAt:  utils.FormatEpoch(noteImport.UpdatedAt),
+	}
+
+	if noteImport.FinishedAt != nil {
+		finishedAt := utils.FormatEpoch(*noteImport.FinishedAt)
+		resp.FinishedAt = &finishedAt
+	}
+
+	for _, file := range files {
+		resp.Files = append(resp.Files, &contract.NoteImportFileResponse{
+			Path:   file.Path,
+			Status: string(file.Status),
+			NoteID: file.NoteID,
+			Reason: file.Reason,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/png"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/infrastructure/storage"
+	"simplenotes/cmd/internal/utils"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestImportTurnsAVaultIntoNotesInTheBackground(t *testing.T) {
+	db := newTestDB(t)
+
+	local, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create local storage: %v", err)
+	}
+
+	noteSvc := newTestNoteService(t, db, 14200)
+	noteSvc.Storage = local
+	importSvc := NewNoteImportService(db, repository.NewNoteImportRepository(db), noteSvc, newTestValidator())
+	owner := newTestWriter(t, db)
+
+	if _, apierr := noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+		Name:       "Existing",
+		Content:    "already here",
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: string(entity.VisibilityPrivate),
+		Tags:       []string{},
+	}); apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+
+	var diagram bytes.Buffer
+	if err = png.Encode(&diagram, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+
+	archive := newTestZip(t, map[string][]byte{
+		"Vault/Welcome.md":          []byte("---\ntitle: Welcome home\ntags: [intro, \"#guide\"]\n---\n# Hello\n![[diagram.png|200]] ![lost](missing.png)\n"),
+		"Vault/Daily/2024-01-01.md": []byte("Plain day, see ![chart](../assets/diagram.png)"),
+		"Vault/Existing.md":         []byte("duplicate"),
+		"Vault/Bad.md":              []byte("---\ntitle: " + strings.Repeat("x", 81) + "\n---\nbody"),
+		"Vault/assets/diagram.png":  diagram.Bytes(),
+		"Vault/assets/unused.png":   diagram.Bytes(),
+		"Vault/notes.txt":           []byte("not markdown"),
+		"Vault/.obsidian/app.json":  []byte("{}"),
+	})
+
+	started, apierr := importSvc.StartNoteImport(owner, &contract.NoteImportRequest{}, newTestNoteFile("vault.zip", archive))
+	if apierr != nil {
+		t.Fatalf("start import returned api error: %#v", apierr)
+	}
+	if started.Status != string(entity.NoteImportPending) || started.Visibility != string(entity.VisibilityPrivate) {
+		t.Fatalf("expected a pending private import, got %#v", started)
+	}
+
+	if ran, err := importSvc.RunPendingImports(utils.NowUTC()); err != nil || ran != 1 {
+		t.Fatalf("expected one import to run, got %d: %v", ran, err)
+	}
+
+	result, apierr := importSvc.GetNoteImport(owner, started.ID)
+	if apierr != nil {
+		t.Fatalf("get import returned api error: %#v", apierr)
+	}
+	if result.Status != string(entity.NoteImportDone) || result.FinishedAt == nil {
+		t.Fatalf("expected the import to be done, got %#v", result)
+	}
+	if result.Total != 8 || result.Processed != 8 || result.Created != 2 || result.Attached != 1 || result.Skipped != 4 || result.Invalid != 1 {
+		t.Fatalf("unexpected import counters: %#v", result)
+	}
+
+	files := make(map[string]*contract.NoteImportFileResponse)
+	for _, file := range result.Files {
+		files[file.Path] = file
+	}
+
+	welcome := files["Vault/Welcome.md"]
+	if welcome.Status != string(entity.NoteImportFileCreated) || !strings.Contains(welcome.Reason, "missing.png") {
+		t.Fatalf("unexpected outcome of the welcome note: %#v", welcome)
+	}
+
+	note, apierr := noteSvc.GetNoteByID(owner, *welcome.NoteID)
+	if apierr != nil {
+		t.Fatalf("get note returned api error: %#v", apierr)
+	}
+	if note.Name != "Welcome home" || strings.Join(note.Tags, ",") != "intro,guide" || !strings.HasPrefix(note.Content, "# Hello") {
+		t.Fatalf("expected the front matter to be mapped onto the note, got %#v", note)
+	}
+
+	attachments, apierr := noteSvc.GetNoteAttachments(owner, note.ID)
+	if apierr != nil || len(attachments) != 1 || attachments[0].Name != "diagram.png" {
+		t.Fatalf("expected the embedded image to be attached, got %#v (%#v)", attachments, apierr)
+	}
+
+	daily := files["Vault/Daily/2024-01-01.md"]
+	if daily.Status != string(entity.NoteImportFileCreated) {
+		t.Fatalf("unexpected outcome of the daily note: %#v", daily)
+	}
+	if attachments, _ = noteSvc.GetNoteAttachments(owner, *daily.NoteID); len(attachments) != 1 {
+		t.Fatalf("expected an image embedded twice to be attached to both notes, got %#v", attachments)
+	}
+	// Files are processed in path order, the daily note comes first
+	if diagramFile := files["Vault/assets/diagram.png"]; diagramFile.Status != string(entity.NoteImportFileAttached) || *diagramFile.NoteID != *daily.NoteID {
+		t.Fatalf("unexpected outcome of the embedded image: %#v", diagramFile)
+	}
+
+	expected := map[string]string{
+		"Vault/Existing.md":        "A note with this name already exists",
+		"Vault/assets/unused.png":  "Not embedded by any note",
+		"Vault/notes.txt":          "Unsupported file type",
+		"Vault/.obsidian/app.json": "Hidden file",
+	}
+	for path, reason := range expected {
+		if file := files[path]; file.Status != string(entity.NoteImportFileSkipped) || file.Reason != reason {
+			t.Fatalf("expected %s to be skipped with %q, got %#v", path, reason, file)
+		}
+	}
+	if bad := files["Vault/Bad.md"]; bad.Status != string(entity.NoteImportFileInvalid) || !strings.Contains(bad.Reason, "name: Value is too long") {
+		t.Fatalf("unexpected outcome of the invalid note: %#v", bad)
+	}
+
+	events, err := repository.NewAuditRepository(db).List(&repository.AuditLogFilter{
+		Limit:      10,
+		ActionType: auditActionPtr(entity.AuditActionNoteCreate),
+	})
+	if err != nil {
+		t.Fatalf("list audit logs: %v", err)
+	}
+	audited := make(map[string]bool)
+	for _, event := range events {
+		audited[event.SubjectID] = true
+	}
+	if len(events) != 3 || !audited[strconv.Itoa(note.ID)] || !audited[strconv.Itoa(*daily.NoteID)] {
+		t.Fatalf("expected every imported note to be audited, got %d events", len(events))
+	}
+
+	if _, apierr = importSvc.GetNoteImport(&entity.User{ID: owner.ID + 1}, started.ID); apierr == nil || apierr.Code() != 404 {
+		t.Fatalf("expected other users not to see the import, got %#v", apierr)
+	}
+}
+
+func TestImportReadsTheManifestOfExportedArchives(t *testing.T) {
+	db := newTestDB(t)
+
+	local, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create local storage: %v", err)
+	}
+
+	noteSvc := newTestNoteService(t, db, 14300)
+	noteSvc.Storage = local
+	importRepo := repository.NewNoteImportRepository(db)
+	importSvc := NewNoteImportService(db, importRepo, noteSvc, newTestValidator())
+	owner := newTestWriter(t, db)
+
+	if _, apierr := noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+		Name:       "Pipeline",
+		Content:    `{"nodes": []}`,
+		NoteType:   string(entity.NoteTypeFlowchart),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"ops"},
+	}); apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+
+	report, apierr := noteSvc.CreateFileNote(owner, &contract.NoteRequest{
+		Name:       "Report",
+		Visibility: string(entity.VisibilityPrivate),
+		Tags:       []string{"finance"},
+	}, newTestNoteFile("report.pdf", []byte("%PDF-1.4 report")))
+	if apierr != nil {
+		t.Fatalf("create file note returned api error: %#v", apierr)
+	}
+
+	if _, apierr = noteSvc.AddNoteAttachment(owner, report.ID, newTestNoteFile("appendix.pdf", []byte("%PDF-1.4 appendix"))); apierr != nil {
+		t.Fatalf("add attachment returned api error: %#v", apierr)
+	}
+
+	writeExport, apierr := noteSvc.ExportNotes(owner, &contract.NoteExportRequest{})
+	if apierr != nil {
+		t.Fatalf("export returned api error: %#v", apierr)
+	}
+	var exported bytes.Buffer
+	if err = writeExport(&exported); err != nil {
+		t.Fatalf("write export: %v", err)
+	}
+
+	copies := &entity.Folder{Name: "Copies", Visibility: entity.VisibilityPublic, CreatedByID: owner.ID}
+	if err = repository.NewFolderRepository(db).SaveWithDB(nil, copies); err != nil {
+		t.Fatalf("save folder: %v", err)
+	}
+
+	started, apierr := importSvc.StartNoteImport(owner, &contract.NoteImportRequest{FolderID: &copies.ID}, newTestNoteFile("export.zip", exported.Bytes()))
+	if apierr != nil {
+		t.Fatalf("start import returned api error: %#v", apierr)
+	}
+	if _, err = importSvc.RunPendingImports(utils.NowUTC()); err != nil {
+		t.Fatalf("run imports: %v", err)
+	}
+
+	result, _ := importSvc.GetNoteImport(owner, started.ID)
+	if result.Status != string(entity.NoteImportDone) || result.Total != 3 || result.Created != 2 || result.Attached != 1 {
+		t.Fatalf("unexpected import result: %#v", result)
+	}
+
+	for _, file := range result.Files {
+		if file.Status != string(entity.NoteImportFileCreated) {
+			continue
+		}
+
+		note, apierr := noteSvc.GetNoteByID(owner, *file.NoteID)
+		if apierr != nil {
+			t.Fatalf("get note returned api error: %#v", apierr)
+		}
+		if note.FolderID == nil || *note.FolderID != copies.ID {
+			t.Fatalf("expected imported notes in the destination folder, got %#v", note)
+		}
+
+		switch note.Name {
+		case "Pipeline":
+			if note.NoteType != string(entity.NoteTypeFlowchart) || note.Visibility != string(entity.VisibilityPublic) || note.Tags[0] != "ops" {
+				t.Fatalf("expected the manifest to be used, got %#v", note)
+			}
+		case "Report":
+			if note.NoteType != string(entity.NoteTypeReference) || note.ContentType != "application/pdf" || note.Visibility != string(entity.VisibilityPrivate) {
+				t.Fatalf("expected the reference file to be imported, got %#v", note)
+			}
+			if attachments, _ := noteSvc.GetNoteAttachments(owner, note.ID); len(attachments) != 1 || attachments[0].Name != "appendix.pdf" {
+				t.Fatalf("expected the attachment to be imported, got %#v", attachments)
+			}
+		default:
+			t.Fatalf("unexpected note %q", note.Name)
+		}
+	}
+
+	noteImport, err := importRepo.FindByID(started.ID)
+	if err != nil {
+		t.Fatalf("find import: %v", err)
+	}
+	if _, err = local.GetFile(storage.PathImports + noteImport.Archive); err == nil {
+		t.Fatal("expected the archive to be removed once imported")
+	}
+}
+
+func TestImportRejectsFilesThatAreNotArchives(t *testing.T) {
+	db := newTestDB(t)
+	noteSvc := newTestNoteService(t, db, 14100)
+	importSvc := NewNoteImportService(db, repository.NewNoteImportRepository(db), noteSvc, newTestValidator())
+	owner := newTestWriter(t, db)
+
+	if _, apierr := importSvc.StartNoteImport(owner, &contract.NoteImportRequest{}, newTestNoteFile("vault.zip", []byte("not a zip"))); apierr == nil || apierr.Code() != 400 {
+		t.Fatalf("expected content that is not a ZIP to be rejected, got %#v", apierr)
+	}
+	if _, apierr := importSvc.StartNoteImport(owner, &contract.NoteImportRequest{}, newTestNoteFile("vault.tar", []byte("PK\x03\x04"))); apierr == nil || apierr.Code() != 400 {
+		t.Fatalf("expected other extensions to be rejected, got %#v", apierr)
+	}
+
+	reader := &entity.User{ID: owner.ID + 1, Active: true}
+	if _, apierr := importSvc.StartNoteImport(reader, &contract.NoteImportRequest{}, newTestNoteFile("vault.zip", []byte("PK\x05\x06"))); apierr == nil || apierr.Code() != 403 {
+		t.Fatalf("expected users who cannot create notes to be rejected, got %#v", apierr)
+	}
+}
+
+func newTestZip(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if _, err = w.Write(data); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		t.Fatalf("close archive: %v", err)
+	}
+	return buf.Bytes()
+}
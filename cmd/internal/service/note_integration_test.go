@@ -0,0 +1,658 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/policy"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+)
+
+func TestSearchNotesMatchesPrefixesAndHidesPrivateNotes(t *testing.T) {
+	db := newTestDB(t)
+
+	noteSvc := newTestNoteService(t, db, 7000)
+	actor := newTestWriter(t, db)
+
+	requests := []*contract.TextNoteRequest{
+		{Name: "Deployment checklist", Content: "Run <script>migrations</script> first", Visibility: "PUBLIC", Tags: []string{"ops"}},
+		{Name: "Secret deployment", Content: "nothing to see", Visibility: "PRIVATE", Tags: []string{"ops"}},
+		{Name: "Groceries", Content: "milk and eggs", Visibility: "PUBLIC", Tags: []string{"home"}},
+	}
+	for _, req := range requests {
+		req.NoteType = string(entity.NoteTypeMarkdown)
+		if _, apierr := noteSvc.CreateTextNote(actor, req); apierr != nil {
+			t.Fatalf("create note returned api error: %#v", apierr)
+		}
+	}
+
+	resp, apierr := noteSvc.SearchNotes(actor, &contract.NoteSearchRequest{Query: "deploy migr"})
+	if apierr != nil {
+		t.Fatalf("search returned api error: %#v", apierr)
+	}
+	if len(resp.Results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(resp.Results))
+	}
+
+	result := resp.Results[0]
+	if result.Name != "Deployment checklist" {
+		t.Fatalf("unexpected result: %s", result.Name)
+	}
+	if result.NameHighlight != "<mark>Deployment</mark> checklist" {
+		t.Fatalf("unexpected name highlight: %s", result.NameHighlight)
+	}
+	if strings.Contains(result.Snippet, "<script>") {
+		t.Fatalf("snippet must be escaped: %s", result.Snippet)
+	}
+
+	if _, apierr = noteSvc.SearchNotes(actor, &contract.NoteSearchRequest{Query: "*** \"\""}); apierr == nil {
+		t.Fatal("expected query without terms to be rejected")
+	}
+}
+
+func TestGetAllNotesPaginatesWithCursorsAndFilters(t *testing.T) {
+	db := newTestDB(t)
+
+	noteSvc := newTestNoteService(t, db, 8000)
+	actor := newTestWriter(t, db)
+
+	tags := []string{"ops weekly", "ops", "weekly", "ops weekly", "home"}
+	for i, tag := range tags {
+		note := &entity.Note{
+			Name:        "Note " + strconv.Itoa(i),
+			CreatedByID: actor.ID,
+			Tags:        tag,
+			NoteType:    entity.NoteTypeMarkdown,
+			Visibility:  entity.VisibilityPublic,
+			CreatedAt:   int64(1000 + i),
+			UpdatedAt:   int64(1000 + i),
+		}
+		if err := noteSvc.NoteRepo.Save(note); err != nil {
+			t.Fatalf("save note: %v", err)
+		}
+	}
+
+	page1, apierr := noteSvc.GetAllNotes(actor, &contract.NoteListRequest{Limit: 2})
+	if apierr != nil {
+		t.Fatalf("get notes page 1: %#v", apierr)
+	}
+	if len(page1.Notes) != 2 || page1.Notes[0].Name != "Note 4" || page1.NextCursor == nil || page1.PrevCursor != nil {
+		t.Fatalf("unexpected page 1: %#v", page1)
+	}
+
+	page2, apierr := noteSvc.GetAllNotes(actor, &contract.NoteListRequest{Limit: 2, After: page1.NextCursor})
+	if apierr != nil {
+		t.Fatalf("get notes page 2: %#v", apierr)
+	}
+	if len(page2.Notes) != 2 || page2.Notes[0].Name != "Note 2" || page2.PrevCursor == nil {
+		t.Fatalf("unexpected page 2: %#v", page2)
+	}
+
+	back, apierr := noteSvc.GetAllNotes(actor, &contract.NoteListRequest{Limit: 2, Before: page2.PrevCursor})
+	if apierr != nil {
+		t.Fatalf("get notes back to page 1: %#v", apierr)
+	}
+	if len(back.Notes) != 2 || back.Notes[0].Name != "Note 4" || back.PrevCursor != nil {
+		t.Fatalf("unexpected previous page: %#v", back)
+	}
+
+	allMode := "all"
+	tagged, apierr := noteSvc.GetAllNotes(actor, &contract.NoteListRequest{Tags: []string{"OPS", "weekly"}, TagsMode: &allMode})
+	if apierr != nil {
+		t.Fatalf("get notes by tags: %#v", apierr)
+	}
+	if len(tagged.Notes) != 2 || tagged.Notes[0].Name != "Note 3" || tagged.Notes[1].Name != "Note 0" {
+		t.Fatalf("unexpected tagged notes: %#v", tagged.Notes)
+	}
+
+	nameSort := "name"
+	if _, apierr = noteSvc.GetAllNotes(actor, &contract.NoteListRequest{Sort: &nameSort, After: page1.NextCursor}); apierr == nil {
+		t.Fatal("expected cursor from another sort to be rejected")
+	}
+}
+
+func TestNoteGrantsShareAndRevokePrivateNotes(t *testing.T) {
+	db := newTestDB(t)
+
+	auditRepo := repository.NewAuditRepository(db)
+	noteSvc := newTestNoteService(t, db, 8000)
+	owner := newTestWriter(t, db)
+
+	reader := &entity.User{
+		Username:    "reader",
+		Email:       "reader@example.com",
+		Permissions: entity.PermissionCreateNotes,
+		Active:      true,
+		CreatedAt:   utils.NowUTC(),
+		UpdatedAt:   utils.NowUTC(),
+	}
+	if err := repository.NewUserRepository(db).Save(reader); err != nil {
+		t.Fatalf("save reader: %v", err)
+	}
+
+	created, apierr := noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+		Name:       "Salaries",
+		Content:    "confidential",
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: string(entity.VisibilityPrivate),
+		Tags:       []string{"hr"},
+	})
+	if apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+
+	if _, apierr = noteSvc.GetNoteByID(owner, created.ID); apierr != nil {
+		t.Fatalf("expected owner to see their private note, got %#v", apierr)
+	}
+	if _, apierr = noteSvc.GetNoteByID(reader, created.ID); apierr == nil || apierr.Code() != 404 {
+		t.Fatalf("expected private note to be hidden, got %#v", apierr)
+	}
+
+	if _, apierr = noteSvc.PutNoteCollaborator(reader, created.ID, reader.ID, &contract.NoteGrantRequest{Role: "VIEWER"}); apierr == nil {
+		t.Fatal("expected non-owner to be unable to share the note")
+	}
+
+	if _, apierr = noteSvc.PutNoteCollaborator(owner, created.ID, reader.ID, &contract.NoteGrantRequest{Role: "VIEWER"}); apierr != nil {
+		t.Fatalf("share note returned api error: %#v", apierr)
+	}
+
+	if _, apierr = noteSvc.GetNoteByID(reader, created.ID); apierr != nil {
+		t.Fatalf("expected viewer to see the note, got %#v", apierr)
+	}
+
+	list, apierr := noteSvc.GetAllNotes(reader, &contract.NoteListRequest{})
+	if apierr != nil {
+		t.Fatalf("list notes returned api error: %#v", apierr)
+	}
+	if len(list.Notes) != 1 {
+		t.Fatalf("expected shared note to be listed, got %d notes", len(list.Notes))
+	}
+
+	search, apierr := noteSvc.SearchNotes(reader, &contract.NoteSearchRequest{Query: "salar"})
+	if apierr != nil {
+		t.Fatalf("search notes returned api error: %#v", apierr)
+	}
+	if len(search.Results) != 1 {
+		t.Fatalf("expected shared note to be found, got %d results", len(search.Results))
+	}
+
+	newName := "Salaries 2026"
+	if _, apierr = noteSvc.UpdateNote(reader, created.ID, &contract.UpdateNoteRequest{Name: &newName}, nil); apierr == nil || apierr.Code() != 403 {
+		t.Fatalf("expected viewer update to be forbidden, got %#v", apierr)
+	}
+
+	if _, apierr = noteSvc.PutNoteCollaborator(owner, created.ID, reader.ID, &contract.NoteGrantRequest{Role: "EDITOR"}); apierr != nil {
+		t.Fatalf("promote collaborator returned api error: %#v", apierr)
+	}
+	if _, apierr = noteSvc.UpdateNote(reader, created.ID, &contract.UpdateNoteRequest{Name: &newName}, nil); apierr != nil {
+		t.Fatalf("expected editor update to succeed, got %#v", apierr)
+	}
+	if apierr = noteSvc.DeleteNote(reader, created.ID, nil); apierr == nil || apierr.Code() != 403 {
+		t.Fatalf("expected editor delete to be forbidden, got %#v", apierr)
+	}
+
+	if apierr = noteSvc.DeleteNoteCollaborator(owner, created.ID, reader.ID); apierr != nil {
+		t.Fatalf("unshare note returned api error: %#v", apierr)
+	}
+	if _, apierr = noteSvc.GetNoteByID(reader, created.ID); apierr == nil || apierr.Code() != 404 {
+		t.Fatalf("expected revoked note to be hidden, got %#v", apierr)
+	}
+
+	shares, err := auditRepo.List(&repository.AuditLogFilter{
+		Limit:      10,
+		ActionType: auditActionPtr(entity.AuditActionNoteShare),
+	})
+	if err != nil {
+		t.Fatalf("list audit logs: %v", err)
+	}
+	if len(shares) != 2 {
+		t.Fatalf("expected 2 share audit events, got %d", len(shares))
+	}
+
+	unshares, err := auditRepo.List(&repository.AuditLogFilter{
+		Limit:      10,
+		ActionType: auditActionPtr(entity.AuditActionNoteUnshare),
+	})
+	if err != nil {
+		t.Fatalf("list audit logs: %v", err)
+	}
+	if len(unshares) != 1 {
+		t.Fatalf("expected 1 unshare audit event, got %d", len(unshares))
+	}
+}
+
+func TestNoteUpdateFanoutRevokesNotesTurnedPrivate(t *testing.T) {
+	db := newTestDB(t)
+
+	gateway := &recordingGateway{posts: map[string][]contract.EventType{}}
+	noteSvc := newTestNoteService(t, db, 9000)
+	noteSvc.WSService = NewWebSocketService(repository.NewConnectionRepository(db), gateway)
+
+	owner := newTestWriter(t, db)
+	reader := &entity.User{Username: "reader", Email: "reader@example.com", Active: true}
+	moderator := &entity.User{
+		Username:    "moderator",
+		Email:       "moderator@example.com",
+		Permissions: entity.PermissionSeeHiddenNotes,
+		Active:      true,
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	connRepo := repository.NewConnectionRepository(db)
+	for _, user := range []*entity.User{owner, reader, moderator} {
+		if user.ID == 0 {
+			if err := userRepo.Save(user); err != nil {
+				t.Fatalf("save user: %v", err)
+			}
+		}
+		conn := &entity.Connection{
+			ConnectionID:    user.Username,
+			UserID:          user.ID,
+			ExpiresAt:       utils.NowUTC() + 60_000,
+			LastHeartbeatAt: utils.NowUTC(),
+			CreatedAt:       utils.NowUTC(),
+		}
+		if err := connRepo.Save(conn); err != nil {
+			t.Fatalf("save connection: %v", err)
+		}
+	}
+
+	before := &entity.Note{ID: 1, Name: "Roadmap", CreatedByID: owner.ID, Visibility: entity.VisibilityPublic}
+	after := *before
+	after.Visibility = entity.VisibilityPrivate
+
+	noteSvc.dispatchNoteUpdateEvent(before, &after, toNoteResponse(&after, false))
+
+	expected := map[string]contract.EventType{
+		"writer":    contract.EventNoteUpdated,
+		"moderator": contract.EventNoteUpdated,
+		"reader":    contract.EventNoteRevoked,
+	}
+	for connID, eventType := range expected {
+		posts := gateway.posts[connID]
+		if len(posts) != 1 || posts[0] != eventType {
+			t.Fatalf("expected %s to receive %s, got %v", connID, eventType, posts)
+		}
+	}
+
+	// A second private update must not reach the reader at all
+	gateway.posts = map[string][]contract.EventType{}
+	noteSvc.dispatchNoteUpdateEvent(&after, &after, toNoteResponse(&after, false))
+	if posts := gateway.posts["reader"]; len(posts) != 0 {
+		t.Fatalf("expected reader to receive nothing, got %v", posts)
+	}
+
+	// Nor must the deletion of the private note
+	gateway.posts = map[string][]contract.EventType{}
+	noteSvc.dispatchNoteDeleteEvent(&after)
+	for _, connID := range []string{"writer", "moderator"} {
+		if posts := gateway.posts[connID]; len(posts) != 1 || posts[0] != contract.EventNoteDeleted {
+			t.Fatalf("expected %s to receive %s, got %v", connID, contract.EventNoteDeleted, posts)
+		}
+	}
+	if posts := gateway.posts["reader"]; len(posts) != 0 {
+		t.Fatalf("expected reader to receive nothing, got %v", posts)
+	}
+}
+
+// recordingGateway keeps the event types posted to each connection.
+type recordingGateway struct {
+	posts map[string][]contract.EventType
+}
+
+func (g *recordingGateway) PostToConnection(_ context.Context, connID string, payload interface{}) error {
+	if msg, ok := payload.(*contract.OutgoingSocketMessage); ok {
+		g.posts[connID] = append(g.posts[connID], msg.Type)
+	}
+	return nil
+}
+
+func (g *recordingGateway) DeleteConnection(context.Context, string) error { return nil }
+
+func TestTrashedNotesCanBeRestoredAndPurged(t *testing.T) {
+	db := newTestDB(t)
+
+	auditRepo := repository.NewAuditRepository(db)
+	noteSvc := newTestNoteService(t, db, 10000)
+	owner := newTestWriter(t, db)
+
+	var ids []int
+	for _, name := range []string{"Draft", "Scratch"} {
+		created, apierr := noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+			Name:       name,
+			Content:    "body",
+			NoteType:   string(entity.NoteTypeMarkdown),
+			Visibility: string(entity.VisibilityPublic),
+			Tags:       []string{"misc"},
+		})
+		if apierr != nil {
+			t.Fatalf("create note returned api error: %#v", apierr)
+		}
+		if apierr = noteSvc.DeleteNote(owner, created.ID, nil); apierr != nil {
+			t.Fatalf("delete note returned api error: %#v", apierr)
+		}
+		ids = append(ids, created.ID)
+	}
+
+	if _, apierr := noteSvc.GetNoteByID(owner, ids[0]); apierr == nil || apierr.Code() != 404 {
+		t.Fatalf("expected trashed note to be hidden, got %#v", apierr)
+	}
+
+	trash, apierr := noteSvc.GetTrashedNotes(owner, &contract.NoteTrashRequest{})
+	if apierr != nil {
+		t.Fatalf("get trash returned api error: %#v", apierr)
+	}
+	if len(trash.Notes) != 2 || trash.Notes[0].DeletedByID == nil || *trash.Notes[0].DeletedByID != owner.ID {
+		t.Fatalf("expected 2 trashed notes deleted by the owner, got %#v", trash.Notes)
+	}
+
+	restored, apierr := noteSvc.RestoreNote(owner, ids[0])
+	if apierr != nil {
+		t.Fatalf("restore note returned api error: %#v", apierr)
+	}
+	if restored.DeletedAt != nil {
+		t.Fatal("expected restored note to leave the trash")
+	}
+	if _, apierr = noteSvc.GetNoteByID(owner, ids[0]); apierr != nil {
+		t.Fatalf("expected restored note to be visible, got %#v", apierr)
+	}
+
+	purged, err := noteSvc.PurgeTrashedNotes(utils.NowUTC() + 1)
+	if err != nil {
+		t.Fatalf("purge trashed notes: %v", err)
+	}
+	if purged != 1 {
+		t.Fatalf("expected 1 purged note, got %d", purged)
+	}
+
+	if apierr = noteSvc.PurgeNote(owner, ids[1]); apierr == nil || apierr.Code() != 404 {
+		t.Fatalf("expected purged note to be gone, got %#v", apierr)
+	}
+
+	for actionType, count := range map[entity.AuditActionType]int{
+		entity.AuditActionNoteDelete:  2,
+		entity.AuditActionNoteRestore: 1,
+		entity.AuditActionNotePurge:   1,
+	} {
+		events, err := auditRepo.List(&repository.AuditLogFilter{
+			Limit:      10,
+			ActionType: auditActionPtr(actionType),
+		})
+		if err != nil {
+			t.Fatalf("list audit logs: %v", err)
+		}
+		if len(events) != count {
+			t.Fatalf("expected %d %s audit events, got %d", count, actionType, len(events))
+		}
+		if actionType == entity.AuditActionNotePurge && (events[0].ActorUserID != nil || events[0].Source != entity.AuditSourceSystem) {
+			t.Fatalf("expected scheduled purge to be audited as a system event, got %#v", events[0])
+		}
+	}
+}
+
+func TestUpdateNoteHonoursIfMatchVersion(t *testing.T) {
+	db := newTestDB(t)
+
+	noteSvc := newTestNoteService(t, db, 16000)
+	actor := newTestWriter(t, db)
+
+	created, apierr := noteSvc.CreateTextNote(actor, &contract.TextNoteRequest{
+		Name:       "Shared plan",
+		Content:    "v1",
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"plan"},
+	})
+	if apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+	if created.Version != 1 {
+		t.Fatalf("expected new note at version 1, got %d", created.Version)
+	}
+
+	first := "first"
+	updated, apierr := noteSvc.UpdateNote(actor, created.ID, &contract.UpdateNoteRequest{Name: &first}, &contract.Precondition{Versions: []int{1}})
+	if apierr != nil {
+		t.Fatalf("update note returned api error: %#v", apierr)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("expected updated note at version 2, got %d", updated.Version)
+	}
+
+	// Nothing changes, so the version stays put
+	unchanged, apierr := noteSvc.UpdateNote(actor, created.ID, &contract.UpdateNoteRequest{Name: &first}, &contract.Precondition{Versions: []int{2}})
+	if apierr != nil {
+		t.Fatalf("no-op update returned api error: %#v", apierr)
+	}
+	if unchanged.Version != 2 {
+		t.Fatalf("expected a no-op update to keep version 2, got %d", unchanged.Version)
+	}
+
+	second := "second"
+	_, apierr = noteSvc.UpdateNote(actor, created.ID, &contract.UpdateNoteRequest{Name: &second}, &contract.Precondition{Versions: []int{1}})
+	preerr, ok := apierr.(*apierror.PreconditionFailedError)
+	if !ok || preerr.Code() != 412 || preerr.Version != 2 {
+		t.Fatalf("expected stale update to fail with the current version, got %#v", apierr)
+	}
+	if current := preerr.Current.(*contract.NoteResponse); current.Name != first {
+		t.Fatalf("expected current state in the error, got %#v", current)
+	}
+
+	// A concurrent write between loading and saving a note is caught by the repository
+	stale, err := noteSvc.NoteRepo.FindByID(created.ID)
+	if err != nil {
+		t.Fatalf("find note: %v", err)
+	}
+	if apierr = noteSvc.DeleteNote(actor, created.ID, &contract.Precondition{Any: true}); apierr != nil {
+		t.Fatalf("delete note returned api error: %#v", apierr)
+	}
+	stale.Name = second
+	if err = noteSvc.NoteRepo.SaveWithDB(nil, stale); !errors.Is(err, repository.ErrStaleVersion) {
+		t.Fatalf("expected stale save to be rejected, got %v", err)
+	}
+}
+
+// staleNoteRepository hands out notes one version behind, as if they were
+// updated by someone else right after being loaded.
+type staleNoteRepository struct {
+	NoteRepository
+}
+
+func (s *staleNoteRepository) FindByID(id int) (*entity.Note, error) {
+	note, err := s.NoteRepository.FindByID(id)
+	if note != nil {
+		note.Version--
+	}
+	return note, err
+}
+
+func TestConcurrentNoteWritesFailTheirPrecondition(t *testing.T) {
+	db := newTestDB(t)
+
+	noteSvc := newTestNoteService(t, db, 16200)
+	actor := newTestWriter(t, db)
+
+	created, apierr := noteSvc.CreateTextNote(actor, &contract.TextNoteRequest{
+		Name:       "Shared plan",
+		Content:    "v1",
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{},
+	})
+	if apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+
+	content := "v2"
+	if _, apierr = noteSvc.UpdateNote(actor, created.ID, &contract.UpdateNoteRequest{Content: &content}, nil); apierr != nil {
+		t.Fatalf("update note returned api error: %#v", apierr)
+	}
+
+	folder := &entity.Folder{Name: "Plans", Visibility: entity.VisibilityPublic, CreatedByID: actor.ID}
+	if err := repository.NewFolderRepository(db).SaveWithDB(nil, folder); err != nil {
+		t.Fatalf("save folder: %v", err)
+	}
+
+	noteSvc.NoteRepo = &staleNoteRepository{NoteRepository: noteSvc.NoteRepo}
+
+	_, apierr = noteSvc.MoveNote(actor, created.ID, &contract.MoveNoteRequest{FolderID: &folder.ID})
+	if apierr == nil || apierr.Code() != 412 {
+		t.Fatalf("expected a stale move to fail its precondition, got %#v", apierr)
+	}
+
+	_, apierr = noteSvc.RestoreNoteRevision(actor, created.ID, 1)
+	if apierr == nil || apierr.Code() != 412 {
+		t.Fatalf("expected a stale revision restore to fail its precondition, got %#v", apierr)
+	}
+}
+
+func newTestNoteService(t *testing.T, db *gorm.DB, auditStartID int64) *NoteService {
+	t.Helper()
+
+	grantRepo := repository.NewNoteGrantRepository(db)
+	return NewNoteService(
+		db,
+		repository.NewNoteRepository(db),
+		repository.NewNoteRevisionRepository(db),
+		repository.NewNoteTextRepository(db),
+		repository.NewAttachmentRepository(db),
+		repository.NewNoteAttachmentRepository(db),
+		grantRepo,
+		repository.NewFolderRepository(db),
+		repository.NewTagRepository(db),
+		repository.NewNoteLinkRepository(db),
+		repository.NewNoteRenderRepository(db),
+		repository.NewUserRepository(db),
+		NewWebSocketService(repository.NewConnectionRepository(db), noopGateway{}),
+		noopStorage{},
+		newTestValidator(),
+		newTestAuditService(t, db, auditStartID),
+		policy.NewNotePolicy(grantRepo),
+		policy.NewFolderPolicy(),
+	)
+}
+
+func newTestWriter(t *testing.T, db *gorm.DB) *entity.User {
+	t.Helper()
+
+	writer := &entity.User{
+		Username:    "writer",
+		Email:       "writer@example.com",
+		Permissions: entity.PermissionCreateNotes.Add(entity.PermissionEditNotes),
+		Active:      true,
+		CreatedAt:   utils.NowUTC(),
+		UpdatedAt:   utils.NowUTC(),
+	}
+	if err := repository.NewUserRepository(db).Save(writer); err != nil {
+		t.Fatalf("save writer: %v", err)
+	}
+	return writer
+}
+
+func TestFlowchartContentIsValidatedOnCreateAndUpdate(t *testing.T) {
+	db := newTestDB(t)
+	noteSvc := newTestNoteService(t, db, 13700)
+	owner := newTestWriter(t, db)
+
+	req := &contract.TextNoteRequest{
+		Name:       "Onboarding",
+		Content:    `{"nodes": [{"id": "start", "label": "Start"}], "edges": [{"id": "e1", "source": "start", "target": "done"}]}`,
+		NoteType:   string(entity.NoteTypeFlowchart),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"process"},
+	}
+
+	_, apierr := noteSvc.CreateTextNote(owner, req)
+	structured, ok := apierr.(*apierror.StructuredError)
+	if !ok || structured.Code() != 400 {
+		t.Fatalf("expected a structured error, got %#v", apierr)
+	}
+	want := map[string][]string{
+		"content.nodes[0].position": {"This field is required"},
+		"content.edges[0].target":   {"Value must be the id of a node"},
+	}
+	if !reflect.DeepEqual(structured.Errors, want) {
+		t.Fatalf("expected %#v, got %#v", want, structured.Errors)
+	}
+
+	req.Content = `{"nodes": [{"id": "start", "label": "Start", "position": {"x": 0, "y": 0}}], "edges": []}`
+	created, apierr := noteSvc.CreateTextNote(owner, req)
+	if apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+
+	broken := `{"nodes": "start"}`
+	_, apierr = noteSvc.UpdateNote(owner, created.ID, &contract.UpdateNoteRequest{Content: &broken}, nil)
+	if structured, ok = apierr.(*apierror.StructuredError); !ok || len(structured.Errors["content.nodes"]) != 1 {
+		t.Fatalf("expected the update to be rejected, got %#v", apierr)
+	}
+
+	markdown := "Not a chart, and that's fine"
+	if _, apierr = noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+		Name:       "Notes",
+		Content:    markdown,
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"process"},
+	}); apierr != nil {
+		t.Fatalf("expected markdown to be left alone, got %#v", apierr)
+	}
+}
+
+func TestRestoreNoteRevisionValidatesFlowchartContent(t *testing.T) {
+	db := newTestDB(t)
+	noteSvc := newTestNoteService(t, db, 16400)
+	owner := newTestWriter(t, db)
+
+	created, apierr := noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+		Name:       "Onboarding",
+		Content:    `{"nodes": [{"id": "start", "label": "Start", "position": {"x": 0, "y": 0}}], "edges": []}`,
+		NoteType:   string(entity.NoteTypeFlowchart),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"process"},
+	})
+	if apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+
+	// A revision saved before flowcharts were validated
+	if err := repository.NewNoteRevisionRepository(db).CreateWithDB(nil, &entity.NoteRevision{
+		NoteID:      created.ID,
+		Name:        "Onboarding",
+		Content:     `{"nodes": "start"}`,
+		NoteType:    entity.NoteTypeFlowchart,
+		Visibility:  entity.VisibilityPublic,
+		CreatedByID: owner.ID,
+		CreatedAt:   utils.NowUTC(),
+	}); err != nil {
+		t.Fatalf("create revision: %v", err)
+	}
+
+	revisions, apierr := noteSvc.GetNoteRevisions(owner, created.ID)
+	if apierr != nil {
+		t.Fatalf("get revisions returned api error: %#v", apierr)
+	}
+
+	_, apierr = noteSvc.RestoreNoteRevision(owner, created.ID, revisions[0].Revision)
+	if structured, ok := apierr.(*apierror.StructuredError); !ok || len(structured.Errors["content.nodes"]) != 1 {
+		t.Fatalf("expected the restore to be rejected, got %#v", apierr)
+	}
+
+	note, err := repository.NewNoteRepository(db).FindByID(created.ID)
+	if err != nil {
+		t.Fatalf("find note: %v", err)
+	}
+	if note.Version != created.Version {
+		t.Fatalf("expected the note to be left alone, got version %d", note.Version)
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils/apierror"
+	"simplenotes/cmd/internal/utils/notelinks"
+	"strconv"
+
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+type NoteLinkRepository interface {
+	FindAllBySourceID(sourceID int) ([]*entity.NoteLink, error)
+	ReplaceNoteLinksWithDB(db *gorm.DB, source *entity.Note, refs []notelinks.Ref) error
+	AdoptWithDB(db *gorm.DB, note *entity.Note) error
+	RenameTargetWithDB(db *gorm.DB, noteID int, name string, sourceIDs []int) error
+	DeleteByNoteIDWithDB(db *gorm.DB, noteID int) error
+}
+
+// syncNoteLinks parses the links of MARKDOWN notes again when their content changed,
+// and points the broken links naming the note to it. A nil 'before' means the
+// note was just created.
+func (n *NoteService) syncNoteLinks(tx *gorm.DB, before, note *entity.Note) error {
+	if note.NoteType == entity.NoteTypeMarkdown && (before == nil || before.Content != note.Content) {
+		if err := n.LinkRepo.ReplaceNoteLinksWithDB(tx, note, notelinks.Parse(note.Content)); err != nil {
+			return err
+		}
+	}
+
+	// Made public, the note can be seen by the owners of more broken links
+	if before != nil && before.Name == note.Name && before.Visibility == note.Visibility {
+		return nil
+	}
+	return n.LinkRepo.AdoptWithDB(tx, note)
+}
+
+// findRenamableNotes returns the notes with [[name]] links to the note that the
+// actor can update, trashed ones included, so a rename can rewrite them.
+func (n *NoteService) findRenamableNotes(actor *entity.User, before, note *entity.Note) ([]*entity.Note, apierror.ErrorResponse) {
+	if before.Name == note.Name {
+		return nil, nil
+	}
+
+	notes, err := n.NoteRepo.FindAllNamingWithDB(nil, note.ID)
+	if err != nil {
+		log.Errorf("failed to fetch notes linking to note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	var renamable []*entity.Note
+	for _, linking := range notes {
+		if n.NotePolicy.CanUpdate(linking, actor) == nil {
+			renamable = append(renamable, linking)
+		}
+	}
+	return renamable, nil
+}
+
+// rewriteNamingNotes follows a rename of the note in the [[name]] links of the
+// 'renamable' notes. Each note gets a revision and a NOTE_UPDATE audit event,
+// as if it was updated by hand. Links of the notes the actor cannot update are
+// left with the old name, and broken.
+func (n *NoteService) rewriteNamingNotes(tx *gorm.DB, actor *entity.User, before, note *entity.Note, renamable []*entity.Note) ([]noteChange, error) {
+	if before.Name == note.Name {
+		return nil, nil
+	}
+
+	var changes []noteChange
+	sourceIDs := make([]int, 0, len(renamable))
+	for _, linking := range renamable {
+		sourceIDs = append(sourceIDs, linking.ID)
+
+		fillLegacyContentHash(linking)
+		previous := *linking
+		linking.Content = notelinks.Rename(linking.Content, before.Name, note.Name)
+		if linking.Content == previous.Content {
+			continue
+		}
+
+		linking.ContentSize = len(linking.Content)
+		linking.ContentHash = contentHash([]byte(linking.Content))
+		linking.UpdatedAt = note.UpdatedAt
+
+		change, err := n.saveRewrittenNote(tx, actor, &previous, linking, nil)
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	// The links keep pointing to the note, only their text changed
+	return changes, n.LinkRepo.RenameTargetWithDB(tx, note.ID, note.Name, sourceIDs)
+}
+
+// GetNoteLinks lists the references found in the note, in the order they appear.
+func (n *NoteService) GetNoteLinks(actor *entity.User, noteId int) ([]*contract.NoteLinkResponse, apierror.ErrorResponse) {
+	note, apierr := n.fetchVisibleNote(actor, noteId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	links, err := n.LinkRepo.FindAllBySourceID(note.ID)
+	if err != nil {
+		log.Errorf("failed to fetch links of note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	var targetIDs []int
+	for _, link := range links {
+		if link.TargetID != nil {
+			targetIDs = append(targetIDs, *link.TargetID)
+		}
+	}
+
+	targets := make(map[int]*entity.Note)
+	if len(targetIDs) > 0 {
+		notes, err := n.NoteRepo.FindAllByIDs(targetIDs)
+		if err != nil {
+			log.Errorf("failed to fetch link targets of note %d: %v", note.ID, err)
+			return nil, apierror.InternalServerError
+		}
+
+		for _, target := range notes {
+			if n.NotePolicy.CanSee(target, actor) == nil {
+				targets[target.ID] = target
+			}
+		}
+	}
+
+	resp := make([]*contract.NoteLinkResponse, len(links))
+	for i, link := range links {
+		resp[i] = toNoteLinkResponse(link, targets)
+	}
+	return resp, nil
+}
+
+// GetNoteBacklinks lists the notes linking to the note that the actor can see,
+// last updated first.
+func (n *NoteService) GetNoteBacklinks(actor *entity.User, noteId int) ([]*contract.NoteResponse, apierror.ErrorResponse) {
+	note, apierr := n.fetchVisibleNote(actor, noteId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	canSeeHidden := actor.Permissions.HasEffective(entity.PermissionSeeHiddenNotes)
+	notes, err := n.NoteRepo.FindAllLinkingTo(note.ID, actor.ID, canSeeHidden)
+	if err != nil {
+		log.Errorf("failed to fetch backlinks of note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	resp := make([]*contract.NoteResponse, len(notes))
+	for i, linking := range notes {
+		resp[i] = toNoteResponse(linking, false)
+	}
+	return resp, nil
+}
+
+// toNoteLinkResponse describes the link, 'targets' holding the active notes
+// the caller can see.
+func toNoteLinkResponse(link *entity.NoteLink, targets map[int]*entity.Note) *contract.NoteLinkResponse {
+	resp := &contract.NoteLinkResponse{Text: "[[" + link.TargetName + "]]"}
+	if link.TargetName == "" {
+		resp.Text = "/notes/" + strconv.Itoa(*link.TargetID)
+	}
+
+	var target *entity.Note
+	if link.TargetID != nil {
+		target = targets[*link.TargetID]
+	}
+
+	if target == nil {
+		resp.Broken = true
+		return resp
+	}
+
+	resp.TargetID = &target.ID
+	resp.TargetName = target.Name
+	return resp
+}
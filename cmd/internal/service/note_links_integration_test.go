@@ -0,0 +1,199 @@
+package service
+
+import (
+	"fmt"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownLinksAreTrackedAndFollowRenames(t *testing.T) {
+	db := newTestDB(t)
+	noteSvc := newTestNoteService(t, db, 13500)
+	owner := newTestWriter(t, db)
+
+	reader := &entity.User{
+		Username:  "reader",
+		Email:     "reader@example.com",
+		Active:    true,
+		CreatedAt: utils.NowUTC(),
+		UpdatedAt: utils.NowUTC(),
+	}
+	if err := repository.NewUserRepository(db).Save(reader); err != nil {
+		t.Fatalf("save reader: %v", err)
+	}
+
+	createNote := func(name, content string, visibility entity.NoteVisibility) *contract.NoteResponse {
+		t.Helper()
+		note, apierr := noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+			Name:       name,
+			Content:    content,
+			NoteType:   string(entity.NoteTypeMarkdown),
+			Visibility: string(visibility),
+			Tags:       []string{"planning"},
+		})
+		if apierr != nil {
+			t.Fatalf("create note %q returned api error: %#v", name, apierr)
+		}
+		return note
+	}
+
+	roadmap := createNote("Roadmap", "Where we are going.", entity.VisibilityPublic)
+	secret := createNote("Secret", "Not for everyone.", entity.VisibilityPrivate)
+	plan := createNote("Plan", fmt.Sprintf(
+		"Follows the [[roadmap]] (see /notes/%d), keeps the [[Secret]], waits on [[Budget]] and /notes/9999.",
+		roadmap.ID,
+	), entity.VisibilityPublic)
+
+	links, apierr := noteSvc.GetNoteLinks(reader, plan.ID)
+	if apierr != nil {
+		t.Fatalf("get links returned api error: %#v", apierr)
+	}
+
+	want := []struct {
+		text   string
+		target int
+	}{
+		{"[[roadmap]]", roadmap.ID},
+		{fmt.Sprintf("/notes/%d", roadmap.ID), roadmap.ID},
+		{"[[Secret]]", 0}, // hidden from the reader
+		{"[[Budget]]", 0},
+		{"/notes/9999", 0},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("expected %d links, got %#v", len(want), links)
+	}
+	for i, link := range links {
+		if link.Text != want[i].text || link.Broken != (want[i].target == 0) {
+			t.Fatalf("unexpected link %d: %#v", i, link)
+		}
+		if want[i].target != 0 && (link.TargetID == nil || *link.TargetID != want[i].target || link.TargetName != "Roadmap") {
+			t.Fatalf("expected link %d to point to the roadmap, got %#v", i, link)
+		}
+	}
+
+	budget := createNote("Budget", "Numbers.", entity.VisibilityPublic)
+	links, apierr = noteSvc.GetNoteLinks(owner, plan.ID)
+	if apierr != nil {
+		t.Fatalf("get links returned api error: %#v", apierr)
+	}
+	if links[2].Broken || *links[2].TargetID != secret.ID || links[3].Broken || *links[3].TargetID != budget.ID {
+		t.Fatalf("expected the owner to see the secret and the new budget note, got %#v %#v", links[2], links[3])
+	}
+
+	backlinks, apierr := noteSvc.GetNoteBacklinks(reader, roadmap.ID)
+	if apierr != nil {
+		t.Fatalf("get backlinks returned api error: %#v", apierr)
+	}
+	if len(backlinks) != 1 || backlinks[0].ID != plan.ID {
+		t.Fatalf("expected the plan to link to the roadmap, got %#v", backlinks)
+	}
+
+	newName := "Launch Plan"
+	if _, apierr = noteSvc.UpdateNote(owner, roadmap.ID, &contract.UpdateNoteRequest{Name: &newName}, nil); apierr != nil {
+		t.Fatalf("rename returned api error: %#v", apierr)
+	}
+
+	rewritten, apierr := noteSvc.GetNoteByID(owner, plan.ID)
+	if apierr != nil {
+		t.Fatalf("get note returned api error: %#v", apierr)
+	}
+	if !strings.HasPrefix(rewritten.Content, "Follows the [[Launch Plan]] (see") || rewritten.Version != plan.Version+1 {
+		t.Fatalf("expected the link to follow the rename, got version %d: %q", rewritten.Version, rewritten.Content)
+	}
+
+	links, apierr = noteSvc.GetNoteLinks(owner, plan.ID)
+	if apierr != nil {
+		t.Fatalf("get links returned api error: %#v", apierr)
+	}
+	if links[0].Text != "[[Launch Plan]]" || links[0].Broken || *links[0].TargetID != roadmap.ID {
+		t.Fatalf("expected the renamed link to still point to the roadmap, got %#v", links[0])
+	}
+
+	events, err := repository.NewAuditRepository(db).List(&repository.AuditLogFilter{
+		Limit:      10,
+		ActionType: auditActionPtr(entity.AuditActionNoteUpdate),
+	})
+	if err != nil {
+		t.Fatalf("list audit logs: %v", err)
+	}
+	audited := make(map[string]bool)
+	for _, event := range events {
+		audited[event.SubjectID] = true
+	}
+	if len(events) != 2 || !audited[strconv.Itoa(roadmap.ID)] || !audited[strconv.Itoa(plan.ID)] {
+		t.Fatalf("expected the rename and the rewrite of the plan to be audited, got %#v", events)
+	}
+}
+
+func TestNoteLinksOnlyReachNotesTheirOwnerCanSee(t *testing.T) {
+	db := newTestDB(t)
+	noteSvc := newTestNoteService(t, db, 16100)
+	writer := newTestWriter(t, db)
+
+	other := &entity.User{
+		Username:    "other",
+		Email:       "other@example.com",
+		Permissions: entity.PermissionCreateNotes,
+		Active:      true,
+		CreatedAt:   utils.NowUTC(),
+		UpdatedAt:   utils.NowUTC(),
+	}
+	if err := repository.NewUserRepository(db).Save(other); err != nil {
+		t.Fatalf("save other: %v", err)
+	}
+
+	createNote := func(author *entity.User, name, content string, visibility entity.NoteVisibility) *contract.NoteResponse {
+		t.Helper()
+		note, apierr := noteSvc.CreateTextNote(author, &contract.TextNoteRequest{
+			Name:       name,
+			Content:    content,
+			NoteType:   string(entity.NoteTypeMarkdown),
+			Visibility: string(visibility),
+			Tags:       []string{},
+		})
+		if apierr != nil {
+			t.Fatalf("create note %q returned api error: %#v", name, apierr)
+		}
+		return note
+	}
+
+	roadmap := createNote(writer, "Roadmap", "Where we are going.", entity.VisibilityPublic)
+	createNote(writer, "Secret", "Not for everyone.", entity.VisibilityPrivate)
+	diary := createNote(other, "Diary", "Read the [[Roadmap]], not the [[Secret]] or the [[Vault]].", entity.VisibilityPrivate)
+	createNote(writer, "Vault", "Locked.", entity.VisibilityPrivate)
+
+	// The writer cannot update the private diary, so it keeps the old name
+	newName := "Launch Plan"
+	if _, apierr := noteSvc.UpdateNote(writer, roadmap.ID, &contract.UpdateNoteRequest{Name: &newName}, nil); apierr != nil {
+		t.Fatalf("rename returned api error: %#v", apierr)
+	}
+
+	unchanged, apierr := noteSvc.GetNoteByID(other, diary.ID)
+	if apierr != nil {
+		t.Fatalf("get note returned api error: %#v", apierr)
+	}
+	if unchanged.Content != diary.Content || unchanged.Version != diary.Version {
+		t.Fatalf("expected the diary to be left as it was, got version %d: %q", unchanged.Version, unchanged.Content)
+	}
+
+	links, err := repository.NewNoteLinkRepository(db).FindAllBySourceID(diary.ID)
+	if err != nil {
+		t.Fatalf("find links: %v", err)
+	}
+	if len(links) != 3 {
+		t.Fatalf("expected 3 links, got %d", len(links))
+	}
+	for _, link := range links {
+		if link.TargetID != nil {
+			t.Fatalf("expected [[%s]] to be broken, got target %d", link.TargetName, *link.TargetID)
+		}
+	}
+	if links[0].TargetName != "Roadmap" {
+		t.Fatalf("expected the broken link to keep the old name, got %q", links[0].TargetName)
+	}
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strings"
+
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	defaultNoteListLimit = 50
+	maxNoteListLimit     = 100
+)
+
+// noteCursor is the JSON payload behind the opaque cursors handed to clients.
+// It carries the sort field, so a cursor cannot be reused with another sort.
+type noteCursor struct {
+	Sort  repository.NoteSortField `json:"s"`
+	Value any                      `json:"v"`
+	ID    int                      `json:"id"`
+}
+
+func (n *NoteService) GetAllNotes(actor *entity.User, req *contract.NoteListRequest) (*contract.NoteListResponse, apierror.ErrorResponse) {
+	filter, apierr := toNoteListFilter(req)
+	if apierr != nil {
+		return nil, apierr
+	}
+	filter.ViewerID = actor.ID
+	filter.WithPrivate = actor.Permissions.HasEffective(entity.PermissionSeeHiddenNotes)
+	return n.listNotePage(filter)
+}
+
+// listNotePage fetches a page of notes and builds the cursors around it.
+func (n *NoteService) listNotePage(filter *repository.NoteListFilter) (*contract.NoteListResponse, apierror.ErrorResponse) {
+	// One extra row tells whether there is another page in that direction
+	pageSize := filter.Limit
+	filter.Limit++
+
+	notes, err := n.NoteRepo.List(filter)
+	if err != nil {
+		log.Errorf("failed to fetch notes: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	hasMore := len(notes) > pageSize
+	if hasMore {
+		if filter.Before != nil {
+			notes = notes[1:]
+		} else {
+			notes = notes[:pageSize]
+		}
+	}
+
+	resp := &contract.NoteListResponse{
+		Notes: make([]*contract.NoteResponse, len(notes)),
+	}
+	for i, note := range notes {
+		resp.Notes[i] = toNoteResponse(note, false)
+	}
+
+	if len(notes) == 0 {
+		return resp, nil
+	}
+
+	hasNext := hasMore || filter.Before != nil
+	hasPrev := filter.After != nil || (filter.Before != nil && hasMore)
+	if hasNext {
+		resp.NextCursor = encodeNoteCursor(filter.Sort, notes[len(notes)-1])
+	}
+	if hasPrev {
+		resp.PrevCursor = encodeNoteCursor(filter.Sort, notes[0])
+	}
+	return resp, nil
+}
+
+func toNoteListFilter(req *contract.NoteListRequest) (*repository.NoteListFilter, apierror.ErrorResponse) {
+	filter := &repository.NoteListFilter{
+		Limit:      defaultNoteListLimit,
+		Sort:       repository.NoteSortUpdatedAt,
+		Descending: true,
+	}
+
+	if req == nil {
+		return filter, nil
+	}
+
+	if req.Limit != 0 {
+		if req.Limit < 1 || req.Limit > maxNoteListLimit {
+			return nil, apierror.NewSimple(400, "Limit must be between 1 and %d", maxNoteListLimit)
+		}
+		filter.Limit = req.Limit
+	}
+
+	if req.Sort != nil {
+		sort := repository.NoteSortField(strings.TrimSpace(*req.Sort))
+		if !isNoteSortFieldValid(sort) {
+			return nil, apierror.NewSimple(400, "Sort must be one of the following: updated_at created_at name")
+		}
+		filter.Sort = sort
+	}
+
+	if req.Order != nil {
+		switch strings.ToLower(strings.TrimSpace(*req.Order)) {
+		case "asc":
+			filter.Descending = false
+		case "desc":
+			filter.Descending = true
+		default:
+			return nil, apierror.NewSimple(400, "Order must be one of the following: asc desc")
+		}
+	}
+
+	if req.Before != nil && req.After != nil {
+		return nil, apierror.NewSimple(400, "Parameters 'before' and 'after' cannot be used together")
+	}
+
+	var apierr apierror.ErrorResponse
+	if req.Before != nil {
+		if filter.Before, apierr = decodeNoteCursor(filter.Sort, *req.Before); apierr != nil {
+			return nil, apierr
+		}
+	}
+	if req.After != nil {
+		if filter.After, apierr = decodeNoteCursor(filter.Sort, *req.After); apierr != nil {
+			return nil, apierr
+		}
+	}
+
+	for _, tag := range req.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" {
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+
+	if req.TagsMode != nil {
+		switch strings.ToLower(strings.TrimSpace(*req.TagsMode)) {
+		case "any":
+			filter.MatchAllTags = false
+		case "all":
+			filter.MatchAllTags = true
+		default:
+			return nil, apierror.NewSimple(400, "Tags mode must be one of the following: any all")
+		}
+	}
+
+	if req.NoteType != nil {
+		noteType := entity.NoteType(strings.TrimSpace(*req.NoteType))
+		if !isNoteTypeValid(noteType) {
+			return nil, apierror.NewSimple(400, "Invalid note type")
+		}
+		filter.NoteType = &noteType
+	}
+
+	if req.Visibility != nil {
+		visibility := entity.NoteVisibility(strings.TrimSpace(*req.Visibility))
+		if visibility != entity.VisibilityPublic && visibility != entity.VisibilityPrivate {
+			return nil, apierror.NewSimple(400, "Invalid note visibility")
+		}
+		filter.Visibility = &visibility
+	}
+
+	filter.CreatedByID = req.CreatedByID
+	filter.FolderID = req.FolderID
+	filter.CreatedAfter = req.CreatedAfter
+	filter.CreatedBefore = req.CreatedBefore
+	filter.UpdatedAfter = req.UpdatedAfter
+	filter.UpdatedBefore = req.UpdatedBefore
+	return filter, nil
+}
+
+func isNoteSortFieldValid(sort repository.NoteSortField) bool {
+	switch sort {
+	case repository.NoteSortUpdatedAt, repository.NoteSortCreatedAt, repository.NoteSortName:
+		return true
+	default:
+		return false
+	}
+}
+
+func isNoteTypeValid(noteType entity.NoteType) bool {
+	switch noteType {
+	case entity.NoteTypeReference, entity.NoteTypeMarkdown, entity.NoteTypeFlowchart:
+		return true
+	default:
+		return false
+	}
+}
+
+func encodeNoteCursor(sort repository.NoteSortField, note *entity.Note) *string {
+	cursor := &noteCursor{Sort: sort, ID: note.ID}
+	switch sort {
+	case repository.NoteSortCreatedAt:
+		cursor.Value = note.CreatedAt
+	case repository.NoteSortName:
+		cursor.Value = note.Name
+	case repository.NoteSortDeletedAt:
+		cursor.Value = note.DeletedAt
+	default:
+		cursor.Value = note.UpdatedAt
+	}
+
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		log.Errorf("failed to encode note cursor: %v", err)
+		return nil
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return &encoded
+}
+
+func decodeNoteCursor(sort repository.NoteSortField, raw string) (*repository.NoteCursor, apierror.ErrorResponse) {
+	invalid := apierror.NewSimple(400, "Invalid cursor")
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, invalid
+	}
+
+	var cursor noteCursor
+	if err = json.Unmarshal(payload, &cursor); err != nil || cursor.Sort != sort {
+		return nil, invalid
+	}
+
+	switch value := cursor.Value.(type) {
+	case float64:
+		if sort == repository.NoteSortName {
+			return nil, invalid
+		}
+		return &repository.NoteCursor{Value: int64(value), ID: cursor.ID}, nil
+	case string:
+		if sort != repository.NoteSortName {
+			return nil, invalid
+		}
+		return &repository.NoteCursor{Value: value, ID: cursor.ID}, nil
+	default:
+		return nil, invalid
+	}
+}
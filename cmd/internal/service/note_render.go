@@ -0,0 +1,85 @@
+package service
+
+import (
+	"encoding/json"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"simplenotes/cmd/internal/utils/markdown"
+
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+type NoteRenderRepository interface {
+	FindByNoteID(noteID int) (*entity.NoteRender, error)
+	Save(render *entity.NoteRender) error
+	DeleteByNoteIDWithDB(db *gorm.DB, noteID int) error
+}
+
+// RenderNote returns the sanitized HTML of a MARKDOWN note, along with its table
+// of contents when 'withTOC' is set. Each version of the note is rendered once.
+func (n *NoteService) RenderNote(actor *entity.User, noteId int, withTOC bool) (*contract.NoteRenderResponse, apierror.ErrorResponse) {
+	note, apierr := n.fetchVisibleNote(actor, noteId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	if note.NoteType != entity.NoteTypeMarkdown {
+		return nil, apierror.NoteNotMarkdownError
+	}
+
+	render, err := n.RenderRepo.FindByNoteID(note.ID)
+	if err != nil {
+		log.Errorf("failed to fetch render of note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	if render == nil || render.Version != note.Version || render.Renderer != markdown.Version {
+		if render, err = renderNote(note); err != nil {
+			log.Errorf("failed to render note %d: %v", note.ID, err)
+			return nil, apierror.InternalServerError
+		}
+
+		// A failed cache write only costs a render on the next request
+		if err = n.RenderRepo.Save(render); err != nil {
+			log.Warnf("failed to cache render of note %d: %v", note.ID, err)
+		}
+	}
+
+	resp := &contract.NoteRenderResponse{
+		NoteID:  note.ID,
+		Version: render.Version,
+		HTML:    render.HTML,
+	}
+
+	if withTOC {
+		if err = json.Unmarshal([]byte(render.TOC), &resp.TOC); err != nil {
+			log.Errorf("failed to decode table of contents of note %d: %v", note.ID, err)
+			return nil, apierror.InternalServerError
+		}
+	}
+	return resp, nil
+}
+
+func renderNote(note *entity.Note) (*entity.NoteRender, error) {
+	html, headings, err := markdown.Render(note.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	toc, err := json.Marshal(headings)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.NoteRender{
+		NoteID:    note.ID,
+		Version:   note.Version,
+		Renderer:  markdown.Version,
+		HTML:      html,
+		TOC:       string(toc),
+		CreatedAt: utils.NowUTC(),
+	}, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownNotesAreRenderedSanitizedOncePerVersion(t *testing.T) {
+	db := newTestDB(t)
+	noteSvc := newTestNoteService(t, db, 13600)
+	owner := newTestWriter(t, db)
+
+	note, apierr := noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+		Name:       "Runbook",
+		Content:    "# Deploy\n\n<script>alert('xss')</script>\n\n## Rollback\n\nRun `make rollback`.",
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"ops"},
+	})
+	if apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+
+	render, apierr := noteSvc.RenderNote(owner, note.ID, true)
+	if apierr != nil {
+		t.Fatalf("render returned api error: %#v", apierr)
+	}
+	if strings.Contains(render.HTML, "<script") || !strings.Contains(render.HTML, "<code>make rollback</code>") {
+		t.Fatalf("expected sanitized html, got %q", render.HTML)
+	}
+	if render.Version != note.Version || len(render.TOC) != 2 || render.TOC[1].Text != "Rollback" || render.TOC[1].ID != "rollback" {
+		t.Fatalf("unexpected render: %#v", render)
+	}
+
+	// The cached render is served as long as the note keeps its version
+	if err := db.Model(&entity.NoteRender{}).Where("note_id = ?", note.ID).Update("html", "<p>cached</p>").Error; err != nil {
+		t.Fatalf("update cached render: %v", err)
+	}
+
+	cached, apierr := noteSvc.RenderNote(owner, note.ID, false)
+	if apierr != nil {
+		t.Fatalf("render returned api error: %#v", apierr)
+	}
+	if cached.HTML != "<p>cached</p>" || cached.TOC != nil {
+		t.Fatalf("expected the cached render without headings, got %#v", cached)
+	}
+
+	content := "Nothing to see"
+	if _, apierr = noteSvc.UpdateNote(owner, note.ID, &contract.UpdateNoteRequest{Content: &content}, nil); apierr != nil {
+		t.Fatalf("update note returned api error: %#v", apierr)
+	}
+
+	fresh, apierr := noteSvc.RenderNote(owner, note.ID, false)
+	if apierr != nil {
+		t.Fatalf("render returned api error: %#v", apierr)
+	}
+	if fresh.HTML != "<p>Nothing to see</p>\n" || fresh.Version != note.Version+1 {
+		t.Fatalf("expected the new version to be rendered, got %#v", fresh)
+	}
+
+	flowchart, apierr := noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+		Name:       "Pipeline",
+		Content:    `{"nodes": []}`,
+		NoteType:   string(entity.NoteTypeFlowchart),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"ops"},
+	})
+	if apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+	if _, apierr = noteSvc.RenderNote(owner, flowchart.ID, false); apierr != apierror.NoteNotMarkdownError {
+		t.Fatalf("expected flowcharts not to be rendered, got %#v", apierr)
+	}
+}
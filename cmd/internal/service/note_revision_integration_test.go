@@ -0,0 +1,159 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/policy"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils"
+)
+
+func TestRestoreNoteRevisionCreatesRevisionAndAuditEvent(t *testing.T) {
+	db := newTestDB(t)
+
+	auditRepo := repository.NewAuditRepository(db)
+	auditSvc := newTestAuditService(t, db, 5000)
+	userRepo := repository.NewUserRepository(db)
+	noteRepo := repository.NewNoteRepository(db)
+	revisionRepo := repository.NewNoteRevisionRepository(db)
+	wsSvc := NewWebSocketService(repository.NewConnectionRepository(db), noopGateway{})
+	grantRepo := repository.NewNoteGrantRepository(db)
+	noteSvc := NewNoteService(
+		db, noteRepo, revisionRepo, repository.NewNoteTextRepository(db), repository.NewAttachmentRepository(db),
+		repository.NewNoteAttachmentRepository(db), grantRepo, repository.NewFolderRepository(db), repository.NewTagRepository(db),
+		repository.NewNoteLinkRepository(db), repository.NewNoteRenderRepository(db), userRepo, wsSvc, noopStorage{},
+		newTestValidator(), auditSvc, policy.NewNotePolicy(grantRepo), policy.NewFolderPolicy(),
+	)
+
+	actor := &entity.User{
+		Username:    "writer",
+		Email:       "writer@example.com",
+		Permissions: entity.PermissionCreateNotes.Add(entity.PermissionEditNotes),
+		Active:      true,
+		CreatedAt:   utils.NowUTC(),
+		UpdatedAt:   utils.NowUTC(),
+	}
+	if err := userRepo.Save(actor); err != nil {
+		t.Fatalf("save actor: %v", err)
+	}
+
+	created, apierr := noteSvc.CreateTextNote(actor, &contract.TextNoteRequest{
+		Name:       "Meeting",
+		Content:    "first line\nsecond line\n",
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"weekly"},
+	})
+	if apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+
+	newName := "Weekly Meeting"
+	if _, apierr = noteSvc.UpdateNote(actor, created.ID, &contract.UpdateNoteRequest{Name: &newName}, nil); apierr != nil {
+		t.Fatalf("update note returned api error: %#v", apierr)
+	}
+
+	restored, apierr := noteSvc.RestoreNoteRevision(actor, created.ID, 1)
+	if apierr != nil {
+		t.Fatalf("restore revision returned api error: %#v", apierr)
+	}
+	if restored.Name != "Meeting" {
+		t.Fatalf("expected restored name, got %s", restored.Name)
+	}
+
+	revisions, apierr := noteSvc.GetNoteRevisions(actor, created.ID)
+	if apierr != nil {
+		t.Fatalf("get revisions returned api error: %#v", apierr)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("expected 3 revisions, got %d", len(revisions))
+	}
+	if revisions[0].Revision != 3 || revisions[0].Content != "" {
+		t.Fatalf("expected newest revision first without content, got %#v", revisions[0])
+	}
+
+	diff, apierr := noteSvc.DiffNoteRevisions(actor, created.ID, 1, 3)
+	if apierr != nil {
+		t.Fatalf("diff revisions returned api error: %#v", apierr)
+	}
+	if diff.Diff != "" {
+		t.Fatalf("expected empty diff between equal contents, got %q", diff.Diff)
+	}
+
+	events, err := auditRepo.List(&repository.AuditLogFilter{
+		Limit:      10,
+		ActionType: auditActionPtr(entity.AuditActionNoteRevisionRestore),
+	})
+	if err != nil {
+		t.Fatalf("list audit logs: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 revision restore audit event, got %d", len(events))
+	}
+
+	for _, change := range events[0].Changes {
+		if change.NewValue != nil && strings.Contains(*change.NewValue, "first line") {
+			t.Fatal("audit changes must not contain raw note content")
+		}
+	}
+}
+
+func TestNoteRevisionsKeepTheirOwnVisibility(t *testing.T) {
+	db := newTestDB(t)
+
+	noteSvc := newTestNoteService(t, db, 16500)
+	owner := newTestWriter(t, db)
+
+	reader := &entity.User{
+		Username:    "reader",
+		Email:       "reader@example.com",
+		Permissions: entity.PermissionCreateNotes,
+		Active:      true,
+		CreatedAt:   utils.NowUTC(),
+		UpdatedAt:   utils.NowUTC(),
+	}
+	if err := repository.NewUserRepository(db).Save(reader); err != nil {
+		t.Fatalf("save reader: %v", err)
+	}
+
+	created, apierr := noteSvc.CreateTextNote(owner, &contract.TextNoteRequest{
+		Name:       "Offer",
+		Content:    "salary: 100k",
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: string(entity.VisibilityPrivate),
+		Tags:       []string{"hr"},
+	})
+	if apierr != nil {
+		t.Fatalf("create note returned api error: %#v", apierr)
+	}
+
+	content, public := "salary: redacted", string(entity.VisibilityPublic)
+	if _, apierr = noteSvc.UpdateNote(owner, created.ID, &contract.UpdateNoteRequest{Content: &content, Visibility: &public}, nil); apierr != nil {
+		t.Fatalf("update note returned api error: %#v", apierr)
+	}
+
+	revisions, apierr := noteSvc.GetNoteRevisions(reader, created.ID)
+	if apierr != nil {
+		t.Fatalf("get revisions returned api error: %#v", apierr)
+	}
+	if len(revisions) != 1 || revisions[0].Revision != 2 {
+		t.Fatalf("expected only the public revision, got %#v", revisions)
+	}
+
+	if _, apierr = noteSvc.GetNoteRevision(reader, created.ID, 1); apierr == nil || apierr.Code() != 404 {
+		t.Fatalf("expected the private revision to be hidden, got %#v", apierr)
+	}
+	if _, apierr = noteSvc.DiffNoteRevisions(reader, created.ID, 1, 2); apierr == nil || apierr.Code() != 404 {
+		t.Fatalf("expected a diff against the private revision to be hidden, got %#v", apierr)
+	}
+
+	if revisions, apierr = noteSvc.GetNoteRevisions(owner, created.ID); apierr != nil || len(revisions) != 2 {
+		t.Fatalf("expected the owner to see both revisions, got %d (%#v)", len(revisions), apierr)
+	}
+	if rev, apierr := noteSvc.GetNoteRevision(owner, created.ID, 1); apierr != nil || rev.Content != "salary: 100k" {
+		t.Fatalf("expected the owner to read the private revision, got %#v", apierr)
+	}
+}
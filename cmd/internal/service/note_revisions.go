@@ -0,0 +1,299 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"simplenotes/cmd/internal/utils/diff"
+	"slices"
+	"strconv"
+
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+type NoteRevisionRepository interface {
+	FindAllByNoteID(noteID int) ([]*entity.NoteRevision, error)
+	FindByRevision(noteID, revision int) (*entity.NoteRevision, error)
+	ExistsByNoteIDWithDB(db *gorm.DB, noteID int) (bool, error)
+	CreateWithDB(db *gorm.DB, rev *entity.NoteRevision) error
+	DeleteByNoteIDWithDB(db *gorm.DB, noteID int) error
+}
+
+func (n *NoteService) GetNoteRevisions(actor *entity.User, noteId int) ([]*contract.NoteRevisionResponse, apierror.ErrorResponse) {
+	note, apierr := n.fetchVisibleNote(actor, noteId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	revisions, err := n.RevisionRepo.FindAllByNoteID(note.ID)
+	if err != nil {
+		log.Errorf("failed to fetch revisions of note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	// Every private revision gets the same answer, so the policy is asked once
+	var privateErr apierror.ErrorResponse
+	if slices.ContainsFunc(revisions, isPrivateRevision) {
+		privateErr = n.canSeeRevision(actor, note, &entity.NoteRevision{Visibility: entity.VisibilityPrivate})
+		if privateErr != nil && privateErr.Code() != http.StatusNotFound {
+			return nil, privateErr
+		}
+	}
+
+	resp := make([]*contract.NoteRevisionResponse, 0, len(revisions))
+	for _, rev := range revisions {
+		if privateErr != nil && isPrivateRevision(rev) {
+			continue
+		}
+		resp = append(resp, toNoteRevisionResponse(rev, false))
+	}
+	return resp, nil
+}
+
+func (n *NoteService) GetNoteRevision(actor *entity.User, noteId, revision int) (*contract.NoteRevisionResponse, apierror.ErrorResponse) {
+	note, apierr := n.fetchVisibleNote(actor, noteId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	rev, apierr := n.fetchRevision(note.ID, revision)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	if apierr = n.canSeeRevision(actor, note, rev); apierr != nil {
+		return nil, apierr
+	}
+	return toNoteRevisionResponse(rev, true), nil
+}
+
+func (n *NoteService) DiffNoteRevisions(actor *entity.User, noteId, from, to int) (*contract.NoteRevisionDiffResponse, apierror.ErrorResponse) {
+	note, apierr := n.fetchVisibleNote(actor, noteId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	fromRev, apierr := n.fetchRevision(note.ID, from)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	toRev, apierr := n.fetchRevision(note.ID, to)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	for _, rev := range []*entity.NoteRevision{fromRev, toRev} {
+		if apierr = n.canSeeRevision(actor, note, rev); apierr != nil {
+			return nil, apierr
+		}
+	}
+
+	return &contract.NoteRevisionDiffResponse{
+		NoteID: note.ID,
+		From:   fromRev.Revision,
+		To:     toRev.Revision,
+		Diff: diff.Unified(
+			fmt.Sprintf("revision %d", fromRev.Revision),
+			fmt.Sprintf("revision %d", toRev.Revision),
+			fromRev.Content,
+			toRev.Content,
+			diff.DefaultContext,
+		),
+	}, nil
+}
+
+func (n *NoteService) RestoreNoteRevision(actor *entity.User, noteId, revision int) (*contract.NoteResponse, apierror.ErrorResponse) {
+	note, err := n.NoteRepo.FindByID(noteId)
+	if err != nil {
+		log.Errorf("failed to fetch note: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	apierr := n.NotePolicy.CanUpdate(note, actor)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	rev, apierr := n.fetchRevision(note.ID, revision)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	fillLegacyContentHash(note)
+	before := *note
+	note.Name = rev.Name
+	note.Tags = rev.Tags
+	note.Visibility = rev.Visibility
+
+	// The note may have been moved into a private folder since that revision
+	private, apierr := n.isFolderPrivate(note.FolderID)
+	if apierr != nil {
+		return nil, apierr
+	}
+	if private {
+		note.Visibility = entity.VisibilityPrivate
+	}
+
+	// Attachments are not versioned, the S3 object of an older revision
+	// may not exist anymore. REFERENCE notes only get their metadata back.
+	if note.NoteType != entity.NoteTypeReference {
+		// Revisions saved before content was validated may not be valid anymore
+		if apierr := checkNoteContent(note.NoteType, rev.Content); apierr != nil {
+			return nil, apierr
+		}
+		note.Content = rev.Content
+		note.ContentSize = rev.ContentSize
+		note.ContentHash = contentHash([]byte(rev.Content))
+	}
+
+	note.UpdatedAt = utils.NowUTC()
+	changes := buildNoteUpdateAuditChanges(&before, note)
+	changes = append(changes, newAuditCreateValue("revision", entity.AuditValueTypeInt, strconv.Itoa(rev.Revision)))
+
+	err = n.DB.Transaction(func(tx *gorm.DB) error {
+		if err := n.NoteRepo.SaveWithDB(tx, note); err != nil {
+			return err
+		}
+		if err := n.syncNoteTags(tx, &before, note); err != nil {
+			return err
+		}
+		if err := n.syncNoteLinks(tx, &before, note); err != nil {
+			return err
+		}
+		if err := n.recordNoteRevision(tx, &before, note, actor.ID); err != nil {
+			return err
+		}
+		return n.Audit.Record(tx, &entity.AuditLogEvent{
+			ActorUserID: &actor.ID,
+			ActionType:  entity.AuditActionNoteRevisionRestore,
+			SubjectType: entity.AuditSubjectNote,
+			SubjectID:   strconv.Itoa(note.ID),
+			Source:      entity.AuditSourceHTTPAPI,
+			Changes:     changes,
+		})
+	})
+	if errors.Is(err, repository.ErrStaleVersion) {
+		return nil, n.notePreconditionFailed(note.ID)
+	}
+
+	if err != nil {
+		log.Errorf("failed to restore revision %d of note %d: %v", revision, note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	go n.dispatchNoteUpdateEvent(&before, note, toNoteResponse(note, false))
+	return toNoteResponse(note, true), nil
+}
+
+// fetchVisibleNote finds the note and makes sure the actor is allowed to see it.
+func (n *NoteService) fetchVisibleNote(actor *entity.User, noteId int) (*entity.Note, apierror.ErrorResponse) {
+	note, err := n.NoteRepo.FindByID(noteId)
+	if err != nil {
+		log.Errorf("failed to fetch note: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	if apierr := n.NotePolicy.CanSee(note, actor); apierr != nil {
+		return nil, apierr
+	}
+	return note, nil
+}
+
+// canSeeRevision checks the revision against the visibility it was saved with,
+// so making a note public does not expose what it held while private.
+func (n *NoteService) canSeeRevision(actor *entity.User, note *entity.Note, rev *entity.NoteRevision) apierror.ErrorResponse {
+	if !isPrivateRevision(rev) {
+		return nil
+	}
+
+	snapshot := *note
+	snapshot.Visibility = rev.Visibility
+	return n.NotePolicy.CanSee(&snapshot, actor)
+}
+
+func isPrivateRevision(rev *entity.NoteRevision) bool {
+	return rev.Visibility == entity.VisibilityPrivate
+}
+
+func (n *NoteService) fetchRevision(noteID, revision int) (*entity.NoteRevision, apierror.ErrorResponse) {
+	rev, err := n.RevisionRepo.FindByRevision(noteID, revision)
+	if err != nil {
+		log.Errorf("failed to fetch revision %d of note %d: %v", revision, noteID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	if rev == nil {
+		return nil, apierror.NotFoundError
+	}
+	return rev, nil
+}
+
+// recordNoteRevision snapshots the current state of the note.
+//
+// Notes created before revisions existed have no history at all, so their
+// previous state ('before') is stored first as a baseline revision.
+// The 'before' argument may be nil for newly created notes.
+func (n *NoteService) recordNoteRevision(tx *gorm.DB, before, after *entity.Note, authorID int) error {
+	if before != nil {
+		exists, err := n.RevisionRepo.ExistsByNoteIDWithDB(tx, before.ID)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			baseline := newNoteRevision(before, before.CreatedByID)
+			baseline.CreatedAt = before.UpdatedAt
+			if err := n.RevisionRepo.CreateWithDB(tx, baseline); err != nil {
+				return err
+			}
+		}
+	}
+	return n.RevisionRepo.CreateWithDB(tx, newNoteRevision(after, authorID))
+}
+
+// isRevisionChanged tells if the note differs from 'before' in what revisions hold.
+func isRevisionChanged(before, after *entity.Note) bool {
+	return before.Name != after.Name || before.Content != after.Content || before.Tags != after.Tags ||
+		before.NoteType != after.NoteType || before.Visibility != after.Visibility
+}
+
+func newNoteRevision(note *entity.Note, authorID int) *entity.NoteRevision {
+	return &entity.NoteRevision{
+		NoteID:      note.ID,
+		Name:        note.Name,
+		Content:     note.Content,
+		Tags:        note.Tags,
+		NoteType:    note.NoteType,
+		ContentSize: note.ContentSize,
+		Visibility:  note.Visibility,
+		CreatedByID: authorID,
+		CreatedAt:   note.UpdatedAt,
+	}
+}
+
+func toNoteRevisionResponse(rev *entity.NoteRevision, withContent bool) *contract.NoteRevisionResponse {
+	var content string
+	if withContent {
+		content = rev.Content
+	}
+
+	return &contract.NoteRevisionResponse{
+		NoteID:      rev.NoteID,
+		Revision:    rev.Revision,
+		Name:        rev.Name,
+		Content:     content,
+		Tags:        toTagsArray(rev.Tags),
+		Visibility:  string(rev.Visibility),
+		NoteType:    string(rev.NoteType),
+		ContentSize: rev.ContentSize,
+		CreatedByID: rev.CreatedByID,
+		CreatedAt:   utils.FormatEpoch(rev.CreatedAt),
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"html"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strings"
+	"unicode"
+
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	defaultNoteSearchLimit = 20
+	maxNoteSearchLimit     = 50
+	maxNoteSearchTerms     = 10
+)
+
+var highlightReplacer = strings.NewReplacer(
+	repository.SearchMatchStart, "<mark>",
+	repository.SearchMatchEnd, "</mark>",
+)
+
+func (n *NoteService) SearchNotes(actor *entity.User, req *contract.NoteSearchRequest) (*contract.NoteSearchResponse, apierror.ErrorResponse) {
+	limit := defaultNoteSearchLimit
+	if req.Limit != 0 {
+		if req.Limit < 1 || req.Limit > maxNoteSearchLimit {
+			return nil, apierror.NewSimple(400, "Limit must be between 1 and %d", maxNoteSearchLimit)
+		}
+		limit = req.Limit
+	}
+
+	match := toFTSQuery(req.Query)
+	if match == "" {
+		return nil, apierror.NewSimple(400, "Search query must contain at least one letter or number")
+	}
+
+	canSeeHidden := actor.Permissions.HasEffective(entity.PermissionSeeHiddenNotes)
+	hits, err := n.NoteRepo.Search(match, actor.ID, canSeeHidden, limit)
+	if err != nil {
+		log.Errorf("failed to search notes: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	resp := &contract.NoteSearchResponse{
+		Results: make([]*contract.NoteSearchResult, 0, len(hits)),
+	}
+
+	for _, hit := range hits {
+		if n.NotePolicy.CanSee(&hit.Note, actor) != nil {
+			continue
+		}
+
+		resp.Results = append(resp.Results, &contract.NoteSearchResult{
+			NoteResponse:  toNoteResponse(&hit.Note, false),
+			NameHighlight: renderSearchHighlight(hit.NameHighlight),
+			Snippet:       renderSearchHighlight(hit.Snippet),
+			Score:         -hit.Score, // bm25 scores are negative, lower is better
+		})
+	}
+	return resp, nil
+}
+
+// toFTSQuery turns free user input into a safe FTS5 expression.
+// Every word becomes a quoted prefix query, and all of them must match.
+func toFTSQuery(raw string) string {
+	terms := strings.FieldsFunc(raw, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	if len(terms) > maxNoteSearchTerms {
+		terms = terms[:maxNoteSearchTerms]
+	}
+
+	for i, term := range terms {
+		terms[i] = `"` + term + `"*`
+	}
+	return strings.Join(terms, " ")
+}
+
+// renderSearchHighlight escapes the indexed text before turning the match
+// delimiters into <mark> tags, so note content can never inject markup.
+func renderSearchHighlight(fragment string) string {
+	return highlightReplacer.Replace(html.EscapeString(fragment))
+}
@@ -1,23 +1,29 @@
 package service
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/labstack/gommon/log"
+	"hash"
 	"io"
-	"mime/multipart"
+	"net/http"
 	"path/filepath"
 	"simplenotes/cmd/internal/contract"
 	"simplenotes/cmd/internal/domain/entity"
 	"simplenotes/cmd/internal/domain/events"
 	"simplenotes/cmd/internal/domain/policy"
-	"simplenotes/cmd/internal/infrastructure/aws/storage"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/infrastructure/storage"
 	"simplenotes/cmd/internal/utils"
 	"simplenotes/cmd/internal/utils/apierror"
+	"simplenotes/cmd/internal/utils/flowchart"
 	"strconv"
 	"strings"
 
@@ -26,61 +32,88 @@ import (
 
 type NoteRepository interface {
 	FindAll(withPrivate bool) ([]*entity.Note, error)
+	List(filter *repository.NoteListFilter) ([]*entity.Note, error)
 	FindByID(id int) (*entity.Note, error)
+	FindByName(createdByID int, folderID *int, name string) (*entity.Note, error)
+	FindAllByAttachment(filename string) ([]*entity.Note, error)
+	FindTrashedByID(id int) (*entity.Note, error)
+	FindTrashedBefore(cutoff int64, limit int) ([]*entity.Note, error)
+	FindAllByFolderIDsWithDB(db *gorm.DB, folderIDs []int) ([]*entity.Note, error)
+	FindAllByTagIDWithDB(db *gorm.DB, tagID int) ([]*entity.Note, error)
+	FindAllByIDs(ids []int) ([]*entity.Note, error)
+	FindAllLinkingTo(targetID, viewerID int, withPrivate bool) ([]*entity.Note, error)
+	FindAllNamingWithDB(db *gorm.DB, targetID int) ([]*entity.Note, error)
+	Search(match string, viewerID int, withPrivate bool, limit int) ([]*repository.NoteSearchHit, error)
 	Save(note *entity.Note) error
 	SaveWithDB(db *gorm.DB, note *entity.Note) error
 	Delete(note *entity.Note) error
 	DeleteWithDB(db *gorm.DB, note *entity.Note) error
+	UpdateTextStatusWithDB(db *gorm.DB, noteID int, attachment string, status entity.TextExtractionStatus) (bool, error)
 }
 
 type NoteService struct {
-	DB         *gorm.DB
-	NoteRepo   NoteRepository
-	UserRepo   UserRepository
-	WSService  *WebSocketService
-	S3         storage.S3Client
-	Validate   *validator.Validate
-	Audit      *AuditService
-	NotePolicy *policy.NotePolicy
+	DB             *gorm.DB
+	NoteRepo       NoteRepository
+	RevisionRepo   NoteRevisionRepository
+	TextRepo       NoteTextRepository
+	FileRepo       AttachmentRepository
+	AttachmentRepo NoteAttachmentRepository
+	GrantRepo      NoteGrantRepository
+	FolderRepo     FolderRepository
+	TagRepo        TagRepository
+	LinkRepo       NoteLinkRepository
+	RenderRepo     NoteRenderRepository
+	UserRepo       UserRepository
+	WSService      *WebSocketService
+	Storage        storage.Backend
+	Validate       *validator.Validate
+	Audit          *AuditService
+	NotePolicy     *policy.NotePolicy
+	FolderPolicy   *policy.FolderPolicy
 }
 
 func NewNoteService(
 	db *gorm.DB,
 	noteRepo NoteRepository,
+	revisionRepo NoteRevisionRepository,
+	textRepo NoteTextRepository,
+	fileRepo AttachmentRepository,
+	attachmentRepo NoteAttachmentRepository,
+	grantRepo NoteGrantRepository,
+	folderRepo FolderRepository,
+	tagRepo TagRepository,
+	linkRepo NoteLinkRepository,
+	renderRepo NoteRenderRepository,
 	userRepo UserRepository,
 	wsService *WebSocketService,
-	s3 storage.S3Client,
+	store storage.Backend,
 	validate *validator.Validate,
 	auditService *AuditService,
 	notePolicy *policy.NotePolicy,
+	folderPolicy *policy.FolderPolicy,
 ) *NoteService {
 	return &NoteService{
-		DB:         db,
-		NoteRepo:   noteRepo,
-		UserRepo:   userRepo,
-		WSService:  wsService,
-		S3:         s3,
-		Validate:   validate,
-		Audit:      auditService,
-		NotePolicy: notePolicy,
+		DB:             db,
+		NoteRepo:       noteRepo,
+		RevisionRepo:   revisionRepo,
+		TextRepo:       textRepo,
+		FileRepo:       fileRepo,
+		AttachmentRepo: attachmentRepo,
+		GrantRepo:      grantRepo,
+		FolderRepo:     folderRepo,
+		TagRepo:        tagRepo,
+		LinkRepo:       linkRepo,
+		RenderRepo:     renderRepo,
+		UserRepo:       userRepo,
+		WSService:      wsService,
+		Storage:        store,
+		Validate:       validate,
+		Audit:          auditService,
+		NotePolicy:     notePolicy,
+		FolderPolicy:   folderPolicy,
 	}
 }
 
-func (n *NoteService) GetAllNotes(actor *entity.User) ([]*contract.NoteResponse, apierror.ErrorResponse) {
-	canSeeHidden := actor.Permissions.HasEffective(entity.PermissionSeeHiddenNotes)
-	notes, err := n.NoteRepo.FindAll(canSeeHidden)
-	if err != nil {
-		log.Errorf("failed to fetch notes: %v", err)
-		return nil, apierror.InternalServerError
-	}
-
-	resp := make([]*contract.NoteResponse, len(notes))
-	for i, note := range notes {
-		resp[i] = toNoteResponse(note, false)
-	}
-	return resp, nil
-}
-
 func (n *NoteService) GetNoteByID(actor *entity.User, noteId int) (*contract.NoteResponse, apierror.ErrorResponse) {
 	note, err := n.NoteRepo.FindByID(noteId)
 	if err != nil {
@@ -105,6 +138,15 @@ func (n *NoteService) CreateTextNote(actor *entity.User, req *contract.TextNoteR
 		return nil, apierror.FromValidationError(valerr)
 	}
 
+	if apierr := checkNoteContent(entity.NoteType(req.NoteType), req.Content); apierr != nil {
+		return nil, apierr
+	}
+
+	folder, apierr := n.fetchNoteFolder(actor, req.FolderID)
+	if apierr != nil {
+		return nil, apierr
+	}
+
 	tags := strings.Join(req.Tags, " ")
 	now := utils.NowUTC()
 
@@ -115,15 +157,27 @@ func (n *NoteService) CreateTextNote(actor *entity.User, req *contract.TextNoteR
 		Tags:        strings.ToLower(tags),
 		NoteType:    entity.NoteType(req.NoteType),
 		ContentSize: len(req.Content),
+		ContentHash: contentHash([]byte(req.Content)),
 		Visibility:  entity.NoteVisibility(req.Visibility),
+		FolderID:    req.FolderID,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
+	inheritFolderVisibility(note, folder)
 
 	err := n.DB.Transaction(func(tx *gorm.DB) error {
 		if err := n.NoteRepo.SaveWithDB(tx, note); err != nil {
 			return err
 		}
+		if err := n.syncNoteTags(tx, nil, note); err != nil {
+			return err
+		}
+		if err := n.syncNoteLinks(tx, nil, note); err != nil {
+			return err
+		}
+		if err := n.recordNoteRevision(tx, nil, note, actor.ID); err != nil {
+			return err
+		}
 		return n.Audit.Record(tx, &entity.AuditLogEvent{
 			ActorUserID: &actor.ID,
 			ActionType:  entity.AuditActionNoteCreate,
@@ -140,11 +194,11 @@ func (n *NoteService) CreateTextNote(actor *entity.User, req *contract.TextNoteR
 
 	// I cannot reuse the same response since gateway events should not include the
 	// `content` if it's not a REFERENCE type (the payload gets too big ^^).
-	go n.dispatchNoteCreateEvent(toNoteResponse(note, false))
+	go n.dispatchNoteCreateEvent(note, toNoteResponse(note, false))
 	return toNoteResponse(note, true), nil
 }
 
-func (n *NoteService) CreateFileNote(actor *entity.User, req *contract.NoteRequest, fileHeader *multipart.FileHeader) (*contract.NoteResponse, apierror.ErrorResponse) {
+func (n *NoteService) CreateFileNote(actor *entity.User, req *contract.NoteRequest, file *contract.NoteFile) (*contract.NoteResponse, apierror.ErrorResponse) {
 	if !actor.Permissions.HasEffective(entity.PermissionCreateNotes) {
 		return nil, apierror.UserMissingPermsError
 	}
@@ -154,11 +208,16 @@ func (n *NoteService) CreateFileNote(actor *entity.User, req *contract.NoteReque
 		return nil, apierror.FromValidationError(valerr)
 	}
 
-	if apierr := checkNoteFile(fileHeader); apierr != nil {
+	if apierr := checkNoteFileName(file.Filename); apierr != nil {
 		return nil, apierr
 	}
 
-	filename, fileLength, apierr := handleNoteUpload(n.S3, fileHeader)
+	folder, apierr := n.fetchNoteFolder(actor, req.FolderID)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	upload, apierr := n.handleNoteUpload(file, noteFileSizeLimit)
 	if apierr != nil {
 		return nil, apierr
 	}
@@ -167,20 +226,41 @@ func (n *NoteService) CreateFileNote(actor *entity.User, req *contract.NoteReque
 	now := utils.NowUTC()
 	note := &entity.Note{
 		Name:        req.Name,
-		Content:     filename,
+		Content:     upload.filename,
 		CreatedByID: actor.ID,
 		Tags:        strings.ToLower(tags),
 		NoteType:    entity.NoteTypeReference,
-		ContentSize: fileLength,
+		ContentSize: upload.size,
+		ContentHash: upload.hash,
+		ContentType: upload.contentType,
+		Thumbnail:   upload.thumbnail,
+		TextStatus:  textStatusFor(upload.contentType),
 		Visibility:  entity.NoteVisibility(req.Visibility),
+		FolderID:    req.FolderID,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
+	inheritFolderVisibility(note, folder)
 
 	err := n.DB.Transaction(func(tx *gorm.DB) error {
 		if err := n.NoteRepo.SaveWithDB(tx, note); err != nil {
 			return err
 		}
+		if err := n.acquireAttachment(tx, upload); err != nil {
+			return err
+		}
+		if err := n.syncNoteTags(tx, nil, note); err != nil {
+			return err
+		}
+		if err := n.syncNoteLinks(tx, nil, note); err != nil {
+			return err
+		}
+		if err := n.recordNoteRevision(tx, nil, note, actor.ID); err != nil {
+			return err
+		}
+		if err := n.scheduleTextExtraction(tx, note); err != nil {
+			return err
+		}
 		return n.Audit.Record(tx, &entity.AuditLogEvent{
 			ActorUserID: &actor.ID,
 			ActionType:  entity.AuditActionNoteCreate,
@@ -191,17 +271,18 @@ func (n *NoteService) CreateFileNote(actor *entity.User, req *contract.NoteReque
 		})
 	})
 	if err != nil {
-		_ = deleteBucketObject(n.S3, note)
+		n.discardUpload(upload)
 		log.Errorf("failed to create note: %v", err)
 		return nil, apierror.InternalServerError
 	}
 
 	resp := toNoteResponse(note, true)
-	go n.dispatchNoteCreateEvent(resp)
+	go n.dispatchNoteCreateEvent(note, resp)
 	return resp, nil
 }
 
-func (n *NoteService) UpdateNote(actor *entity.User, noteId int, req *contract.UpdateNoteRequest) (*contract.NoteResponse, apierror.ErrorResponse) {
+// UpdateNote applies the PATCH request, as long as the note still matches 'pre'.
+func (n *NoteService) UpdateNote(actor *entity.User, noteId int, req *contract.UpdateNoteRequest, pre *contract.Precondition) (*contract.NoteResponse, apierror.ErrorResponse) {
 	note, err := n.NoteRepo.FindByID(noteId)
 	if err != nil {
 		log.Errorf("failed to fetch note: %v", err)
@@ -213,11 +294,32 @@ func (n *NoteService) UpdateNote(actor *entity.User, noteId int, req *contract.U
 		return nil, apierr
 	}
 
+	if !pre.Matches(note.Version) {
+		return nil, apierror.NewPreconditionFailedError(toNoteResponse(note, true), note.Version)
+	}
+
 	utils.Sanitize(req)
 	if valerr := n.Validate.Struct(req); valerr != nil {
 		return nil, apierror.FromValidationError(valerr)
 	}
 
+	if req.Content != nil && note.NoteType == entity.NoteTypeReference {
+		return nil, apierror.ReferenceContentUpdateError
+	}
+
+	if req.Content != nil {
+		if apierr = checkNoteContent(note.NoteType, *req.Content); apierr != nil {
+			return nil, apierr
+		}
+	}
+
+	if req.Visibility != nil && entity.NoteVisibility(*req.Visibility) == entity.VisibilityPublic {
+		if apierr = n.checkPublicInFolder(note.FolderID); apierr != nil {
+			return nil, apierr
+		}
+	}
+
+	fillLegacyContentHash(note)
 	before := *note
 
 	// Now, we can finally PATCH our data :D
@@ -225,6 +327,11 @@ func (n *NoteService) UpdateNote(actor *entity.User, noteId int, req *contract.U
 	if req.Name != nil {
 		note.Name = *req.Name
 	}
+	if req.Content != nil {
+		note.Content = *req.Content
+		note.ContentSize = len(note.Content)
+		note.ContentHash = contentHash([]byte(note.Content))
+	}
 	if req.Visibility != nil {
 		note.Visibility = entity.NoteVisibility(*req.Visibility)
 	}
@@ -232,15 +339,34 @@ func (n *NoteService) UpdateNote(actor *entity.User, noteId int, req *contract.U
 		note.Tags = strings.ToLower(tags)
 	}
 
-	note.UpdatedAt = utils.NowUTC()
 	changes := buildNoteUpdateAuditChanges(&before, note)
+	if len(changes) == 0 {
+		return toNoteResponse(note, false), nil
+	}
+	note.UpdatedAt = utils.NowUTC()
 
+	renamable, apierr := n.findRenamableNotes(actor, &before, note)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	var renamed []noteChange
 	err = n.DB.Transaction(func(tx *gorm.DB) error {
 		if err := n.NoteRepo.SaveWithDB(tx, note); err != nil {
 			return err
 		}
-		if len(changes) == 0 {
-			return nil
+		if err := n.syncNoteTags(tx, &before, note); err != nil {
+			return err
+		}
+		var err error
+		if renamed, err = n.rewriteNamingNotes(tx, actor, &before, note, renamable); err != nil {
+			return err
+		}
+		if err := n.syncNoteLinks(tx, &before, note); err != nil {
+			return err
+		}
+		if err := n.recordNoteRevision(tx, &before, note, actor.ID); err != nil {
+			return err
 		}
 		return n.Audit.Record(tx, &entity.AuditLogEvent{
 			ActorUserID: &actor.ID,
@@ -251,17 +377,148 @@ func (n *NoteService) UpdateNote(actor *entity.User, noteId int, req *contract.U
 			Changes:     changes,
 		})
 	})
+	if errors.Is(err, repository.ErrStaleVersion) {
+		return nil, n.notePreconditionFailed(note.ID)
+	}
+
 	if err != nil {
 		log.Errorf("failed to update note: %v", err)
 		return nil, apierror.InternalServerError
 	}
 
 	resp := toNoteResponse(note, false)
-	go n.dispatchNoteUpdateEvent(resp)
+	go n.dispatchNoteUpdateEvent(&before, note, resp)
+	go n.dispatchNoteChanges(renamed)
 	return resp, nil
 }
 
-func (n *NoteService) DeleteNote(actor *entity.User, noteId int) apierror.ErrorResponse {
+// saveRewrittenNote saves a note changed as a side effect of another operation,
+// with the revision and NOTE_UPDATE audit event of a regular update. 'batchID'
+// ties the event to that operation when not nil. The returned change is nil
+// for trashed notes, which are not known by clients.
+func (n *NoteService) saveRewrittenNote(tx *gorm.DB, actor *entity.User, before, note *entity.Note, batchID *int64) (*noteChange, error) {
+	changes := buildNoteUpdateAuditChanges(before, note)
+	if err := n.NoteRepo.SaveWithDB(tx, note); err != nil {
+		return nil, err
+	}
+	if err := n.syncNoteTags(tx, before, note); err != nil {
+		return nil, err
+	}
+	// Folders are not part of revisions, moving a note alone does not record one
+	if isRevisionChanged(before, note) {
+		if err := n.recordNoteRevision(tx, before, note, actor.ID); err != nil {
+			return nil, err
+		}
+	}
+	err := n.Audit.Record(tx, &entity.AuditLogEvent{
+		ActorUserID: &actor.ID,
+		ActionType:  entity.AuditActionNoteUpdate,
+		SubjectType: entity.AuditSubjectNote,
+		SubjectID:   strconv.Itoa(note.ID),
+		Source:      entity.AuditSourceHTTPAPI,
+		BatchID:     batchID,
+		Changes:     changes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if note.DeletedAt != nil {
+		return nil, nil
+	}
+	return &noteChange{before: before, after: note}, nil
+}
+
+// ReplaceNoteFile uploads a new attachment for a REFERENCE note and
+// deletes the previous object once the note points to the new one.
+func (n *NoteService) ReplaceNoteFile(actor *entity.User, noteId int, file *contract.NoteFile) (*contract.NoteResponse, apierror.ErrorResponse) {
+	note, err := n.NoteRepo.FindByID(noteId)
+	if err != nil {
+		log.Errorf("failed to fetch note: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	apierr := n.NotePolicy.CanUpdate(note, actor)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	if note.NoteType != entity.NoteTypeReference {
+		return nil, apierror.NoteNotReferenceError
+	}
+
+	if apierr = checkNoteFileName(file.Filename); apierr != nil {
+		return nil, apierr
+	}
+
+	upload, apierr := n.handleNoteUpload(file, noteFileSizeLimit)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	before := *note
+	note.Content = upload.filename
+	note.ContentSize = upload.size
+	note.ContentHash = upload.hash
+	note.ContentType = upload.contentType
+	note.Thumbnail = upload.thumbnail
+	note.TextStatus = textStatusFor(upload.contentType)
+	note.UpdatedAt = utils.NowUTC()
+	changes := buildNoteUpdateAuditChanges(&before, note)
+
+	lastReference := false
+	err = n.DB.Transaction(func(tx *gorm.DB) error {
+		if err := n.NoteRepo.SaveWithDB(tx, note); err != nil {
+			return err
+		}
+		if err := n.acquireAttachment(tx, upload); err != nil {
+			return err
+		}
+		last, err := n.releaseAttachment(tx, &before)
+		if err != nil {
+			return err
+		}
+		lastReference = last
+		if err := n.recordNoteRevision(tx, &before, note, actor.ID); err != nil {
+			return err
+		}
+		if err := n.scheduleTextExtraction(tx, note); err != nil {
+			return err
+		}
+		return n.Audit.Record(tx, &entity.AuditLogEvent{
+			ActorUserID: &actor.ID,
+			ActionType:  entity.AuditActionNoteUpdate,
+			SubjectType: entity.AuditSubjectNote,
+			SubjectID:   strconv.Itoa(note.ID),
+			Source:      entity.AuditSourceHTTPAPI,
+			Changes:     changes,
+		})
+	})
+	if err != nil {
+		// The note still points to the old object, so the new one must go
+		n.discardUpload(upload)
+		if errors.Is(err, repository.ErrStaleVersion) {
+			return nil, n.notePreconditionFailed(note.ID)
+		}
+		log.Errorf("failed to replace file of note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	// Other notes may still use the previous file
+	if lastReference {
+		if err = deleteBucketObject(n.Storage, &before); err != nil {
+			log.Errorf("failed to delete previous file %s of note %d: %v", before.Content, note.ID, err)
+		}
+	}
+
+	resp := toNoteResponse(note, true)
+	go n.dispatchNoteUpdateEvent(&before, note, resp)
+	return resp, nil
+}
+
+// DeleteNote moves the note to the trash, as long as it still matches 'pre'.
+// Its attachment is kept until the note is purged.
+func (n *NoteService) DeleteNote(actor *entity.User, noteId int, pre *contract.Precondition) apierror.ErrorResponse {
 	note, err := n.NoteRepo.FindByID(noteId)
 	if err != nil {
 		log.Errorf("failed to fetch note: %v", err)
@@ -273,14 +530,17 @@ func (n *NoteService) DeleteNote(actor *entity.User, noteId int) apierror.ErrorR
 		return apierr
 	}
 
-	err = deleteBucketObject(n.S3, note)
-	if err != nil {
-		log.Errorf("failed to delete note: %v", err)
-		return apierror.InternalServerError
+	if !pre.Matches(note.Version) {
+		return apierror.NewPreconditionFailedError(toNoteResponse(note, true), note.Version)
 	}
 
+	before := *note
+	now := utils.NowUTC()
+	note.DeletedAt = &now
+	note.DeletedByID = &actor.ID
+
 	err = n.DB.Transaction(func(tx *gorm.DB) error {
-		if err := n.NoteRepo.DeleteWithDB(tx, note); err != nil {
+		if err := n.NoteRepo.SaveWithDB(tx, note); err != nil {
 			return err
 		}
 		return n.Audit.Record(tx, &entity.AuditLogEvent{
@@ -292,80 +552,212 @@ func (n *NoteService) DeleteNote(actor *entity.User, noteId int) apierror.ErrorR
 			Changes:     buildNoteDeleteAuditChanges(note),
 		})
 	})
+	if errors.Is(err, repository.ErrStaleVersion) {
+		return n.notePreconditionFailed(note.ID)
+	}
+
 	if err != nil {
 		log.Errorf("failed to delete note: %v", err)
 		return apierror.InternalServerError
 	}
 
-	go n.dispatchNoteDeleteEvent(note.ID)
+	go n.dispatchNoteDeleteEvent(&before)
 	return nil
 }
 
-func (n *NoteService) dispatchNoteCreateEvent(note *contract.NoteResponse) {
-	n.WSService.Broadcast(context.Background(), &events.NoteCreated{
-		NoteResponse: note,
-	})
+// notePreconditionFailed reports the current state of a note which changed
+// between the moment it was loaded and the moment it was saved.
+func (n *NoteService) notePreconditionFailed(noteId int) apierror.ErrorResponse {
+	current, err := n.NoteRepo.FindByID(noteId)
+	if err != nil {
+		log.Errorf("failed to fetch note: %v", err)
+		return apierror.InternalServerError
+	}
+
+	if current == nil {
+		return apierror.NotFoundError
+	}
+	return apierror.NewPreconditionFailedError(toNoteResponse(current, true), current.Version)
 }
 
-func (n *NoteService) dispatchNoteUpdateEvent(note *contract.NoteResponse) {
-	n.WSService.Broadcast(context.Background(), &events.NoteUpdated{
-		NoteResponse: note,
+func (n *NoteService) dispatchNoteCreateEvent(note *entity.Note, resp *contract.NoteResponse) {
+	n.fanoutNoteEvent(nil, note, &events.NoteCreated{
+		NoteResponse: resp,
 	})
 }
 
-func (n *NoteService) dispatchNoteDeleteEvent(noteID int) {
-	n.WSService.Broadcast(context.Background(), &events.NoteDeleted{
-		NoteID: noteID,
+// dispatchNoteUpdateEvent notifies the users who can see the updated note,
+// and revokes it from the ones who could only see it 'before' the update.
+func (n *NoteService) dispatchNoteUpdateEvent(before, note *entity.Note, resp *contract.NoteResponse) {
+	n.fanoutNoteEvent(before, note, &events.NoteUpdated{
+		NoteResponse: resp,
 	})
 }
 
-// handleNoteUpload tries to upload the note to S3 and already generates the
-// new UUID name of the file object that will persist.
-func handleNoteUpload(s3 storage.S3Client, fileheader *multipart.FileHeader) (string, int, apierror.ErrorResponse) {
-	ext := filepath.Ext(fileheader.Filename)
-	bytes, apierr := readNoteFile(fileheader)
-	if apierr != nil {
-		return "", 0, apierr
+func (n *NoteService) dispatchNoteChanges(changes []noteChange) {
+	for _, change := range changes {
+		n.dispatchNoteUpdateEvent(change.before, change.after, toNoteResponse(change.after, false))
 	}
+}
 
-	filename := uuid.NewString() + ext
-	err := s3.UploadFile(bytes, storage.PathAttachments+filename)
+// dispatchNoteDeleteEvent tells the users who could see the note, in its state
+// before deletion, that it is gone.
+func (n *NoteService) dispatchNoteDeleteEvent(note *entity.Note) {
+	n.fanoutNoteEvent(nil, note, &events.NoteDeleted{
+		NoteID: note.ID,
+	})
+}
+
+// fanoutNoteEvent sends 'evt' to every connected user allowed to see 'note'.
+// Users who could see the 'before' state (if any) but not the current one
+// get a NoteRevoked event instead.
+func (n *NoteService) fanoutNoteEvent(before, note *entity.Note, evt events.SocketEvent) {
+	revoked := &events.NoteRevoked{NoteID: note.ID}
+	n.WSService.BroadcastPerUser(context.Background(), func(userID int) events.SocketEvent {
+		return n.noteEventFor(userID, before, note, evt, revoked)
+	})
+}
+
+func (n *NoteService) noteEventFor(userID int, before, note *entity.Note, evt, revoked events.SocketEvent) events.SocketEvent {
+	recipient, err := n.UserRepo.FindActiveByID(userID)
 	if err != nil {
-		log.Errorf("failed to upload file: %v", err)
-		return "", 0, apierror.InternalServerError
+		log.Errorf("failed to find user (%d) by id: %v", userID, err)
+		return nil
 	}
-	return filename, len(bytes), nil
-}
 
-func checkNoteFile(fileHeader *multipart.FileHeader) apierror.ErrorResponse {
-	if fileHeader.Size > contract.MaxNoteFileSizeBytes {
-		return apierror.NewNoteContentTooLargeError(contract.MaxNoteFileSizeBytes)
+	if recipient == nil {
+		return nil
 	}
 
-	if strings.TrimSpace(fileHeader.Filename) == "" {
-		return apierror.MissingFileNameError
+	if n.NotePolicy.CanSee(note, recipient) == nil {
+		return evt
 	}
 
-	if ext, ok := utils.CheckFileExt(fileHeader.Filename, contract.ValidNoteFileTypes); !ok {
-		return apierror.NewInvalidFileExtError(ext)
+	if before != nil && n.NotePolicy.CanSee(before, recipient) == nil {
+		return revoked
 	}
 	return nil
 }
 
-func readNoteFile(fileHeader *multipart.FileHeader) ([]byte, apierror.ErrorResponse) {
-	file, err := fileHeader.Open()
-	if err != nil {
-		log.Errorf("failed to open file: %v", err)
+// noteUpload describes an attachment that was successfully uploaded to S3.
+// Reused uploads point to a file stored before with the same bytes.
+type noteUpload struct {
+	filename    string
+	size        int
+	hash        string
+	contentType string
+	thumbnail   string
+	reused      bool
+}
+
+// errNoteFileTooLarge aborts uploads going over their size limit.
+var errNoteFileTooLarge = errors.New("note file is too large")
+
+// noteFileReader computes the size and hash of a note file while it streams to
+// S3, and fails as soon as the file goes over 'limit' bytes.
+type noteFileReader struct {
+	body  io.Reader
+	hash  hash.Hash
+	size  int64
+	limit int64
+}
+
+func (f *noteFileReader) Read(p []byte) (int, error) {
+	n, err := f.body.Read(p)
+	f.size += int64(n)
+	f.hash.Write(p[:n])
+
+	if f.size > f.limit {
+		return n, errNoteFileTooLarge
+	}
+	return n, err
+}
+
+// noteFileSizeLimit caps the main file of REFERENCE notes, whatever its type.
+func noteFileSizeLimit(string) int64 {
+	return contract.MaxNoteFileSizeBytes
+}
+
+// handleNoteUpload streams the note file to S3 under a temporary UUID name, then
+// stores it under its hash. The file is never held in memory as a whole.
+// Its first bytes must match the signature of the type its extension claims,
+// and its size must stay within the limit 'sizeLimit' gives for that type.
+func (n *NoteService) handleNoteUpload(file *contract.NoteFile, sizeLimit func(contentType string) int64) (*noteUpload, apierror.ErrorResponse) {
+	ext := filepath.Ext(file.Filename)
+	sniffer := bufio.NewReader(file.Body)
+	head, err := sniffer.Peek(utils.FileSignatureLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		log.Errorf("failed to read file: %v", err)
 		return nil, apierror.InternalServerError
 	}
-	defer file.Close()
 
-	bytes, err := io.ReadAll(file)
+	contentType, ok := utils.SniffFileType(ext, head)
+	if !ok {
+		return nil, apierror.NewFileContentMismatchError(ext)
+	}
+
+	limit := sizeLimit(contentType)
+	tmp := storage.PathUploads + uuid.NewString() + ext
+	body := &noteFileReader{
+		body:  sniffer,
+		hash:  sha256.New(),
+		limit: limit,
+	}
+
+	err = n.Storage.UploadFile(body, tmp, contentType)
+	if errors.Is(err, errNoteFileTooLarge) {
+		return nil, apierror.NewNoteContentTooLargeError(limit)
+	}
+
 	if err != nil {
-		log.Errorf("failed to read file: %v", err)
+		log.Errorf("failed to upload file: %v", err)
 		return nil, apierror.InternalServerError
 	}
-	return bytes, nil
+
+	upload := &noteUpload{
+		size:        int(body.size),
+		hash:        hex.EncodeToString(body.hash.Sum(nil)),
+		contentType: contentType,
+	}
+	if err = n.storeAttachment(tmp, ext, upload); err != nil {
+		log.Errorf("failed to store file %s: %v", upload.hash, err)
+		return nil, apierror.InternalServerError
+	}
+	return upload, nil
+}
+
+// checkNoteContent validates the structure of FLOWCHART notes, reporting each
+// problem under its path in the content. Other types accept any text.
+func checkNoteContent(noteType entity.NoteType, content string) apierror.ErrorResponse {
+	if noteType != entity.NoteTypeFlowchart {
+		return nil
+	}
+
+	problems := flowchart.Validate(content)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	apierr := apierror.NewStructured(http.StatusBadRequest)
+	for _, problem := range problems {
+		field := "content"
+		if problem.Field != "" {
+			field += "." + problem.Field
+		}
+		apierr.Add(field, problem.Message)
+	}
+	return apierr
+}
+
+func checkNoteFileName(filename string) apierror.ErrorResponse {
+	if strings.TrimSpace(filename) == "" {
+		return apierror.MissingFileNameError
+	}
+
+	if ext, ok := utils.CheckFileExt(filename, contract.ValidNoteFileTypes); !ok {
+		return apierror.NewInvalidFileExtError(ext)
+	}
+	return nil
 }
 
 func toNoteResponse(note *entity.Note, forceContent bool) *contract.NoteResponse {
@@ -374,25 +766,40 @@ func toNoteResponse(note *entity.Note, forceContent bool) *contract.NoteResponse
 		content = note.Content
 	}
 
+	var deletedAt *string
+	if note.DeletedAt != nil {
+		formatted := utils.FormatEpoch(*note.DeletedAt)
+		deletedAt = &formatted
+	}
+
 	return &contract.NoteResponse{
-		ID:          note.ID,
-		Name:        note.Name,
-		Content:     content,
-		Tags:        toTagsArray(note.Tags),
-		Visibility:  string(note.Visibility),
-		NoteType:    string(note.NoteType),
-		ContentSize: note.ContentSize,
-		CreatedByID: note.CreatedByID,
-		CreatedAt:   utils.FormatEpoch(note.CreatedAt),
-		UpdatedAt:   utils.FormatEpoch(note.UpdatedAt),
+		ID:           note.ID,
+		Name:         note.Name,
+		Content:      content,
+		Tags:         toTagsArray(note.Tags),
+		Visibility:   string(note.Visibility),
+		NoteType:     string(note.NoteType),
+		ContentSize:  note.ContentSize,
+		ContentType:  note.ContentType,
+		ThumbnailURL: thumbnailURL(note),
+		TextStatus:   string(note.TextStatus),
+		CreatedByID:  note.CreatedByID,
+		FolderID:     note.FolderID,
+		CreatedAt:    utils.FormatEpoch(note.CreatedAt),
+		UpdatedAt:    utils.FormatEpoch(note.UpdatedAt),
+		DeletedAt:    deletedAt,
+		DeletedByID:  note.DeletedByID,
+		Version:      note.Version,
 	}
 }
 
-// deleteBucketObject deletes the file with the given name from the attachments directory in S3.
+// deleteBucketObject deletes the attachment of the note from S3, along with its thumbnail.
+// Attachments are shared, so callers must first release the reference of the
+// note and only call it when that was the last one.
 //
 // It is idempotent: it returns nil if the object does not exist.
 // This prevents errors when the database and S3 bucket are out of sync.
-func deleteBucketObject(bucket storage.S3Client, note *entity.Note) error {
+func deleteBucketObject(bucket storage.Backend, note *entity.Note) error {
 	fileName := note.Content
 
 	// If the note is a text/chart file, then there is nothing to delete from
@@ -404,19 +811,44 @@ func deleteBucketObject(bucket storage.S3Client, note *entity.Note) error {
 	if fileName == "" {
 		return fmt.Errorf("deleteBucketObject: filename cannot be empty")
 	}
+	return deleteStoredFile(bucket, fileName, note.Thumbnail)
+}
 
-	key := storage.PathAttachments + fileName
-	err := bucket.DeleteFile(key)
+// deleteStoredFile deletes a stored file along with its thumbnail, if it has one.
+func deleteStoredFile(bucket storage.Backend, filename, thumbnail string) error {
+	if thumbnail != "" {
+		err := ignoreMissingObject(bucket.DeleteFile(storage.PathThumbnails + thumbnail))
+		if err != nil {
+			return err
+		}
+	}
+
+	key := storage.PathAttachments + filename
+	return ignoreMissingObject(bucket.DeleteFile(key))
+}
 
+func ignoreMissingObject(err error) error {
 	var noKey *types.NoSuchKey
 	if errors.As(err, &noKey) {
 		return nil
 	}
+	return err
+}
 
-	if err != nil {
-		return err
+// contentHash returns the hex encoded SHA-256 of the given content.
+// It lets audit rows track content changes without storing the content itself.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fillLegacyContentHash computes the hash of text notes created before
+// hashes were stored, so their first content change can be audited properly.
+func fillLegacyContentHash(note *entity.Note) {
+	if note.ContentHash != "" || note.NoteType == entity.NoteTypeReference {
+		return
 	}
-	return nil
+	note.ContentHash = contentHash([]byte(note.Content))
 }
 
 func toTagsArray(tags string) []string {
@@ -427,14 +859,20 @@ func toTagsArray(tags string) []string {
 }
 
 func buildNoteCreateAuditChanges(note *entity.Note) []*entity.AuditLogChange {
-	return []*entity.AuditLogChange{
+	changes := []*entity.AuditLogChange{
 		newAuditCreateValue("name", entity.AuditValueTypeString, note.Name),
 		newAuditCreateValue("created_by_id", entity.AuditValueTypeInt, strconv.Itoa(note.CreatedByID)),
 		newAuditCreateValue("tags", entity.AuditValueTypeStringArray, auditJSONString(toTagsArray(note.Tags))),
 		newAuditCreateValue("note_type", entity.AuditValueTypeEnum, string(note.NoteType)),
 		newAuditCreateValue("content_size", entity.AuditValueTypeInt, strconv.Itoa(note.ContentSize)),
+		newAuditCreateValue("content_hash", entity.AuditValueTypeString, note.ContentHash),
 		newAuditCreateValue("visibility", entity.AuditValueTypeEnum, string(note.Visibility)),
 	}
+
+	if note.ContentType != "" {
+		changes = append(changes, newAuditCreateValue("content_type", entity.AuditValueTypeString, note.ContentType))
+	}
+	return changes
 }
 
 func buildNoteUpdateAuditChanges(before, after *entity.Note) []*entity.AuditLogChange {
@@ -442,6 +880,10 @@ func buildNoteUpdateAuditChanges(before, after *entity.Note) []*entity.AuditLogC
 	appendAuditStringChange(&changes, "name", before.Name, after.Name)
 	appendAuditEnumChange(&changes, "visibility", string(before.Visibility), string(after.Visibility))
 	appendAuditStringArrayChange(&changes, "tags", toTagsArray(before.Tags), toTagsArray(after.Tags))
+	appendAuditIntChange(&changes, "content_size", int64(before.ContentSize), int64(after.ContentSize))
+	appendAuditStringChange(&changes, "content_hash", before.ContentHash, after.ContentHash)
+	appendAuditStringChange(&changes, "content_type", before.ContentType, after.ContentType)
+	appendAuditOptionalIntChange(&changes, "folder_id", before.FolderID, after.FolderID)
 	return changes
 }
 
@@ -452,6 +894,7 @@ func buildNoteDeleteAuditChanges(note *entity.Note) []*entity.AuditLogChange {
 		newAuditDeleteValue("tags", entity.AuditValueTypeStringArray, auditJSONString(toTagsArray(note.Tags))),
 		newAuditDeleteValue("note_type", entity.AuditValueTypeEnum, string(note.NoteType)),
 		newAuditDeleteValue("content_size", entity.AuditValueTypeInt, strconv.Itoa(note.ContentSize)),
+		newAuditDeleteValue("content_hash", entity.AuditValueTypeString, note.ContentHash),
 		newAuditDeleteValue("visibility", entity.AuditValueTypeEnum, string(note.Visibility)),
 	}
 }
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/events"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strconv"
+
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+type NoteGrantRepository interface {
+	FindGrant(noteID, userID int) (*entity.NoteGrant, error)
+	FindAllByNoteID(noteID int) ([]*entity.NoteGrant, error)
+	SaveWithDB(db *gorm.DB, grant *entity.NoteGrant) error
+	DeleteWithDB(db *gorm.DB, grant *entity.NoteGrant) error
+	DeleteByNoteIDWithDB(db *gorm.DB, noteID int) error
+}
+
+func (n *NoteService) GetNoteCollaborators(actor *entity.User, noteId int) ([]*contract.NoteGrantResponse, apierror.ErrorResponse) {
+	note, apierr := n.fetchVisibleNote(actor, noteId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	grants, err := n.GrantRepo.FindAllByNoteID(note.ID)
+	if err != nil {
+		log.Errorf("failed to fetch grants of note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	resp := make([]*contract.NoteGrantResponse, len(grants))
+	for i, grant := range grants {
+		resp[i] = toNoteGrantResponse(grant)
+	}
+	return resp, nil
+}
+
+// PutNoteCollaborator grants 'userId' the requested role on the note,
+// replacing the role they may already have.
+func (n *NoteService) PutNoteCollaborator(actor *entity.User, noteId, userId int, req *contract.NoteGrantRequest) (*contract.NoteGrantResponse, apierror.ErrorResponse) {
+	utils.Sanitize(req)
+	if err := n.Validate.Struct(req); err != nil {
+		return nil, apierror.FromValidationError(err)
+	}
+
+	note, apierr := n.fetchManageableNote(actor, noteId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	if userId == note.CreatedByID {
+		return nil, apierror.NewSimple(400, "The note owner cannot be added as a collaborator")
+	}
+
+	target, err := n.UserRepo.FindActiveByID(userId)
+	if err != nil {
+		log.Errorf("failed to fetch user %d: %v", userId, err)
+		return nil, apierror.InternalServerError
+	}
+
+	if target == nil {
+		return nil, apierror.NotFoundError
+	}
+
+	grant, err := n.GrantRepo.FindGrant(note.ID, target.ID)
+	if err != nil {
+		log.Errorf("failed to fetch grant of user %d on note %d: %v", target.ID, note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	role := entity.NoteGrantRole(req.Role)
+	now := utils.NowUTC()
+
+	changes := []*entity.AuditLogChange{
+		newAuditCreateValue("user_id", entity.AuditValueTypeInt, strconv.Itoa(target.ID)),
+	}
+
+	if grant == nil {
+		grant = &entity.NoteGrant{
+			NoteID:    note.ID,
+			UserID:    target.ID,
+			CreatedAt: now,
+		}
+		changes = append(changes, newAuditCreateValue("role", entity.AuditValueTypeEnum, string(role)))
+	} else {
+		if grant.Role == role {
+			return toNoteGrantResponse(grant), nil
+		}
+		appendAuditEnumChange(&changes, "role", string(grant.Role), string(role))
+	}
+
+	grant.Role = role
+	grant.GrantedByID = actor.ID
+	grant.UpdatedAt = now
+
+	err = n.DB.Transaction(func(tx *gorm.DB) error {
+		if err := n.GrantRepo.SaveWithDB(tx, grant); err != nil {
+			return err
+		}
+		return n.Audit.Record(tx, &entity.AuditLogEvent{
+			ActorUserID: &actor.ID,
+			ActionType:  entity.AuditActionNoteShare,
+			SubjectType: entity.AuditSubjectNote,
+			SubjectID:   strconv.Itoa(note.ID),
+			Source:      entity.AuditSourceHTTPAPI,
+			Changes:     changes,
+		})
+	})
+	if err != nil {
+		log.Errorf("failed to share note %d with user %d: %v", note.ID, target.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	go n.dispatchNoteGrantEvent(note, target.ID)
+	return toNoteGrantResponse(grant), nil
+}
+
+func (n *NoteService) DeleteNoteCollaborator(actor *entity.User, noteId, userId int) apierror.ErrorResponse {
+	note, apierr := n.fetchManageableNote(actor, noteId)
+	if apierr != nil {
+		return apierr
+	}
+
+	grant, err := n.GrantRepo.FindGrant(note.ID, userId)
+	if err != nil {
+		log.Errorf("failed to fetch grant of user %d on note %d: %v", userId, note.ID, err)
+		return apierror.InternalServerError
+	}
+
+	if grant == nil {
+		return apierror.NotFoundError
+	}
+
+	err = n.DB.Transaction(func(tx *gorm.DB) error {
+		if err := n.GrantRepo.DeleteWithDB(tx, grant); err != nil {
+			return err
+		}
+		return n.Audit.Record(tx, &entity.AuditLogEvent{
+			ActorUserID: &actor.ID,
+			ActionType:  entity.AuditActionNoteUnshare,
+			SubjectType: entity.AuditSubjectNote,
+			SubjectID:   strconv.Itoa(note.ID),
+			Source:      entity.AuditSourceHTTPAPI,
+			Changes: []*entity.AuditLogChange{
+				newAuditDeleteValue("user_id", entity.AuditValueTypeInt, strconv.Itoa(grant.UserID)),
+				newAuditDeleteValue("role", entity.AuditValueTypeEnum, string(grant.Role)),
+			},
+		})
+	})
+	if err != nil {
+		log.Errorf("failed to unshare note %d with user %d: %v", note.ID, userId, err)
+		return apierror.InternalServerError
+	}
+
+	go n.dispatchNoteGrantEvent(note, userId)
+	return nil
+}
+
+func (n *NoteService) fetchManageableNote(actor *entity.User, noteId int) (*entity.Note, apierror.ErrorResponse) {
+	note, err := n.NoteRepo.FindByID(noteId)
+	if err != nil {
+		log.Errorf("failed to fetch note: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	if apierr := n.NotePolicy.CanManageGrants(note, actor); apierr != nil {
+		return nil, apierr
+	}
+	return note, nil
+}
+
+// dispatchNoteGrantEvent syncs a single collaborator after their grant changed,
+// since nobody else's view of the note is affected.
+func (n *NoteService) dispatchNoteGrantEvent(note *entity.Note, userID int) {
+	evt := n.noteEventFor(userID, nil, note, &events.NoteUpdated{
+		NoteResponse: toNoteResponse(note, false),
+	}, nil)
+
+	if evt == nil {
+		evt = &events.NoteRevoked{NoteID: note.ID}
+	}
+	n.WSService.Dispatch(context.Background(), userID, evt)
+}
+
+func toNoteGrantResponse(grant *entity.NoteGrant) *contract.NoteGrantResponse {
+	return &contract.NoteGrantResponse{
+		NoteID:      grant.NoteID,
+		UserID:      grant.UserID,
+		Role:        string(grant.Role),
+		GrantedByID: grant.GrantedByID,
+		CreatedAt:   utils.FormatEpoch(grant.CreatedAt),
+		UpdatedAt:   utils.FormatEpoch(grant.UpdatedAt),
+	}
+}
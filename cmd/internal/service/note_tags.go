@@ -0,0 +1,15 @@
+package service
+
+import (
+	"gorm.io/gorm"
+	"simplenotes/cmd/internal/domain/entity"
+)
+
+// syncNoteTags mirrors the tags of the note into note_tags when they changed.
+// A nil 'before' means the note was just created.
+func (n *NoteService) syncNoteTags(tx *gorm.DB, before, note *entity.Note) error {
+	if before != nil && before.Tags == note.Tags {
+		return nil
+	}
+	return n.TagRepo.ReplaceNoteTagsWithDB(tx, note.ID, toTagsArray(note.Tags))
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"io"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/infrastructure/storage"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"simplenotes/cmd/internal/utils/pdftext"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+const (
+	textExtractionBatchSize   = 20
+	maxTextExtractionAttempts = 5
+	textExtractionBackoff     = 1 * time.Minute // doubled after every failed attempt
+	maxExtractedTextBytes     = 1_000_000
+	maxTextExtractionErrBytes = 500
+
+	defaultTextExtractionLimit = 50
+	maxTextExtractionLimit     = 100
+)
+
+type NoteTextRepository interface {
+	FindByNoteID(noteID int) (*entity.NoteText, error)
+	FindDue(now int64, limit int) ([]*entity.NoteText, error)
+	FindAllByStatus(status entity.TextExtractionStatus, limit int) ([]*repository.NoteTextExtraction, error)
+	SaveWithDB(db *gorm.DB, text *entity.NoteText) error
+	DeleteByNoteIDWithDB(db *gorm.DB, noteID int) error
+}
+
+// textStatusFor gives the initial extraction status of an attachment.
+// Types we cannot extract text from have no status at all.
+func textStatusFor(contentType string) entity.TextExtractionStatus {
+	if contentType == "application/pdf" {
+		return entity.TextExtractionPending
+	}
+	return ""
+}
+
+// scheduleTextExtraction queues the attachment of the note for extraction,
+// dropping the text extracted from its previous attachment, if any.
+func (n *NoteService) scheduleTextExtraction(tx *gorm.DB, note *entity.Note) error {
+	if note.TextStatus != entity.TextExtractionPending {
+		return n.TextRepo.DeleteByNoteIDWithDB(tx, note.ID)
+	}
+
+	return n.TextRepo.SaveWithDB(tx, &entity.NoteText{
+		NoteID:        note.ID,
+		NextAttemptAt: note.UpdatedAt,
+		UpdatedAt:     note.UpdatedAt,
+	})
+}
+
+// ExtractPendingTexts runs every extraction due at 'now' and returns how many
+// were attempted. Failed attempts are retried later with an exponential backoff,
+// until the note is marked as FAILED.
+func (n *NoteService) ExtractPendingTexts(now int64) (int, error) {
+	attempted := 0
+	for {
+		texts, err := n.TextRepo.FindDue(now, textExtractionBatchSize)
+		if err != nil {
+			return attempted, err
+		}
+
+		for _, text := range texts {
+			if err := n.extractNoteText(text); err != nil {
+				return attempted, err
+			}
+			attempted++
+		}
+
+		if len(texts) < textExtractionBatchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// extractNoteText makes one extraction attempt. The outcome is discarded when the
+// note got a new attachment in the meantime, as that one has its own schedule.
+func (n *NoteService) extractNoteText(text *entity.NoteText) error {
+	note, err := n.NoteRepo.FindByID(text.NoteID)
+	if err != nil {
+		return err
+	}
+
+	if note == nil || note.TextStatus != entity.TextExtractionPending {
+		return nil
+	}
+
+	content, exerr := n.readNoteText(note)
+	now := utils.NowUTC()
+	status := entity.TextExtractionDone
+
+	text.Attempts++
+	text.UpdatedAt = now
+	if exerr != nil {
+		log.Warnf("failed to extract text of note %d (attempt %d): %v", note.ID, text.Attempts, exerr)
+		text.LastError = truncateUTF8(exerr.Error(), maxTextExtractionErrBytes)
+
+		if text.Attempts >= maxTextExtractionAttempts {
+			status = entity.TextExtractionFailed
+		} else {
+			status = entity.TextExtractionPending
+			backoff := textExtractionBackoff << (text.Attempts - 1)
+			text.NextAttemptAt = now + backoff.Milliseconds()
+		}
+	} else {
+		text.Content = content
+		text.LastError = ""
+	}
+
+	updated := false
+	err = n.DB.Transaction(func(tx *gorm.DB) error {
+		ok, err := n.NoteRepo.UpdateTextStatusWithDB(tx, note.ID, note.Content, status)
+		if err != nil || !ok {
+			return err
+		}
+		updated = true
+		return n.TextRepo.SaveWithDB(tx, text)
+	})
+	if err != nil {
+		return err
+	}
+
+	if updated && status != note.TextStatus {
+		before := *note
+		note.TextStatus = status
+		note.Version++
+		go n.dispatchNoteUpdateEvent(&before, note, toNoteResponse(note, false))
+	}
+	return nil
+}
+
+// readNoteText reads the attachment of the note back from the storage and
+// extracts its text.
+func (n *NoteService) readNoteText(note *entity.Note) (string, error) {
+	object, err := n.Storage.GetFile(storage.PathAttachments + note.Content)
+	if err != nil {
+		return "", err
+	}
+	defer object.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(object.Body, contract.MaxNoteFileSizeBytes))
+	if err != nil {
+		return "", err
+	}
+
+	return pdftext.Extract(data, maxExtractedTextBytes)
+}
+
+// GetTextExtractions lists the notes whose extraction is in the requested status,
+// FAILED by default, so admins can find attachments that could not be indexed.
+func (n *NoteService) GetTextExtractions(actor *entity.User, req *contract.TextExtractionListRequest) ([]*contract.TextExtractionResponse, apierror.ErrorResponse) {
+	if apierr := n.NotePolicy.CanSeeTextExtractions(actor); apierr != nil {
+		return nil, apierr
+	}
+
+	status := entity.TextExtractionFailed
+	if req.Status != "" {
+		status = entity.TextExtractionStatus(req.Status)
+	}
+
+	switch status {
+	case entity.TextExtractionPending, entity.TextExtractionDone, entity.TextExtractionFailed:
+	default:
+		return nil, apierror.NewSimple(400, "Status must be one of PENDING, DONE or FAILED")
+	}
+
+	limit := defaultTextExtractionLimit
+	if req.Limit != 0 {
+		if req.Limit < 1 || req.Limit > maxTextExtractionLimit {
+			return nil, apierror.NewSimple(400, "Limit must be between 1 and %d", maxTextExtractionLimit)
+		}
+		limit = req.Limit
+	}
+
+	texts, err := n.TextRepo.FindAllByStatus(status, limit)
+	if err != nil {
+		log.Errorf("failed to fetch text extractions: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	resp := make([]*contract.TextExtractionResponse, len(texts))
+	for i, text := range texts {
+		resp[i] = toTextExtractionResponse(text)
+	}
+	return resp, nil
+}
+
+func toTextExtractionResponse(text *repository.NoteTextExtraction) *contract.TextExtractionResponse {
+	var nextAttemptAt *string
+	if text.TextStatus == entity.TextExtractionPending {
+		formatted := utils.FormatEpoch(text.NextAttemptAt)
+		nextAttemptAt = &formatted
+	}
+
+	return &contract.TextExtractionResponse{
+		NoteID:        text.NoteID,
+		NoteName:      text.NoteName,
+		Status:        string(text.TextStatus),
+		Attempts:      text.Attempts,
+		LastError:     text.LastError,
+		NextAttemptAt: nextAttemptAt,
+		UpdatedAt:     utils.FormatEpoch(text.UpdatedAt),
+	}
+}
+
+// truncateUTF8 cuts 's' to at most 'max' bytes, without splitting a character.
+func truncateUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
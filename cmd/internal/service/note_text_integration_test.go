@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/infrastructure/storage"
+	"simplenotes/cmd/internal/utils"
+	"strings"
+	"testing"
+)
+
+func TestPDFTextIsExtractedInTheBackgroundAndSearchable(t *testing.T) {
+	db := newTestDB(t)
+
+	local, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create local storage: %v", err)
+	}
+
+	noteSvc := newTestNoteService(t, db, 15000)
+	noteSvc.Storage = local
+	owner := newTestWriter(t, db)
+
+	admin := &entity.User{
+		Username:    "admin",
+		Email:       "admin@example.com",
+		Permissions: entity.PermissionAdministrator,
+		Active:      true,
+		CreatedAt:   utils.NowUTC(),
+		UpdatedAt:   utils.NowUTC(),
+	}
+	if err = repository.NewUserRepository(db).Save(admin); err != nil {
+		t.Fatalf("save admin: %v", err)
+	}
+
+	report, apierr := noteSvc.CreateFileNote(owner, &contract.NoteRequest{
+		Name:       "Report",
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"finance"},
+	}, newTestNoteFile("report.pdf", newTestPDF("Quarterly zeppelin revenue")))
+	if apierr != nil {
+		t.Fatalf("create file note returned api error: %#v", apierr)
+	}
+	if report.TextStatus != string(entity.TextExtractionPending) {
+		t.Fatalf("expected new PDF note to wait for extraction, got %q", report.TextStatus)
+	}
+
+	broken, apierr := noteSvc.CreateFileNote(owner, &contract.NoteRequest{
+		Name:       "Scan",
+		Visibility: string(entity.VisibilityPublic),
+		Tags:       []string{"finance"},
+	}, newTestNoteFile("scan.pdf", newTestPDF("unreachable")))
+	if apierr != nil {
+		t.Fatalf("create file note returned api error: %#v", apierr)
+	}
+	if err = local.DeleteFile(storage.PathAttachments + broken.Content); err != nil {
+		t.Fatalf("failed to delete attachment: %v", err)
+	}
+
+	now := utils.NowUTC()
+	attempted, err := noteSvc.ExtractPendingTexts(now)
+	if err != nil {
+		t.Fatalf("extract pending texts: %v", err)
+	}
+	if attempted != 2 {
+		t.Fatalf("expected 2 extraction attempts, got %d", attempted)
+	}
+
+	done, apierr := noteSvc.GetNoteByID(owner, report.ID)
+	if apierr != nil {
+		t.Fatalf("get note returned api error: %#v", apierr)
+	}
+	if done.TextStatus != string(entity.TextExtractionDone) {
+		t.Fatalf("expected extraction to be done, got %q", done.TextStatus)
+	}
+	if done.Version != report.Version+1 {
+		t.Fatalf("expected the status change to bump the note version, got %d", done.Version)
+	}
+
+	found, apierr := noteSvc.SearchNotes(owner, &contract.NoteSearchRequest{Query: "zeppelin"})
+	if apierr != nil {
+		t.Fatalf("search returned api error: %#v", apierr)
+	}
+	if len(found.Results) != 1 || found.Results[0].ID != report.ID {
+		t.Fatalf("expected the PDF note to match its text, got %#v", found.Results)
+	}
+	if !strings.Contains(found.Results[0].Snippet, "<mark>zeppelin</mark>") {
+		t.Fatalf("expected snippet from the extracted text, got %q", found.Results[0].Snippet)
+	}
+
+	retried, err := noteSvc.TextRepo.FindByNoteID(broken.ID)
+	if err != nil {
+		t.Fatalf("find note text: %v", err)
+	}
+	if retried.Attempts != 1 || retried.LastError == "" || retried.NextAttemptAt <= now {
+		t.Fatalf("expected a failed attempt scheduled for retry, got %#v", retried)
+	}
+	if pending, _ := noteSvc.GetNoteByID(owner, broken.ID); pending.Version != broken.Version {
+		t.Fatalf("expected a retry to leave the note version alone, got %d", pending.Version)
+	}
+
+	if _, apierr = noteSvc.GetTextExtractions(owner, &contract.TextExtractionListRequest{}); apierr == nil || apierr.Code() != 403 {
+		t.Fatalf("expected non-admins to be denied, got %#v", apierr)
+	}
+
+	for attempt := 2; attempt <= maxTextExtractionAttempts; attempt++ {
+		if _, err = noteSvc.ExtractPendingTexts(now + 24*60*60*1000); err != nil {
+			t.Fatalf("extract pending texts: %v", err)
+		}
+	}
+
+	failed, apierr := noteSvc.GetTextExtractions(admin, &contract.TextExtractionListRequest{})
+	if apierr != nil {
+		t.Fatalf("get text extractions returned api error: %#v", apierr)
+	}
+	if len(failed) != 1 || failed[0].NoteID != broken.ID || failed[0].Attempts != maxTextExtractionAttempts {
+		t.Fatalf("expected the broken note to fail after %d attempts, got %#v", maxTextExtractionAttempts, failed)
+	}
+	if failed[0].NextAttemptAt != nil {
+		t.Fatalf("expected no retry after the last attempt, got %q", *failed[0].NextAttemptAt)
+	}
+
+	if _, apierr = noteSvc.ReplaceNoteFile(owner, report.ID, newTestNoteFile("report.pdf", newTestPDF("Annual budget"))); apierr != nil {
+		t.Fatalf("replace note file returned api error: %#v", apierr)
+	}
+
+	stale, apierr := noteSvc.SearchNotes(owner, &contract.NoteSearchRequest{Query: "zeppelin"})
+	if apierr != nil {
+		t.Fatalf("search returned api error: %#v", apierr)
+	}
+	if len(stale.Results) != 0 {
+		t.Fatalf("expected text of the replaced file to leave the index, got %#v", stale.Results)
+	}
+}
+
+// newTestPDF builds a single page PDF showing 'text'.
+func newTestPDF(text string) []byte {
+	content := fmt.Sprintf("BT /F1 12 Tf 72 712 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}
+
+	var b strings.Builder
+	b.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return []byte(b.String())
+}
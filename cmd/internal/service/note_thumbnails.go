@@ -0,0 +1,97 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/infrastructure/storage"
+	"simplenotes/cmd/internal/utils/apierror"
+	"simplenotes/cmd/internal/utils/thumbnail"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/gommon/log"
+)
+
+// createThumbnail stores the preview of a freshly uploaded attachment and
+// returns its file name. Types without previews, and previews that could
+// not be generated, give an empty name: a missing thumbnail never fails an upload.
+func createThumbnail(store storage.Backend, filename, contentType string) string {
+	var data []byte
+	var err error
+
+	switch contentType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		data, err = imageThumbnail(store, filename)
+	case "application/pdf":
+		data, err = thumbnail.PDFPlaceholder()
+	default:
+		return ""
+	}
+
+	if err != nil {
+		log.Warnf("failed to generate thumbnail of %s: %v", filename, err)
+		return ""
+	}
+
+	name := thumbnailName(filename)
+	if err = store.UploadFile(bytes.NewReader(data), storage.PathThumbnails+name, thumbnail.ContentType); err != nil {
+		log.Errorf("failed to upload thumbnail of %s: %v", filename, err)
+		return ""
+	}
+	return name
+}
+
+// thumbnailName names the thumbnail of a stored file, whether it has one or not.
+func thumbnailName(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + thumbnail.Ext
+}
+
+// imageThumbnail reads the attachment back from the storage, since uploads are
+// streamed and never kept in memory.
+func imageThumbnail(store storage.Backend, filename string) ([]byte, error) {
+	object, err := store.GetFile(storage.PathAttachments + filename)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Body.Close()
+
+	return thumbnail.FromImage(object.Body)
+}
+
+// GetNoteThumbnail opens the thumbnail of the note, as long as the actor can see it.
+// Callers must close the returned object.
+func (n *NoteService) GetNoteThumbnail(actor *entity.User, noteId int) (*storage.Object, apierror.ErrorResponse) {
+	note, err := n.NoteRepo.FindByID(noteId)
+	if err != nil {
+		log.Errorf("failed to fetch note: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	if apierr := n.NotePolicy.CanSee(note, actor); apierr != nil {
+		return nil, apierr
+	}
+
+	if note.Thumbnail == "" {
+		return nil, apierror.NotFoundError
+	}
+
+	object, err := n.Storage.GetFile(storage.PathThumbnails + note.Thumbnail)
+	if errors.Is(err, storage.ErrorObjectNotFound) {
+		return nil, apierror.NotFoundError
+	}
+
+	if err != nil {
+		log.Errorf("failed to open thumbnail of note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+	return object, nil
+}
+
+func thumbnailURL(note *entity.Note) string {
+	if note.Thumbnail == "" {
+		return ""
+	}
+	return "/api/notes/" + strconv.Itoa(note.ID) + "/thumbnail"
+}
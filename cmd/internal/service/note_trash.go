@@ -0,0 +1,213 @@
+package service
+
+import (
+	"errors"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strconv"
+
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+// trashPurgeBatchSize bounds how many notes are loaded at once while purging the trash.
+const trashPurgeBatchSize = 100
+
+// GetTrashedNotes lists the trashed notes the actor is able to restore, most recently deleted first.
+func (n *NoteService) GetTrashedNotes(actor *entity.User, req *contract.NoteTrashRequest) (*contract.NoteListResponse, apierror.ErrorResponse) {
+	filter := &repository.NoteListFilter{
+		Limit:       defaultNoteListLimit,
+		Sort:        repository.NoteSortDeletedAt,
+		Descending:  true,
+		Trashed:     true,
+		ViewerID:    actor.ID,
+		WithPrivate: actor.Permissions.HasEffective(entity.PermissionSeeHiddenNotes),
+	}
+
+	if req.Limit != 0 {
+		if req.Limit < 1 || req.Limit > maxNoteListLimit {
+			return nil, apierror.NewSimple(400, "Limit must be between 1 and %d", maxNoteListLimit)
+		}
+		filter.Limit = req.Limit
+	}
+
+	if req.Before != nil && req.After != nil {
+		return nil, apierror.NewSimple(400, "Parameters 'before' and 'after' cannot be used together")
+	}
+
+	var apierr apierror.ErrorResponse
+	if req.Before != nil {
+		if filter.Before, apierr = decodeNoteCursor(filter.Sort, *req.Before); apierr != nil {
+			return nil, apierr
+		}
+	}
+	if req.After != nil {
+		if filter.After, apierr = decodeNoteCursor(filter.Sort, *req.After); apierr != nil {
+			return nil, apierr
+		}
+	}
+
+	// Without the delete permission, users can only restore their own notes
+	if !actor.Permissions.HasEffective(entity.PermissionDeleteNotes) {
+		filter.CreatedByID = &actor.ID
+	}
+	return n.listNotePage(filter)
+}
+
+func (n *NoteService) RestoreNote(actor *entity.User, noteId int) (*contract.NoteResponse, apierror.ErrorResponse) {
+	note, apierr := n.fetchTrashedNote(actor, noteId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	changes := []*entity.AuditLogChange{
+		newAuditDeleteValue("deleted_at", entity.AuditValueTypeInt, strconv.FormatInt(*note.DeletedAt, 10)),
+		newAuditDeleteValue("deleted_by_id", entity.AuditValueTypeInt, strconv.Itoa(*note.DeletedByID)),
+	}
+
+	note.DeletedAt = nil
+	note.DeletedByID = nil
+
+	err := n.DB.Transaction(func(tx *gorm.DB) error {
+		if err := n.NoteRepo.SaveWithDB(tx, note); err != nil {
+			return err
+		}
+		return n.Audit.Record(tx, &entity.AuditLogEvent{
+			ActorUserID: &actor.ID,
+			ActionType:  entity.AuditActionNoteRestore,
+			SubjectType: entity.AuditSubjectNote,
+			SubjectID:   strconv.Itoa(note.ID),
+			Source:      entity.AuditSourceHTTPAPI,
+			Changes:     changes,
+		})
+	})
+	if errors.Is(err, repository.ErrStaleVersion) {
+		return nil, n.notePreconditionFailed(note.ID)
+	}
+
+	if err != nil {
+		log.Errorf("failed to restore note %d: %v", note.ID, err)
+		return nil, apierror.InternalServerError
+	}
+
+	// Clients dropped the note when it was trashed, so it comes back as a new one
+	go n.dispatchNoteCreateEvent(note, toNoteResponse(note, false))
+	return toNoteResponse(note, true), nil
+}
+
+// PurgeNote permanently deletes a trashed note along with its attachment.
+func (n *NoteService) PurgeNote(actor *entity.User, noteId int) apierror.ErrorResponse {
+	note, apierr := n.fetchTrashedNote(actor, noteId)
+	if apierr != nil {
+		return apierr
+	}
+
+	if err := n.purgeNote(note, &actor.ID, entity.AuditSourceHTTPAPI); err != nil {
+		log.Errorf("failed to purge note %d: %v", note.ID, err)
+		return apierror.InternalServerError
+	}
+	return nil
+}
+
+// PurgeTrashedNotes permanently deletes every note trashed before the 'cutoff'
+// and returns how many were purged.
+func (n *NoteService) PurgeTrashedNotes(cutoff int64) (int, error) {
+	purged := 0
+	for {
+		notes, err := n.NoteRepo.FindTrashedBefore(cutoff, trashPurgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, note := range notes {
+			if err := n.purgeNote(note, nil, entity.AuditSourceSystem); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+
+		if len(notes) < trashPurgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+func (n *NoteService) fetchTrashedNote(actor *entity.User, noteId int) (*entity.Note, apierror.ErrorResponse) {
+	note, err := n.NoteRepo.FindTrashedByID(noteId)
+	if err != nil {
+		log.Errorf("failed to fetch trashed note: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	if apierr := n.NotePolicy.CanDelete(note, actor); apierr != nil {
+		return nil, apierr
+	}
+	return note, nil
+}
+
+// purgeNote deletes the note row and everything attached to it, then its S3 objects
+// no other note shares. Objects go last, so a failure can only leave orphan
+// objects behind, never a note pointing to a missing file.
+func (n *NoteService) purgeNote(note *entity.Note, actorID *int, source entity.AuditSource) error {
+	lastReference := false
+	var unused []string
+	err := n.DB.Transaction(func(tx *gorm.DB) error {
+		if err := n.RevisionRepo.DeleteByNoteIDWithDB(tx, note.ID); err != nil {
+			return err
+		}
+		if err := n.GrantRepo.DeleteByNoteIDWithDB(tx, note.ID); err != nil {
+			return err
+		}
+		if err := n.TextRepo.DeleteByNoteIDWithDB(tx, note.ID); err != nil {
+			return err
+		}
+		last, err := n.releaseAttachment(tx, note)
+		if err != nil {
+			return err
+		}
+		lastReference = last
+		if unused, err = n.releaseNoteAttachments(tx, note.ID); err != nil {
+			return err
+		}
+		if err := n.TagRepo.ReplaceNoteTagsWithDB(tx, note.ID, nil); err != nil {
+			return err
+		}
+		if err := n.LinkRepo.DeleteByNoteIDWithDB(tx, note.ID); err != nil {
+			return err
+		}
+		if err := n.RenderRepo.DeleteByNoteIDWithDB(tx, note.ID); err != nil {
+			return err
+		}
+		if err := n.NoteRepo.DeleteWithDB(tx, note); err != nil {
+			return err
+		}
+		return n.Audit.Record(tx, &entity.AuditLogEvent{
+			ActorUserID: actorID,
+			ActionType:  entity.AuditActionNotePurge,
+			SubjectType: entity.AuditSubjectNote,
+			SubjectID:   strconv.Itoa(note.ID),
+			Source:      source,
+			Changes:     buildNoteDeleteAuditChanges(note),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range unused {
+		if err = deleteStoredFile(n.Storage, filename, thumbnailName(filename)); err != nil {
+			log.Errorf("failed to delete attached file %s of purged note %d: %v", filename, note.ID, err)
+		}
+	}
+
+	if !lastReference {
+		return nil
+	}
+
+	if err = deleteBucketObject(n.Storage, note); err != nil {
+		log.Errorf("failed to delete file %s of purged note %d: %v", note.Content, note.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/policy"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils"
+	"slices"
+	"testing"
+)
+
+func TestTagsCanBeListedRenamedMergedAndDeleted(t *testing.T) {
+	db := newTestDB(t)
+
+	auditRepo := repository.NewAuditRepository(db)
+	noteSvc := newTestNoteService(t, db, 14000)
+	tagSvc := NewTagService(db, noteSvc.TagRepo, noteSvc, newTestValidator(), noteSvc.Audit, policy.NewTagPolicy())
+	writer := newTestWriter(t, db)
+
+	admin := &entity.User{
+		Username:    "admin",
+		Email:       "admin@example.com",
+		Permissions: entity.PermissionAdministrator,
+		Active:      true,
+		CreatedAt:   utils.NowUTC(),
+		UpdatedAt:   utils.NowUTC(),
+	}
+	if err := repository.NewUserRepository(db).Save(admin); err != nil {
+		t.Fatalf("save admin: %v", err)
+	}
+
+	var ids []int
+	for _, tags := range [][]string{{"Work", "urgent"}, {"work", "WORKSHOP"}, {"workshop"}} {
+		created, apierr := noteSvc.CreateTextNote(writer, &contract.TextNoteRequest{
+			Name:       "Note",
+			Content:    "body",
+			NoteType:   string(entity.NoteTypeMarkdown),
+			Visibility: string(entity.VisibilityPublic),
+			Tags:       tags,
+		})
+		if apierr != nil {
+			t.Fatalf("create note returned api error: %#v", apierr)
+		}
+		ids = append(ids, created.ID)
+	}
+
+	listed, apierr := tagSvc.GetTags(writer, &contract.TagListRequest{Prefix: "WORK"})
+	if apierr != nil {
+		t.Fatalf("get tags returned api error: %#v", apierr)
+	}
+	if len(listed) != 2 || listed[0].NoteCount != 2 || listed[1].NoteCount != 2 {
+		t.Fatalf("expected work and workshop used twice each, got %#v", listed)
+	}
+
+	tagIDs := make(map[string]int)
+	for _, tag := range listed {
+		tagIDs[tag.Name] = tag.ID
+	}
+
+	if apierr = tagSvc.RenameTag(writer, tagIDs["work"], &contract.RenameTagRequest{Name: "job"}); apierr == nil || apierr.Code() != 403 {
+		t.Fatalf("expected non-admin rename to be forbidden, got %#v", apierr)
+	}
+	if apierr = tagSvc.RenameTag(admin, tagIDs["work"], &contract.RenameTagRequest{Name: "workshop"}); apierr == nil || apierr.Code() != 409 {
+		t.Fatalf("expected rename into an existing tag to conflict, got %#v", apierr)
+	}
+	if apierr = tagSvc.RenameTag(admin, tagIDs["work"], &contract.RenameTagRequest{Name: "Job"}); apierr != nil {
+		t.Fatalf("rename tag returned api error: %#v", apierr)
+	}
+	if apierr = tagSvc.MergeTag(admin, tagIDs["workshop"], &contract.MergeTagRequest{TargetID: tagIDs["work"]}); apierr != nil {
+		t.Fatalf("merge tag returned api error: %#v", apierr)
+	}
+
+	urgent, apierr := tagSvc.GetTags(writer, &contract.TagListRequest{Prefix: "urg"})
+	if apierr != nil || len(urgent) != 1 {
+		t.Fatalf("expected the urgent tag, got %#v (%#v)", urgent, apierr)
+	}
+	if apierr = tagSvc.DeleteTag(admin, urgent[0].ID); apierr != nil {
+		t.Fatalf("delete tag returned api error: %#v", apierr)
+	}
+
+	expected := [][]string{{"job"}, {"job"}, {"job"}}
+	for i, id := range ids {
+		note, apierr := noteSvc.GetNoteByID(writer, id)
+		if apierr != nil {
+			t.Fatalf("get note returned api error: %#v", apierr)
+		}
+		if !slices.Equal(note.Tags, expected[i]) {
+			t.Fatalf("expected note %d to have tags %v, got %v", id, expected[i], note.Tags)
+		}
+	}
+
+	remaining, apierr := tagSvc.GetTags(writer, &contract.TagListRequest{})
+	if apierr != nil {
+		t.Fatalf("get tags returned api error: %#v", apierr)
+	}
+	if len(remaining) != 1 || remaining[0].Name != "job" || remaining[0].NoteCount != 3 {
+		t.Fatalf("expected only job used 3 times, got %#v", remaining)
+	}
+
+	for actionType, count := range map[entity.AuditActionType]int{
+		entity.AuditActionTagRename:  1,
+		entity.AuditActionTagMerge:   1,
+		entity.AuditActionTagDelete:  1,
+		entity.AuditActionNoteUpdate: 5,
+	} {
+		events, err := auditRepo.List(&repository.AuditLogFilter{
+			Limit:      10,
+			ActionType: auditActionPtr(actionType),
+		})
+		if err != nil {
+			t.Fatalf("list audit events: %v", err)
+		}
+		if len(events) != count {
+			t.Fatalf("expected %d %s audit events, got %d", count, actionType, len(events))
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/policy"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTagListLimit = 20
+	maxTagListLimit     = 100
+)
+
+type TagRepository interface {
+	FindAllWithUsage(prefix string, viewerID int, withPrivate bool, limit int) ([]*repository.TagUsage, error)
+	FindByID(id int) (*entity.Tag, error)
+	FindByName(name string) (*entity.Tag, error)
+	SaveWithDB(db *gorm.DB, tag *entity.Tag) error
+	DeleteWithDB(db *gorm.DB, tag *entity.Tag) error
+	ReplaceNoteTagsWithDB(db *gorm.DB, noteID int, names []string) error
+}
+
+type TagService struct {
+	DB        *gorm.DB
+	TagRepo   TagRepository
+	Notes     *NoteService
+	Validate  *validator.Validate
+	Audit     *AuditService
+	TagPolicy *policy.TagPolicy
+}
+
+func NewTagService(
+	db *gorm.DB,
+	tagRepo TagRepository,
+	noteService *NoteService,
+	validate *validator.Validate,
+	auditService *AuditService,
+	tagPolicy *policy.TagPolicy,
+) *TagService {
+	return &TagService{
+		DB:        db,
+		TagRepo:   tagRepo,
+		Notes:     noteService,
+		Validate:  validate,
+		Audit:     auditService,
+		TagPolicy: tagPolicy,
+	}
+}
+
+// GetTags lists the tags used by the notes the actor can see, most used first.
+func (t *TagService) GetTags(actor *entity.User, req *contract.TagListRequest) ([]*contract.TagResponse, apierror.ErrorResponse) {
+	limit := defaultTagListLimit
+	if req.Limit != 0 {
+		if req.Limit < 1 || req.Limit > maxTagListLimit {
+			return nil, apierror.NewSimple(400, "Limit must be between 1 and %d", maxTagListLimit)
+		}
+		limit = req.Limit
+	}
+
+	prefix := strings.ToLower(strings.TrimSpace(req.Prefix))
+	withPrivate := actor.Permissions.HasEffective(entity.PermissionSeeHiddenNotes)
+
+	tags, err := t.TagRepo.FindAllWithUsage(prefix, actor.ID, withPrivate, limit)
+	if err != nil {
+		log.Errorf("failed to fetch tags: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	resp := make([]*contract.TagResponse, len(tags))
+	for i, tag := range tags {
+		resp[i] = &contract.TagResponse{
+			ID:        tag.ID,
+			Name:      tag.Name,
+			NoteCount: tag.NoteCount,
+		}
+	}
+	return resp, nil
+}
+
+// RenameTag renames the tag on every note using it. Renaming into an
+// existing tag is rejected, since that is what MergeTag is for.
+func (t *TagService) RenameTag(actor *entity.User, tagId int, req *contract.RenameTagRequest) apierror.ErrorResponse {
+	tag, apierr := t.fetchManageableTag(actor, tagId)
+	if apierr != nil {
+		return apierr
+	}
+
+	utils.Sanitize(req)
+	if err := t.Validate.Struct(req); err != nil {
+		return apierror.FromValidationError(err)
+	}
+
+	name := strings.ToLower(req.Name)
+	if name == tag.Name {
+		return nil
+	}
+
+	existing, err := t.TagRepo.FindByName(name)
+	if err != nil {
+		log.Errorf("failed to fetch tag %q: %v", name, err)
+		return apierror.InternalServerError
+	}
+
+	if existing != nil {
+		return apierror.NewSimple(409, "Tag '%s' already exists, merge the tags instead", name)
+	}
+
+	oldName := tag.Name
+	tag.Name = name
+
+	var changes []noteChange
+	err = t.DB.Transaction(func(tx *gorm.DB) error {
+		if err := t.TagRepo.SaveWithDB(tx, tag); err != nil {
+			return err
+		}
+
+		var err error
+		changes, err = t.rewriteTaggedNotes(tx, actor, tag.ID, func(tags []string) []string {
+			return replaceTag(tags, oldName, name)
+		})
+		if err != nil {
+			return err
+		}
+
+		var auditChanges []*entity.AuditLogChange
+		appendAuditStringChange(&auditChanges, "name", oldName, name)
+		return t.recordTagAudit(tx, actor, entity.AuditActionTagRename, tag, auditChanges)
+	})
+	var stale *staleNoteError
+	if errors.As(err, &stale) {
+		return t.Notes.notePreconditionFailed(stale.noteID)
+	}
+
+	if err != nil {
+		log.Errorf("failed to rename tag %d: %v", tag.ID, err)
+		return apierror.InternalServerError
+	}
+
+	go t.Notes.dispatchNoteChanges(changes)
+	return nil
+}
+
+// MergeTag replaces the tag by the target one on every note, then deletes it.
+func (t *TagService) MergeTag(actor *entity.User, tagId int, req *contract.MergeTagRequest) apierror.ErrorResponse {
+	if err := t.Validate.Struct(req); err != nil {
+		return apierror.FromValidationError(err)
+	}
+
+	source, apierr := t.fetchManageableTag(actor, tagId)
+	if apierr != nil {
+		return apierr
+	}
+
+	if req.TargetID == source.ID {
+		return apierror.NewSimple(400, "A tag cannot be merged into itself")
+	}
+
+	target, err := t.TagRepo.FindByID(req.TargetID)
+	if err != nil {
+		log.Errorf("failed to fetch tag %d: %v", req.TargetID, err)
+		return apierror.InternalServerError
+	}
+
+	if target == nil {
+		return apierror.NotFoundError
+	}
+
+	var changes []noteChange
+	err = t.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		changes, err = t.rewriteTaggedNotes(tx, actor, source.ID, func(tags []string) []string {
+			return replaceTag(tags, source.Name, target.Name)
+		})
+		if err != nil {
+			return err
+		}
+
+		if err = t.TagRepo.DeleteWithDB(tx, source); err != nil {
+			return err
+		}
+		return t.recordTagAudit(tx, actor, entity.AuditActionTagMerge, source, []*entity.AuditLogChange{
+			newAuditDeleteValue("name", entity.AuditValueTypeString, source.Name),
+			newAuditCreateValue("merged_into_id", entity.AuditValueTypeInt, strconv.Itoa(target.ID)),
+		})
+	})
+	var stale *staleNoteError
+	if errors.As(err, &stale) {
+		return t.Notes.notePreconditionFailed(stale.noteID)
+	}
+
+	if err != nil {
+		log.Errorf("failed to merge tag %d into %d: %v", source.ID, target.ID, err)
+		return apierror.InternalServerError
+	}
+
+	go t.Notes.dispatchNoteChanges(changes)
+	return nil
+}
+
+// DeleteTag removes the tag from every note, then deletes it.
+func (t *TagService) DeleteTag(actor *entity.User, tagId int) apierror.ErrorResponse {
+	tag, apierr := t.fetchManageableTag(actor, tagId)
+	if apierr != nil {
+		return apierr
+	}
+
+	var changes []noteChange
+	err := t.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		changes, err = t.rewriteTaggedNotes(tx, actor, tag.ID, func(tags []string) []string {
+			return slices.DeleteFunc(tags, func(name string) bool { return name == tag.Name })
+		})
+		if err != nil {
+			return err
+		}
+
+		if err = t.TagRepo.DeleteWithDB(tx, tag); err != nil {
+			return err
+		}
+		return t.recordTagAudit(tx, actor, entity.AuditActionTagDelete, tag, []*entity.AuditLogChange{
+			newAuditDeleteValue("name", entity.AuditValueTypeString, tag.Name),
+		})
+	})
+	var stale *staleNoteError
+	if errors.As(err, &stale) {
+		return t.Notes.notePreconditionFailed(stale.noteID)
+	}
+
+	if err != nil {
+		log.Errorf("failed to delete tag %d: %v", tag.ID, err)
+		return apierror.InternalServerError
+	}
+
+	go t.Notes.dispatchNoteChanges(changes)
+	return nil
+}
+
+// rewriteTaggedNotes applies 'rewrite' to the tags of every note using the tag,
+// trashed ones included. Each note gets a revision and a NOTE_UPDATE audit event,
+// as if it was updated by hand.
+func (t *TagService) rewriteTaggedNotes(tx *gorm.DB, actor *entity.User, tagID int, rewrite func([]string) []string) ([]noteChange, error) {
+	notes, err := t.Notes.NoteRepo.FindAllByTagIDWithDB(tx, tagID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := utils.NowUTC()
+	var changes []noteChange
+	for _, note := range notes {
+		fillLegacyContentHash(note)
+		before := *note
+		note.Tags = strings.Join(rewrite(toTagsArray(note.Tags)), " ")
+		note.UpdatedAt = now
+
+		change, err := t.Notes.saveRewrittenNote(tx, actor, &before, note, nil)
+		if errors.Is(err, repository.ErrStaleVersion) {
+			return nil, &staleNoteError{noteID: note.ID}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	return changes, nil
+}
+
+// staleNoteError tells which note changed while its tags were being rewritten.
+type staleNoteError struct {
+	noteID int
+}
+
+func (e *staleNoteError) Error() string {
+	return fmt.Sprintf("note %d: %v", e.noteID, repository.ErrStaleVersion)
+}
+
+func (e *staleNoteError) Unwrap() error {
+	return repository.ErrStaleVersion
+}
+
+func (t *TagService) recordTagAudit(tx *gorm.DB, actor *entity.User, action entity.AuditActionType, tag *entity.Tag, changes []*entity.AuditLogChange) error {
+	return t.Audit.Record(tx, &entity.AuditLogEvent{
+		ActorUserID: &actor.ID,
+		ActionType:  action,
+		SubjectType: entity.AuditSubjectTag,
+		SubjectID:   strconv.Itoa(tag.ID),
+		Source:      entity.AuditSourceHTTPAPI,
+		Changes:     changes,
+	})
+}
+
+func (t *TagService) fetchManageableTag(actor *entity.User, tagId int) (*entity.Tag, apierror.ErrorResponse) {
+	if apierr := t.TagPolicy.CanManage(actor); apierr != nil {
+		return nil, apierr
+	}
+
+	tag, err := t.TagRepo.FindByID(tagId)
+	if err != nil {
+		log.Errorf("failed to fetch tag %d: %v", tagId, err)
+		return nil, apierror.InternalServerError
+	}
+
+	if tag == nil {
+		return nil, apierror.NotFoundError
+	}
+	return tag, nil
+}
+
+// replaceTag swaps 'from' by 'to', dropping it instead when the note already has 'to'.
+func replaceTag(tags []string, from, to string) []string {
+	if slices.Contains(tags, to) {
+		return slices.DeleteFunc(tags, func(name string) bool { return name == from })
+	}
+
+	for i, name := range tags {
+		if name == from {
+			tags[i] = to
+		}
+	}
+	return tags
+}
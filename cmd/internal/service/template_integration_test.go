@@ -0,0 +1,132 @@
+package service
+
+import (
+	"net/http"
+	"reflect"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/policy"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplatesRequireTheirPermissionAndRenderNotes(t *testing.T) {
+	db := newTestDB(t)
+	noteSvc := newTestNoteService(t, db, 14600)
+	templateSvc := NewTemplateService(
+		db,
+		repository.NewNoteTemplateRepository(db),
+		noteSvc,
+		newTestValidator(),
+		newTestAuditService(t, db, 14700),
+		policy.NewTemplatePolicy(),
+	)
+	writer := newTestWriter(t, db)
+
+	editor := &entity.User{
+		Username:    "editor",
+		Email:       "editor@example.com",
+		Permissions: entity.PermissionManageTemplates,
+		Active:      true,
+		CreatedAt:   utils.NowUTC(),
+		UpdatedAt:   utils.NowUTC(),
+	}
+	if err := repository.NewUserRepository(db).Save(editor); err != nil {
+		t.Fatalf("save editor: %v", err)
+	}
+
+	req := &contract.TemplateRequest{
+		Name:    "Incident report",
+		Content: "# {{title}}\nReported by {{author}} on {{ date }}\n\nService: {{service}}\nImpact: {{Impact}}",
+		Tags:    []string{"Incident"},
+	}
+	if _, apierr := templateSvc.CreateTemplate(writer, req); apierr == nil || apierr.Code() != http.StatusForbidden {
+		t.Fatalf("expected forbidden without the template permission, got %#v", apierr)
+	}
+
+	template, apierr := templateSvc.CreateTemplate(editor, req)
+	if apierr != nil {
+		t.Fatalf("create template returned api error: %#v", apierr)
+	}
+	if want := []string{"service", "impact"}; !reflect.DeepEqual(template.Variables, want) {
+		t.Fatalf("expected variables %v, got %v", want, template.Variables)
+	}
+
+	req.Name = "incident REPORT"
+	if _, apierr = templateSvc.CreateTemplate(editor, req); apierr == nil || apierr.Code() != http.StatusConflict {
+		t.Fatalf("expected conflict on a taken name, got %#v", apierr)
+	}
+
+	// Every custom placeholder needs a value
+	fromTemplate := &contract.NoteFromTemplateRequest{
+		TemplateID: template.ID,
+		Name:       "Billing outage",
+		Visibility: string(entity.VisibilityPublic),
+		Variables:  map[string]string{"Service": "Billing", "author": "someone else"},
+	}
+	_, apierr = templateSvc.CreateNoteFromTemplate(writer, fromTemplate)
+	structured, ok := apierr.(*apierror.StructuredError)
+	if !ok || !strings.Contains(strings.Join(structured.Errors["variables"], ""), "impact") {
+		t.Fatalf("expected missing impact variable, got %#v", apierr)
+	}
+
+	fromTemplate.Variables["impact"] = "Invoices are late"
+	note, apierr := templateSvc.CreateNoteFromTemplate(writer, fromTemplate)
+	if apierr != nil {
+		t.Fatalf("create note from template returned api error: %#v", apierr)
+	}
+
+	date := time.Now().UTC().Format(time.DateOnly)
+	want := "# Billing outage\nReported by writer on " + date + "\n\nService: Billing\nImpact: Invoices are late"
+	if note.Content != want {
+		t.Fatalf("expected content %q, got %q", want, note.Content)
+	}
+	if note.NoteType != string(entity.NoteTypeMarkdown) || !reflect.DeepEqual(note.Tags, []string{"incident"}) {
+		t.Fatalf("expected a markdown note with the template tags, got %s %v", note.NoteType, note.Tags)
+	}
+
+	content := "# {{title}}\n{{summary}}"
+	updated, apierr := templateSvc.UpdateTemplate(editor, template.ID, &contract.UpdateTemplateRequest{Content: &content})
+	if apierr != nil {
+		t.Fatalf("update template returned api error: %#v", apierr)
+	}
+	if !reflect.DeepEqual(updated.Variables, []string{"summary"}) {
+		t.Fatalf("expected the summary variable, got %v", updated.Variables)
+	}
+
+	if apierr = templateSvc.DeleteTemplate(editor, template.ID); apierr != nil {
+		t.Fatalf("delete template returned api error: %#v", apierr)
+	}
+	if _, apierr = templateSvc.GetTemplateByID(writer, template.ID); apierr == nil || apierr.Code() != http.StatusNotFound {
+		t.Fatalf("expected deleted template to be gone, got %#v", apierr)
+	}
+
+	subjectType := entity.AuditSubjectTemplate
+	subjectID := strconv.Itoa(template.ID)
+	events, err := repository.NewAuditRepository(db).List(&repository.AuditLogFilter{
+		Limit:       10,
+		SubjectType: &subjectType,
+		SubjectID:   &subjectID,
+	})
+	if err != nil {
+		t.Fatalf("list audit events: %v", err)
+	}
+
+	var actions []entity.AuditActionType
+	for _, event := range events {
+		actions = append(actions, event.ActionType)
+	}
+	wantActions := []entity.AuditActionType{
+		entity.AuditActionTemplateDelete,
+		entity.AuditActionTemplateUpdate,
+		entity.AuditActionTemplateCreate,
+	}
+	if !reflect.DeepEqual(actions, wantActions) {
+		t.Fatalf("expected audit actions %v, got %v", wantActions, actions)
+	}
+}
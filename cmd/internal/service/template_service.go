@@ -0,0 +1,327 @@
+package service
+
+import (
+	"net/http"
+	"simplenotes/cmd/internal/contract"
+	"simplenotes/cmd/internal/domain/entity"
+	"simplenotes/cmd/internal/domain/policy"
+	"simplenotes/cmd/internal/utils"
+	"simplenotes/cmd/internal/utils/apierror"
+	"simplenotes/cmd/internal/utils/placeholders"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+// Built-in placeholders, filled in by the server when a note is created.
+// Callers cannot override them.
+const (
+	placeholderTitle  = "title"  // Name of the new note
+	placeholderDate   = "date"   // Current UTC date, 2006-01-02
+	placeholderTime   = "time"   // Current UTC time, 15:04
+	placeholderAuthor = "author" // Username of the creator
+)
+
+var builtinPlaceholders = []string{placeholderTitle, placeholderDate, placeholderTime, placeholderAuthor}
+
+type TemplateRepository interface {
+	FindAll() ([]*entity.NoteTemplate, error)
+	FindByID(id int) (*entity.NoteTemplate, error)
+	FindByName(name string) (*entity.NoteTemplate, error)
+	SaveWithDB(db *gorm.DB, template *entity.NoteTemplate) error
+	DeleteWithDB(db *gorm.DB, template *entity.NoteTemplate) error
+}
+
+type TemplateService struct {
+	DB             *gorm.DB
+	TemplateRepo   TemplateRepository
+	Notes          *NoteService
+	Validate       *validator.Validate
+	Audit          *AuditService
+	TemplatePolicy *policy.TemplatePolicy
+}
+
+func NewTemplateService(
+	db *gorm.DB,
+	templateRepo TemplateRepository,
+	noteService *NoteService,
+	validate *validator.Validate,
+	auditService *AuditService,
+	templatePolicy *policy.TemplatePolicy,
+) *TemplateService {
+	return &TemplateService{
+		DB:             db,
+		TemplateRepo:   templateRepo,
+		Notes:          noteService,
+		Validate:       validate,
+		Audit:          auditService,
+		TemplatePolicy: templatePolicy,
+	}
+}
+
+func (t *TemplateService) GetTemplates(actor *entity.User) ([]*contract.TemplateResponse, apierror.ErrorResponse) {
+	templates, err := t.TemplateRepo.FindAll()
+	if err != nil {
+		log.Errorf("failed to fetch templates: %v", err)
+		return nil, apierror.InternalServerError
+	}
+
+	resp := make([]*contract.TemplateResponse, len(templates))
+	for i, template := range templates {
+		resp[i] = toTemplateResponse(template)
+	}
+	return resp, nil
+}
+
+func (t *TemplateService) GetTemplateByID(actor *entity.User, templateId int) (*contract.TemplateResponse, apierror.ErrorResponse) {
+	template, apierr := t.fetchTemplate(templateId)
+	if apierr != nil {
+		return nil, apierr
+	}
+	return toTemplateResponse(template), nil
+}
+
+func (t *TemplateService) CreateTemplate(actor *entity.User, req *contract.TemplateRequest) (*contract.TemplateResponse, apierror.ErrorResponse) {
+	if apierr := t.TemplatePolicy.CanManage(actor); apierr != nil {
+		return nil, apierr
+	}
+
+	utils.Sanitize(req)
+	if err := t.Validate.Struct(req); err != nil {
+		return nil, apierror.FromValidationError(err)
+	}
+
+	if apierr := t.checkTemplateName(req.Name, 0); apierr != nil {
+		return nil, apierr
+	}
+
+	now := utils.NowUTC()
+	template := &entity.NoteTemplate{
+		Name:        req.Name,
+		Description: req.Description,
+		Content:     req.Content,
+		Tags:        strings.ToLower(strings.Join(req.Tags, " ")),
+		CreatedByID: actor.ID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err := t.DB.Transaction(func(tx *gorm.DB) error {
+		if err := t.TemplateRepo.SaveWithDB(tx, template); err != nil {
+			return err
+		}
+
+		changes := []*entity.AuditLogChange{
+			newAuditCreateValue("name", entity.AuditValueTypeString, template.Name),
+			newAuditCreateValue("description", entity.AuditValueTypeString, template.Description),
+			newAuditCreateValue("tags", entity.AuditValueTypeStringArray, auditJSONString(toTagsArray(template.Tags))),
+			newAuditCreateValue("content_size", entity.AuditValueTypeInt, strconv.Itoa(len(template.Content))),
+			newAuditCreateValue("content_hash", entity.AuditValueTypeString, contentHash([]byte(template.Content))),
+		}
+		return t.recordTemplateAudit(tx, actor, entity.AuditActionTemplateCreate, template, changes)
+	})
+	if err != nil {
+		log.Errorf("failed to create template: %v", err)
+		return nil, apierror.InternalServerError
+	}
+	return toTemplateResponse(template), nil
+}
+
+func (t *TemplateService) UpdateTemplate(actor *entity.User, templateId int, req *contract.UpdateTemplateRequest) (*contract.TemplateResponse, apierror.ErrorResponse) {
+	if req.IsEmpty() {
+		return nil, apierror.EmptyPatchCallError
+	}
+
+	if apierr := t.TemplatePolicy.CanManage(actor); apierr != nil {
+		return nil, apierr
+	}
+
+	template, apierr := t.fetchTemplate(templateId)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	utils.Sanitize(req)
+	if err := t.Validate.Struct(req); err != nil {
+		return nil, apierror.FromValidationError(err)
+	}
+
+	before := *template
+	if req.Name != nil {
+		if apierr = t.checkTemplateName(*req.Name, template.ID); apierr != nil {
+			return nil, apierr
+		}
+		template.Name = *req.Name
+	}
+	if req.Description != nil {
+		template.Description = *req.Description
+	}
+	if req.Content != nil {
+		template.Content = *req.Content
+	}
+	if req.Tags != nil {
+		template.Tags = strings.ToLower(strings.Join(req.Tags, " "))
+	}
+
+	var changes []*entity.AuditLogChange
+	appendAuditStringChange(&changes, "name", before.Name, template.Name)
+	appendAuditStringChange(&changes, "description", before.Description, template.Description)
+	appendAuditStringArrayChange(&changes, "tags", toTagsArray(before.Tags), toTagsArray(template.Tags))
+	appendAuditIntChange(&changes, "content_size", int64(len(before.Content)), int64(len(template.Content)))
+	appendAuditStringChange(&changes, "content_hash", contentHash([]byte(before.Content)), contentHash([]byte(template.Content)))
+	if len(changes) == 0 {
+		return toTemplateResponse(template), nil
+	}
+	template.UpdatedAt = utils.NowUTC()
+
+	err := t.DB.Transaction(func(tx *gorm.DB) error {
+		if err := t.TemplateRepo.SaveWithDB(tx, template); err != nil {
+			return err
+		}
+		return t.recordTemplateAudit(tx, actor, entity.AuditActionTemplateUpdate, template, changes)
+	})
+	if err != nil {
+		log.Errorf("failed to update template %d: %v", template.ID, err)
+		return nil, apierror.InternalServerError
+	}
+	return toTemplateResponse(template), nil
+}
+
+// DeleteTemplate removes the template. Notes created from it are left as they are.
+func (t *TemplateService) DeleteTemplate(actor *entity.User, templateId int) apierror.ErrorResponse {
+	if apierr := t.TemplatePolicy.CanManage(actor); apierr != nil {
+		return apierr
+	}
+
+	template, apierr := t.fetchTemplate(templateId)
+	if apierr != nil {
+		return apierr
+	}
+
+	err := t.DB.Transaction(func(tx *gorm.DB) error {
+		if err := t.TemplateRepo.DeleteWithDB(tx, template); err != nil {
+			return err
+		}
+
+		changes := []*entity.AuditLogChange{
+			newAuditDeleteValue("name", entity.AuditValueTypeString, template.Name),
+		}
+		return t.recordTemplateAudit(tx, actor, entity.AuditActionTemplateDelete, template, changes)
+	})
+	if err != nil {
+		log.Errorf("failed to delete template %d: %v", template.ID, err)
+		return apierror.InternalServerError
+	}
+	return nil
+}
+
+// CreateNoteFromTemplate fills in the placeholders of the template, then creates
+// the MARKDOWN note like POST /notes would. Every custom placeholder needs a value.
+func (t *TemplateService) CreateNoteFromTemplate(actor *entity.User, req *contract.NoteFromTemplateRequest) (*contract.NoteResponse, apierror.ErrorResponse) {
+	if !actor.Permissions.HasEffective(entity.PermissionCreateNotes) {
+		return nil, apierror.UserMissingPermsError
+	}
+
+	utils.Sanitize(req)
+	if err := t.Validate.Struct(req); err != nil {
+		return nil, apierror.FromValidationError(err)
+	}
+
+	template, apierr := t.fetchTemplate(req.TemplateID)
+	if apierr != nil {
+		return nil, apierr
+	}
+
+	values := make(map[string]string, len(req.Variables)+len(builtinPlaceholders))
+	for name, value := range req.Variables {
+		values[strings.ToLower(name)] = value
+	}
+
+	now := time.UnixMilli(utils.NowUTC()).UTC()
+	values[placeholderTitle] = req.Name
+	values[placeholderDate] = now.Format(time.DateOnly)
+	values[placeholderTime] = now.Format("15:04")
+	values[placeholderAuthor] = actor.Username
+
+	content, missing := placeholders.Render(template.Content, values)
+	if len(missing) > 0 {
+		apierr := apierror.NewStructured(http.StatusBadRequest)
+		apierr.Add("variables", "Missing values for: "+strings.Join(missing, ", "))
+		return nil, apierr
+	}
+
+	tags := req.Tags
+	if tags == nil {
+		tags = toTagsArray(template.Tags)
+	}
+
+	return t.Notes.CreateTextNote(actor, &contract.TextNoteRequest{
+		Name:       req.Name,
+		Content:    content,
+		NoteType:   string(entity.NoteTypeMarkdown),
+		Visibility: req.Visibility,
+		Tags:       tags,
+		FolderID:   req.FolderID,
+	})
+}
+
+// checkTemplateName rejects names already used by another template, ignoring case.
+func (t *TemplateService) checkTemplateName(name string, templateId int) apierror.ErrorResponse {
+	existing, err := t.TemplateRepo.FindByName(name)
+	if err != nil {
+		log.Errorf("failed to fetch template %q: %v", name, err)
+		return apierror.InternalServerError
+	}
+
+	if existing != nil && existing.ID != templateId {
+		return apierror.NewSimple(http.StatusConflict, "Template '%s' already exists", existing.Name)
+	}
+	return nil
+}
+
+func (t *TemplateService) fetchTemplate(templateId int) (*entity.NoteTemplate, apierror.ErrorResponse) {
+	template, err := t.TemplateRepo.FindByID(templateId)
+	if err != nil {
+		log.Errorf("failed to fetch template %d: %v", templateId, err)
+		return nil, apierror.InternalServerError
+	}
+
+	if template == nil {
+		return nil, apierror.NotFoundError
+	}
+	return template, nil
+}
+
+func (t *TemplateService) recordTemplateAudit(tx *gorm.DB, actor *entity.User, action entity.AuditActionType, template *entity.NoteTemplate, changes []*entity.AuditLogChange) error {
+	return t.Audit.Record(tx, &entity.AuditLogEvent{
+		ActorUserID: &actor.ID,
+		ActionType:  action,
+		SubjectType: entity.AuditSubjectTemplate,
+		SubjectID:   strconv.Itoa(template.ID),
+		Source:      entity.AuditSourceHTTPAPI,
+		Changes:     changes,
+	})
+}
+
+func toTemplateResponse(template *entity.NoteTemplate) *contract.TemplateResponse {
+	variables := slices.DeleteFunc(placeholders.Names(template.Content), func(name string) bool {
+		return slices.Contains(builtinPlaceholders, name)
+	})
+
+	return &contract.TemplateResponse{
+		ID:          template.ID,
+		Name:        template.Name,
+		Description: template.Description,
+		Content:     template.Content,
+		Tags:        toTagsArray(template.Tags),
+		Variables:   variables,
+		CreatedByID: template.CreatedByID,
+		CreatedAt:   utils.FormatEpoch(template.CreatedAt),
+		UpdatedAt:   utils.FormatEpoch(template.UpdatedAt),
+	}
+}
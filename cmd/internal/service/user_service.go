@@ -2,10 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
 	"simplenotes/cmd/internal/contract"
 	"simplenotes/cmd/internal/domain/entity"
 	"simplenotes/cmd/internal/domain/events"
 	"simplenotes/cmd/internal/domain/policy"
+	"simplenotes/cmd/internal/domain/sqlite/repository"
 	cognitoclient "simplenotes/cmd/internal/infrastructure/aws/cognito"
 	"simplenotes/cmd/internal/utils"
 	"simplenotes/cmd/internal/utils/apierror"
@@ -98,7 +100,8 @@ func (u *UserService) GetUser(actor *entity.User, rawId string) (*contract.UserR
 	return resp, nil
 }
 
-func (u *UserService) UpdateUser(actor *entity.User, targetId string, req *contract.UpdateUserRequest) (*contract.UserResponse, apierror.ErrorResponse) {
+// UpdateUser applies the PATCH request, as long as the user still matches 'pre'.
+func (u *UserService) UpdateUser(actor *entity.User, targetId string, req *contract.UpdateUserRequest, pre *contract.Precondition) (*contract.UserResponse, apierror.ErrorResponse) {
 	if req.IsEmpty() {
 		return nil, apierror.EmptyPatchCallError
 	}
@@ -117,6 +120,10 @@ func (u *UserService) UpdateUser(actor *entity.User, targetId string, req *contr
 		return nil, apierror.NotFoundError
 	}
 
+	if !pre.Matches(target.Version) {
+		return nil, u.userPreconditionFailed(actor, target.ID)
+	}
+
 	updater := &userUpdater{
 		actor:  actor,
 		target: target,
@@ -152,6 +159,9 @@ func (u *UserService) UpdateUser(actor *entity.User, targetId string, req *contr
 				Changes:     changes,
 			})
 		}); err != nil {
+			if errors.Is(err, repository.ErrStaleVersion) {
+				return nil, u.userPreconditionFailed(actor, target.ID)
+			}
 			log.Errorf("actor %s failed to update user %s: %v", actor.SubUUID, targetId, err)
 			return nil, apierror.InternalServerError
 		}
@@ -167,7 +177,8 @@ func (u *UserService) UpdateUser(actor *entity.User, targetId string, req *contr
 	return toUserResponse(target, actor, presence), nil
 }
 
-func (u *UserService) DeleteUser(actor *entity.User, targetRawID string) apierror.ErrorResponse {
+// DeleteUser deletes the user, as long as they still match 'pre'.
+func (u *UserService) DeleteUser(actor *entity.User, targetRawID string, pre *contract.Precondition) apierror.ErrorResponse {
 	target, err := u.fetchByID(targetRawID, false)
 	if err != nil {
 		log.Errorf("failed to fetch user by ID %s: %v", targetRawID, err)
@@ -182,6 +193,10 @@ func (u *UserService) DeleteUser(actor *entity.User, targetRawID string) apierro
 		return perr
 	}
 
+	if !pre.Matches(target.Version) {
+		return u.userPreconditionFailed(actor, target.ID)
+	}
+
 	cerr := u.Cognito.AdminDeleteUser(target.Email)
 	if cerr != nil {
 		log.Errorf("failed to delete user %d from cognito: %v", target.ID, cerr)
@@ -426,6 +441,27 @@ func (u *UserService) fetchUser(requester *entity.User, rawId string, force bool
 	return u.fetchByID(rawId, force)
 }
 
+// userPreconditionFailed reports the current state of a user
+// which does not match the If-Match header of the request anymore.
+func (u *UserService) userPreconditionFailed(actor *entity.User, userId int) apierror.ErrorResponse {
+	current, err := u.UserRepo.FindActiveByID(userId)
+	if err != nil {
+		log.Errorf("failed to find user (%d) by id: %v", userId, err)
+		return apierror.InternalServerError
+	}
+
+	if current == nil {
+		return apierror.NotFoundError
+	}
+
+	presence := contract.PresenceOffline
+	isOnline, _ := u.WSService.ConnRepo.IsOnline(current.ID)
+	if isOnline {
+		presence = contract.PresenceOnline
+	}
+	return apierror.NewPreconditionFailedError(toUserResponse(current, actor, presence), current.Version)
+}
+
 func (u *UserService) fetchBySub(sub string) (*entity.User, apierror.ErrorResponse) {
 	user, err := u.UserRepo.FindActiveBySub(sub)
 	if err != nil {
@@ -565,6 +601,7 @@ func toUserResponse(user, requester *entity.User, presence contract.UserPresence
 		Presence:  presence,
 		CreatedAt: utils.FormatEpoch(user.CreatedAt),
 		UpdatedAt: utils.FormatEpoch(user.UpdatedAt),
+		Version:   user.Version,
 	}
 
 	hasMngUsers := requester.Permissions.HasEffective(entity.PermissionManageUsers)
@@ -171,6 +171,21 @@ func (s *WebSocketService) BroadcastSupplier(ctx context.Context, supplier func(
 	}
 }
 
+// BroadcastPerUser works like BroadcastSupplier, but the supplier runs only
+// once per user, no matter how many connections they have open.
+func (s *WebSocketService) BroadcastPerUser(ctx context.Context, supplier func(userID int) events.SocketEvent) {
+	resolved := make(map[int]events.SocketEvent)
+	s.BroadcastSupplier(ctx, func(userID int) events.SocketEvent {
+		if evt, ok := resolved[userID]; ok {
+			return evt
+		}
+
+		evt := supplier(userID)
+		resolved[userID] = evt
+		return evt
+	})
+}
+
 func (s *WebSocketService) dispatchPresenceEvent(userID int, presence contract.UserPresence) {
 	s.Broadcast(context.Background(), &events.PresenceUpdated{
 		UserID:   userID,
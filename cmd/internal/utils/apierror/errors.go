@@ -41,6 +41,19 @@ func (s *StructuredError) Add(field, problem string) {
 	s.Errors[field] = append(s.Errors[field], problem)
 }
 
+// PreconditionFailedError is returned when the If-Match header of a request
+// does not match the current version of a resource. The current state is
+// sent back, so clients can merge their changes without another request.
+type PreconditionFailedError struct {
+	Message string `json:"message"`
+	Current any    `json:"current"`
+	Version int    `json:"-"`
+}
+
+func (p *PreconditionFailedError) Code() int {
+	return http.StatusPreconditionFailed
+}
+
 var (
 	MalformedBodyError  = NewSimple(400, "Malformed form body")
 	InternalServerError = NewSimple(500, "Internal server error")
@@ -56,6 +69,16 @@ var (
 	MissingFileNameError  = NewSimple(400, "File name is required")
 	InvalidMediaTypeError = NewSimple(415, "Unsupported media type. Use application/json or multipart/form-data")
 
+	ReferenceContentUpdateError = NewSimple(400, "Content of REFERENCE notes can only be replaced by uploading a new file")
+	NoteNotReferenceError       = NewSimple(400, "Only REFERENCE notes have a file to be replaced")
+	NoteWithoutFileError        = NewSimple(400, "Only REFERENCE notes have a file to download")
+	NoteNotMarkdownError        = NewSimple(400, "Only MARKDOWN notes can be rendered")
+	PrivateFolderContentError   = NewSimple(400, "Content of a private folder must be private")
+	FolderCycleError            = NewSimple(400, "A folder cannot be moved inside itself")
+	FolderForeignNotesError     = NewSimple(403, "The folder holds notes you cannot make private")
+	NoteTooManyTagsError        = NewSimple(400, "A note cannot have more than 50 tags")
+	NoteBulkConflictError       = NewSimple(409, "A note was modified while the operation ran, nothing was changed")
+
 	/*
 	 * Used for authentications
 	 */
@@ -125,6 +148,14 @@ func NewSimple(status int, msg string, args ...any) *APIError {
 	return &APIError{Status: status, Message: msg}
 }
 
+func NewPreconditionFailedError(current any, version int) *PreconditionFailedError {
+	return &PreconditionFailedError{
+		Message: "Resource was modified by someone else",
+		Current: current,
+		Version: version,
+	}
+}
+
 func NewStructured(code int) *StructuredError {
 	return &StructuredError{
 		Errors: make(map[string][]string),
@@ -148,6 +179,10 @@ func NewInvalidFileExtError(ext string) *APIError {
 	return NewSimple(http.StatusBadRequest, "Invalid file extension: %s", ext)
 }
 
+func NewFileContentMismatchError(ext string) *APIError {
+	return NewSimple(http.StatusBadRequest, "File content does not match its extension: %s", ext)
+}
+
 func NewPermissionError(bitmask int64) *APIError {
 	return NewSimple(http.StatusForbidden, "Missing permissions: %d", bitmask)
 }
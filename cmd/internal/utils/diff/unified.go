@@ -0,0 +1,244 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each hunk,
+// the same default used by `diff -u`.
+const DefaultContext = 3
+
+// maxEditDistance caps how many line edits the Myers search explores.
+// Beyond that, the differing region is reported as a full replacement,
+// which keeps memory bounded for notes close to the 1M characters limit.
+const maxEditDistance = 1000
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type edit struct {
+	kind opKind
+	text string
+}
+
+// Unified returns the unified diff between the texts 'a' and 'b', labeled
+// with 'fromName' and 'toName'. It returns an empty string if both texts
+// are equal.
+func Unified(fromName, toName, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+
+	edits := lineEdits(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	writeHunks(&sb, edits, context)
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineEdits computes the shortest edit script between 'a' and 'b'.
+// Common prefixes and suffixes are trimmed before running Myers' algorithm.
+func lineEdits(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]edit, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		edits = append(edits, edit{kind: opEqual, text: line})
+	}
+
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, edit{kind: opEqual, text: line})
+	}
+	return edits
+}
+
+func myers(a, b []string) []edit {
+	n, m := len(a), len(b)
+	maxD := n + m
+	if maxD > maxEditDistance {
+		maxD = maxEditDistance
+	}
+
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// trace[d] holds the furthest reaching x for every diagonal k in
+	// [-d-1, d+1] before step d, indexed by k+d+1.
+	var trace [][]int
+
+	for d := 0; d <= maxD; d++ {
+		snapshot := make([]int, 2*d+3)
+		copy(snapshot, v[offset-d-1:offset+d+2])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	return replaceAll(a, b)
+}
+
+func backtrack(trace [][]int, a, b []string) []edit {
+	x, y := len(a), len(b)
+	var reversed []edit
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, edit{kind: opEqual, text: a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, edit{kind: opInsert, text: b[y-1]})
+			} else {
+				reversed = append(reversed, edit{kind: opDelete, text: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	edits := make([]edit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits
+}
+
+func replaceAll(a, b []string) []edit {
+	edits := make([]edit, 0, len(a)+len(b))
+	for _, line := range a {
+		edits = append(edits, edit{kind: opDelete, text: line})
+	}
+	for _, line := range b {
+		edits = append(edits, edit{kind: opInsert, text: line})
+	}
+	return edits
+}
+
+func writeHunks(sb *strings.Builder, edits []edit, context int) {
+	// aPos[i] and bPos[i] are the 0-based line positions right before edits[i]
+	aPos := make([]int, len(edits)+1)
+	bPos := make([]int, len(edits)+1)
+	for i, e := range edits {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if e.kind != opInsert {
+			aPos[i+1]++
+		}
+		if e.kind != opDelete {
+			bPos[i+1]++
+		}
+	}
+
+	i := 0
+	for i < len(edits) {
+		if edits[i].kind == opEqual {
+			i++
+			continue
+		}
+
+		start := max(i-context, 0)
+		end := i
+		// Extend the hunk while the next change is close enough to share context
+		for j := i; j < len(edits); j++ {
+			if edits[j].kind == opEqual {
+				continue
+			}
+			if j-end > 2*context {
+				break
+			}
+			end = j + 1
+		}
+		end = min(end+context, len(edits))
+
+		writeHunk(sb, edits[start:end], aPos[start], bPos[start], aPos[end]-aPos[start], bPos[end]-bPos[start])
+		i = end
+	}
+}
+
+func writeHunk(sb *strings.Builder, edits []edit, aStart, bStart, aCount, bCount int) {
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, e := range edits {
+		switch e.kind {
+		case opEqual:
+			sb.WriteByte(' ')
+		case opDelete:
+			sb.WriteByte('-')
+		case opInsert:
+			sb.WriteByte('+')
+		}
+		sb.WriteString(e.text)
+		sb.WriteByte('\n')
+	}
+}
+
+func hunkRange(start, count int) string {
+	// Empty ranges point at the line right before the hunk
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,52 @@
+package diff
+
+import "testing"
+
+func TestUnifiedReturnsEmptyForEqualTexts(t *testing.T) {
+	if got := Unified("a", "b", "same\ntext\n", "same\ntext\n", DefaultContext); got != "" {
+		t.Fatalf("expected empty diff, got %q", got)
+	}
+}
+
+func TestUnifiedProducesSingleHunk(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\n"
+	b := "one\n2\nthree\nfour\nfive\n"
+
+	want := "--- revision 1\n" +
+		"+++ revision 2\n" +
+		"@@ -1,4 +1,5 @@\n" +
+		" one\n" +
+		"-two\n" +
+		"+2\n" +
+		" three\n" +
+		" four\n" +
+		"+five\n"
+
+	if got := Unified("revision 1", "revision 2", a, b, DefaultContext); got != want {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+}
+
+func TestUnifiedSplitsDistantChanges(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	b := "A\nb\nc\nd\ne\nf\ng\nh\ni\nJ\n"
+
+	want := "--- x\n" +
+		"+++ y\n" +
+		"@@ -1,4 +1,4 @@\n" +
+		"-a\n" +
+		"+A\n" +
+		" b\n" +
+		" c\n" +
+		" d\n" +
+		"@@ -7,4 +7,4 @@\n" +
+		" g\n" +
+		" h\n" +
+		" i\n" +
+		"-j\n" +
+		"+J\n"
+
+	if got := Unified("x", "y", a, b, DefaultContext); got != want {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+)
+
+// FileSignatureLen is how many leading bytes SniffFileType needs to look at.
+const FileSignatureLen = 12
+
+// fileSignature recognizes a file format from its first bytes.
+type fileSignature struct {
+	mimeType string
+	matches  func(head []byte) bool
+}
+
+var fileSignatures = map[string]fileSignature{
+	"pdf":  {"application/pdf", hasPrefix("%PDF-")},
+	"png":  {"image/png", hasPrefix("\x89PNG\r\n\x1a\n")},
+	"jpg":  {"image/jpeg", hasPrefix("\xff\xd8\xff")},
+	"jpeg": {"image/jpeg", hasPrefix("\xff\xd8\xff")},
+	"jfif": {"image/jpeg", hasPrefix("\xff\xd8\xff")},
+	"gif":  {"image/gif", isGIF},
+	"webp": {"image/webp", isWebP},
+	"mp4":  {"video/mp4", isMP4},
+	"mp3":  {"audio/mpeg", isMP3},
+}
+
+// SniffFileType checks 'head', the first bytes of a file, against the signature
+// of the format its extension claims. It returns the MIME type of that format,
+// and false when the bytes do not match or the extension is unknown.
+func SniffFileType(ext string, head []byte) (string, bool) {
+	signature, ok := fileSignatures[strings.ToLower(strings.TrimPrefix(ext, "."))]
+	if !ok || !signature.matches(head) {
+		return "", false
+	}
+	return signature.mimeType, true
+}
+
+func hasPrefix(magic string) func([]byte) bool {
+	return func(head []byte) bool {
+		return bytes.HasPrefix(head, []byte(magic))
+	}
+}
+
+func isGIF(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("GIF87a")) || bytes.HasPrefix(head, []byte("GIF89a"))
+}
+
+// isWebP matches a RIFF container holding a WEBP payload.
+func isWebP(head []byte) bool {
+	return len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WEBP"))
+}
+
+// isMP4 matches the `ftyp` box every ISO media file starts with, after its size.
+func isMP4(head []byte) bool {
+	return len(head) >= 8 && bytes.Equal(head[4:8], []byte("ftyp"))
+}
+
+// isMP3 matches an ID3 tag or, for untagged files, an MPEG audio frame sync.
+func isMP3(head []byte) bool {
+	if bytes.HasPrefix(head, []byte("ID3")) {
+		return true
+	}
+	return len(head) >= 2 && head[0] == 0xff && head[1]&0xe0 == 0xe0
+}
@@ -0,0 +1,190 @@
+// Package flowchart defines the JSON document stored as the content of FLOWCHART
+// notes, and checks documents against it before they are saved.
+//
+//	{
+//	  "nodes": [{"id": "start", "label": "Start", "position": {"x": 0, "y": 0}}],
+//	  "edges": [{"id": "e1", "source": "start", "target": "end", "label": "next"}]
+//	}
+//
+// Unknown fields are kept as they are, so clients can store their own styling.
+package flowchart
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxNodes       = 500
+	MaxEdges       = 2000
+	MaxIDLength    = 64
+	MaxLabelLength = 500
+	MaxCoordinate  = 1_000_000 // Positions are kept within this distance of the origin, on both axes
+)
+
+type Chart struct {
+	Nodes []*Node `json:"nodes"`
+	Edges []*Edge `json:"edges"`
+}
+
+type Node struct {
+	ID       string    `json:"id"`
+	Label    string    `json:"label"`
+	Position *Position `json:"position"`
+}
+
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type Edge struct {
+	ID     string `json:"id"`
+	Source string `json:"source"` // ID of the node the edge starts from
+	Target string `json:"target"` // ID of the node the edge points to
+	Label  string `json:"label"`
+}
+
+// Problem is a rule broken by a document. Field is the JSON path of the
+// offending value, such as "nodes[2].id", and is empty for the whole document.
+type Problem struct {
+	Field   string
+	Message string
+}
+
+// Validate lists every problem of the document in 'content', in document order.
+// A valid document has none.
+func Validate(content string) []Problem {
+	var chart Chart
+	if problem := decode(content, &chart); problem != nil {
+		return []Problem{*problem}
+	}
+
+	var problems []Problem
+	add := func(field, message string) {
+		problems = append(problems, Problem{Field: field, Message: message})
+	}
+
+	if chart.Nodes == nil {
+		add("nodes", "This field is required")
+	}
+	if len(chart.Nodes) > MaxNodes {
+		add("nodes", "Value is too long, max: "+strconv.Itoa(MaxNodes))
+		return problems
+	}
+	if len(chart.Edges) > MaxEdges {
+		add("edges", "Value is too long, max: "+strconv.Itoa(MaxEdges))
+		return problems
+	}
+
+	nodeIDs := make(map[string]bool, len(chart.Nodes))
+	for i, node := range chart.Nodes {
+		field := fmt.Sprintf("nodes[%d]", i)
+		if node == nil {
+			add(field, "Value must be an object")
+			continue
+		}
+
+		checkID(add, field+".id", node.ID, nodeIDs)
+		checkLabel(add, field+".label", node.Label)
+
+		switch {
+		case node.Position == nil:
+			add(field+".position", "This field is required")
+		case math.Abs(node.Position.X) > MaxCoordinate || math.Abs(node.Position.Y) > MaxCoordinate:
+			add(field+".position", "Value is out of range, max: "+strconv.Itoa(MaxCoordinate))
+		}
+	}
+
+	edgeIDs := make(map[string]bool, len(chart.Edges))
+	for i, edge := range chart.Edges {
+		field := fmt.Sprintf("edges[%d]", i)
+		if edge == nil {
+			add(field, "Value must be an object")
+			continue
+		}
+
+		checkID(add, field+".id", edge.ID, edgeIDs)
+		checkLabel(add, field+".label", edge.Label)
+		checkEnd(add, field+".source", edge.Source, nodeIDs)
+		checkEnd(add, field+".target", edge.Target, nodeIDs)
+	}
+	return problems
+}
+
+func decode(content string, chart *Chart) *Problem {
+	err := json.Unmarshal([]byte(content), chart)
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &Problem{Field: fieldPath(typeErr.Field), Message: "Value must be of type " + typeName(typeErr.Type)}
+	}
+	return &Problem{Message: "Value must be a JSON object with nodes and edges"}
+}
+
+// fieldPath turns the dotted path of the decoder ("nodes.0.id") into the
+// notation used by the other problems ("nodes[0].id").
+func fieldPath(dotted string) string {
+	var b strings.Builder
+	for i, part := range strings.Split(dotted, ".") {
+		if _, err := strconv.Atoi(part); err == nil {
+			b.WriteString("[" + part + "]")
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Slice:
+		return "array"
+	case reflect.Pointer, reflect.Struct:
+		return "object"
+	case reflect.Float64:
+		return "number"
+	default:
+		return t.Kind().String()
+	}
+}
+
+func checkID(add func(field, message string), field, id string, seen map[string]bool) {
+	switch {
+	case id == "":
+		add(field, "This field is required")
+	case len(id) > MaxIDLength:
+		add(field, "Value is too long, max: "+strconv.Itoa(MaxIDLength))
+	case seen[id]:
+		add(field, "Value must be unique")
+	default:
+		seen[id] = true
+	}
+}
+
+func checkLabel(add func(field, message string), field, label string) {
+	if utf8.RuneCountInString(label) > MaxLabelLength {
+		add(field, "Value is too long, max: "+strconv.Itoa(MaxLabelLength))
+	}
+}
+
+func checkEnd(add func(field, message string), field, nodeID string, nodeIDs map[string]bool) {
+	switch {
+	case nodeID == "":
+		add(field, "This field is required")
+	case !nodeIDs[nodeID]:
+		add(field, "Value must be the id of a node")
+	}
+}
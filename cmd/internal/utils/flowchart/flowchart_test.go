@@ -0,0 +1,84 @@
+package flowchart
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateAcceptsCharts(t *testing.T) {
+	for _, content := range []string{
+		`{"nodes": []}`,
+		`{
+			"nodes": [
+				{"id": "start", "label": "Start", "position": {"x": 0, "y": 0}, "color": "#fff"},
+				{"id": "end", "position": {"x": 120.5, "y": -40}}
+			],
+			"edges": [{"id": "e1", "source": "start", "target": "end", "label": "done"}],
+			"viewport": {"zoom": 2}
+		}`,
+	} {
+		if problems := Validate(content); len(problems) != 0 {
+			t.Fatalf("expected %s to be valid, got %#v", content, problems)
+		}
+	}
+}
+
+func TestValidateReportsProblemsPerField(t *testing.T) {
+	content := `{
+		"nodes": [
+			{"id": "a", "label": "A", "position": {"x": 0, "y": 0}},
+			{"id": "a", "position": {"x": 5000000, "y": 0}},
+			{"label": "No id"}
+		],
+		"edges": [
+			{"id": "e1", "source": "a", "target": "ghost"},
+			{"id": "e1", "target": "a"}
+		]
+	}`
+
+	want := []Problem{
+		{Field: "nodes[1].id", Message: "Value must be unique"},
+		{Field: "nodes[1].position", Message: "Value is out of range, max: 1000000"},
+		{Field: "nodes[2].id", Message: "This field is required"},
+		{Field: "nodes[2].position", Message: "This field is required"},
+		{Field: "edges[0].target", Message: "Value must be the id of a node"},
+		{Field: "edges[1].id", Message: "Value must be unique"},
+		{Field: "edges[1].source", Message: "This field is required"},
+	}
+	if got := Validate(content); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %#v, got %#v", want, got)
+	}
+}
+
+func TestValidateRejectsMalformedDocuments(t *testing.T) {
+	cases := map[string]Problem{
+		`not json`:               {Message: "Value must be a JSON object with nodes and edges"},
+		`[]`:                     {Message: "Value must be a JSON object with nodes and edges"},
+		`{"nodes": []} {}`:       {Message: "Value must be a JSON object with nodes and edges"},
+		`{}`:                     {Field: "nodes", Message: "This field is required"},
+		`{"nodes": "a"}`:         {Field: "nodes", Message: "Value must be of type array"},
+		`{"nodes": [{"id": 1}]}`: {Field: "nodes[0].id", Message: "Value must be of type string"},
+	}
+
+	for content, want := range cases {
+		got := Validate(content)
+		if len(got) != 1 || got[0] != want {
+			t.Fatalf("expected %#v for %s, got %#v", want, content, got)
+		}
+	}
+}
+
+func TestValidateCapsTheNodeCount(t *testing.T) {
+	nodes := make([]string, MaxNodes+1)
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf(`{"id": "n%d", "position": {"x": 0, "y": 0}}`, i)
+	}
+
+	content := `{"nodes": [` + strings.Join(nodes, ",") + `]}`
+	want := []Problem{{Field: "nodes", Message: "Value is too long, max: 500"}}
+	if got := Validate(content); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %#v, got %#v", want, got)
+	}
+}
@@ -0,0 +1,101 @@
+// Package markdown renders note content to HTML that is safe to embed:
+// CommonMark with the GitHub extensions, passed through an allowlist sanitizer.
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// Version identifies the output of Render. Bump it whenever the parser or the
+// sanitizer policy changes, so cached renders are thrown away.
+const Version = 1
+
+// Heading is an entry of the table of contents.
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	ID    string `json:"id"` // Anchor of the heading in the rendered HTML
+}
+
+var (
+	converter = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		// Raw HTML is kept here and cleaned up by the sanitizer
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	policy = newPolicy()
+)
+
+// newPolicy allows what user generated content usually needs, plus the
+// disabled checkboxes of task lists.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// Render converts 'source' to sanitized HTML and lists its headings in order.
+func Render(source string) (string, []Heading, error) {
+	src := []byte(source)
+	doc := converter.Parser().Parse(text.NewReader(src))
+
+	var out bytes.Buffer
+	if err := converter.Renderer().Render(&out, src, doc); err != nil {
+		return "", nil, err
+	}
+
+	return policy.Sanitize(out.String()), tableOfContents(doc, src), nil
+}
+
+func tableOfContents(doc ast.Node, source []byte) []Heading {
+	headings := []Heading{}
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := node.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+
+		id, _ := heading.AttributeString("id")
+		idBytes, _ := id.([]byte)
+		headings = append(headings, Heading{
+			Level: heading.Level,
+			Text:  plainText(heading, source),
+			ID:    string(idBytes),
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	return headings
+}
+
+// plainText concatenates the text of the node, leaving out any markup.
+func plainText(node ast.Node, source []byte) string {
+	var b bytes.Buffer
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch c := child.(type) {
+		case *ast.Text:
+			b.Write(c.Segment.Value(source))
+			if c.SoftLineBreak() || c.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(c.Value)
+		case *ast.RawHTML:
+			// Tags are not text
+		default:
+			b.WriteString(plainText(child, source))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderSanitizesEmbeddedHTML(t *testing.T) {
+	source := "# Title\n\n" +
+		"<script>alert(1)</script>\n\n" +
+		"<img src=\"x\" onerror=\"alert(2)\"> [click](javascript:alert(3)) <b>bold</b>\n"
+
+	html, _, err := Render(source)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	for _, unsafe := range []string{"<script", "alert(1)", "onerror", "javascript:"} {
+		if strings.Contains(html, unsafe) {
+			t.Fatalf("expected %q to be removed, got %q", unsafe, html)
+		}
+	}
+	if !strings.Contains(html, "<b>bold</b>") || !strings.Contains(html, `<h1 id="title">Title</h1>`) {
+		t.Fatalf("expected safe markup to be kept, got %q", html)
+	}
+}
+
+func TestRenderSupportsGitHubExtensions(t *testing.T) {
+	source := "| a | b |\n|---|---|\n| 1 | 2 |\n\n" +
+		"- [x] done\n- [ ] todo\n\n" +
+		"~~gone~~ https://example.com\n"
+
+	html, _, err := Render(source)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	for _, want := range []string{
+		"<table>",
+		`<input checked="" disabled="" type="checkbox">`,
+		"<del>gone</del>",
+		`<a href="https://example.com" rel="nofollow">https://example.com</a>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("expected %q in %q", want, html)
+		}
+	}
+}
+
+func TestRenderListsHeadings(t *testing.T) {
+	_, toc, err := Render("# Intro\n\ntext\n\n## The *plan*\n\n### Intro\n")
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	want := []Heading{
+		{Level: 1, Text: "Intro", ID: "intro"},
+		{Level: 2, Text: "The plan", ID: "the-plan"},
+		{Level: 3, Text: "Intro", ID: "intro-1"},
+	}
+	if !reflect.DeepEqual(toc, want) {
+		t.Fatalf("expected %#v, got %#v", want, toc)
+	}
+}
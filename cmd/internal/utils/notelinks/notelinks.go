@@ -0,0 +1,88 @@
+// Package notelinks finds the references a markdown note makes to other notes:
+// wiki links to a note name ([[Note Name]]) and note paths (/notes/:id).
+package notelinks
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxNameLength matches the longest name a note can have, longer wiki links
+// cannot point to any note.
+const maxNameLength = 80
+
+var (
+	namePattern = regexp.MustCompile(`\[\[([^\[\]\n]+)\]\]`)
+	pathPattern = regexp.MustCompile(`/notes/([0-9]+)\b`)
+)
+
+// Ref is a reference to another note, either by name or by id.
+type Ref struct {
+	Name   string // Set for [[Note Name]] links, as written
+	NoteID int    // Set for /notes/:id links
+}
+
+// Parse returns the distinct references of 'content' in order of appearance.
+// Names are compared case-insensitively and keep their first spelling.
+func Parse(content string) []Ref {
+	type match struct {
+		start int
+		ref   Ref
+	}
+
+	var matches []match
+	for _, loc := range namePattern.FindAllStringSubmatchIndex(content, -1) {
+		name := strings.TrimSpace(content[loc[2]:loc[3]])
+		if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+			continue
+		}
+		matches = append(matches, match{start: loc[0], ref: Ref{Name: name}})
+	}
+
+	for _, loc := range pathPattern.FindAllStringSubmatchIndex(content, -1) {
+		id, err := strconv.Atoi(content[loc[2]:loc[3]])
+		if err != nil || id == 0 {
+			continue
+		}
+		matches = append(matches, match{start: loc[0], ref: Ref{NoteID: id}})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].start < matches[j].start
+	})
+
+	seenNames := make(map[string]bool)
+	seenIDs := make(map[int]bool)
+	refs := make([]Ref, 0, len(matches))
+	for _, m := range matches {
+		if m.ref.Name != "" {
+			key := strings.ToLower(m.ref.Name)
+			if seenNames[key] {
+				continue
+			}
+			seenNames[key] = true
+		} else {
+			if seenIDs[m.ref.NoteID] {
+				continue
+			}
+			seenIDs[m.ref.NoteID] = true
+		}
+		refs = append(refs, m.ref)
+	}
+	return refs
+}
+
+// Rename points the [[from]] links of 'content' to 'to' instead, whatever
+// their case. Other references are left as they are.
+func Rename(content, from, to string) string {
+	return namePattern.ReplaceAllStringFunc(content, func(link string) string {
+		name := strings.TrimSpace(link[2 : len(link)-2])
+		if !strings.EqualFold(name, from) {
+			return link
+		}
+		return "[[" + to + "]]"
+	})
+}
@@ -0,0 +1,38 @@
+package notelinks
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKeepsDistinctReferencesInOrder(t *testing.T) {
+	content := "See [[Roadmap]] and /notes/12, then [[ roadmap ]] again.\n" +
+		"Details in [Budget](/api/notes/7) and [[Q3 Budget]], not in /notes/12 nor /notes/3abc.\n" +
+		"Empty links [[ ]] and [[broken\n]] are ignored."
+
+	want := []Ref{
+		{Name: "Roadmap"},
+		{NoteID: 12},
+		{NoteID: 7},
+		{Name: "Q3 Budget"},
+	}
+	if got := Parse(content); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %#v, got %#v", want, got)
+	}
+}
+
+func TestParseSkipsNamesNoNoteCanHave(t *testing.T) {
+	long := "[[" + strings.Repeat("a", maxNameLength+1) + "]]"
+	if got := Parse(long + " /notes/0"); len(got) != 0 {
+		t.Fatalf("expected no reference, got %#v", got)
+	}
+}
+
+func TestRenameOnlyRewritesMatchingNames(t *testing.T) {
+	content := "[[Roadmap]], [[ROADMAP ]], [[Roadmap 2]] and /notes/4"
+	want := "[[Plan]], [[Plan]], [[Roadmap 2]] and /notes/4"
+	if got := Rename(content, "roadmap", "Plan"); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,153 @@
+package pdftext
+
+import (
+	"slices"
+	"unicode/utf16"
+)
+
+// maxCMapEntries bounds how many codes the ToUnicode CMaps of a file can map,
+// since ranges are expanded in memory.
+const maxCMapEntries = 1 << 17
+
+type codespace struct {
+	low, high []byte
+}
+
+// cmap maps character codes to text, as described by ToUnicode CMaps. Fonts
+// are not told apart, the CMaps of every font of the file are merged.
+type cmap struct {
+	codespaces []codespace
+	chars      map[string]string
+}
+
+func newCMap() *cmap {
+	return &cmap{chars: map[string]string{}}
+}
+
+// parse adds the mappings of a CMap stream.
+func (c *cmap) parse(data []byte) {
+	eachOperation(data, func(op string, operands []operand) bool {
+		switch op {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				low, high := operands[i], operands[i+1]
+				if low.kind == tokenString && high.kind == tokenString && len(low.str) == len(high.str) && len(low.str) > 0 {
+					c.codespaces = append(c.codespaces, codespace{low: low.str, high: high.str})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, dst := operands[i], operands[i+1]
+				if src.kind == tokenString && dst.kind == tokenString && len(c.chars) < maxCMapEntries {
+					c.chars[string(src.str)] = decodeUTF16(dst.str)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				c.addRange(operands[i], operands[i+1], operands[i+2])
+			}
+		}
+		return true
+	})
+
+	// Shorter codes first, so the first matching codespace gives the code length
+	slices.SortStableFunc(c.codespaces, func(a, b codespace) int { return len(a.low) - len(b.low) })
+}
+
+// addRange maps the codes from 'lo' to 'hi' either to consecutive text, by
+// incrementing the last character of 'dst', or to each string of a 'dst' array.
+func (c *cmap) addRange(lo, hi, dst operand) {
+	if lo.kind != tokenString || hi.kind != tokenString || len(lo.str) != len(hi.str) || len(lo.str) == 0 || len(lo.str) > 4 {
+		return
+	}
+
+	from, to := codeValue(lo.str), codeValue(hi.str)
+	if to < from || int(to-from) >= maxCMapEntries-len(c.chars) {
+		return
+	}
+
+	var base []uint16
+	if dst.kind == tokenString {
+		base = utf16Units(dst.str)
+	}
+
+	for code := from; code <= to; code++ {
+		key := string(codeBytes(code, len(lo.str)))
+		offset := int(code - from)
+
+		switch {
+		case dst.isArray():
+			if offset < len(dst.items) && dst.items[offset].kind == tokenString {
+				c.chars[key] = decodeUTF16(dst.items[offset].str)
+			}
+		case len(base) > 0:
+			units := slices.Clone(base)
+			units[len(units)-1] += uint16(offset)
+			c.chars[key] = string(utf16.Decode(units))
+		}
+	}
+}
+
+// decode turns the codes of 's' into text. Codes missing from the CMaps are dropped.
+func (c *cmap) decode(s []byte) string {
+	var text []byte
+	for i := 0; i < len(s); {
+		n := c.codeLength(s[i:], 2)
+		text = append(text, c.chars[string(s[i:i+n])]...)
+		i += n
+	}
+	return string(text)
+}
+
+// codeLength returns the length of the code starting 's', from the codespaces
+// or 'fallback' when none matches.
+func (c *cmap) codeLength(s []byte, fallback int) int {
+	for _, cs := range c.codespaces {
+		n := len(cs.low)
+		if n > len(s) {
+			continue
+		}
+
+		inside := true
+		for i := 0; i < n; i++ {
+			if s[i] < cs.low[i] || s[i] > cs.high[i] {
+				inside = false
+				break
+			}
+		}
+
+		if inside {
+			return n
+		}
+	}
+	return min(fallback, len(s))
+}
+
+func decodeUTF16(s []byte) string {
+	return string(utf16.Decode(utf16Units(s)))
+}
+
+func utf16Units(s []byte) []uint16 {
+	units := make([]uint16, len(s)/2)
+	for i := range units {
+		units[i] = uint16(s[2*i])<<8 | uint16(s[2*i+1])
+	}
+	return units
+}
+
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+func codeBytes(v uint32, n int) []byte {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return b
+}
@@ -0,0 +1,244 @@
+package pdftext
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// wordGap is the TJ adjustment, in thousandths of an em, above which two
+	// strings are considered separate words rather than kerned letters.
+	wordGap = 200
+
+	// headerWindow is how far into the file the "%PDF-" header is looked for.
+	headerWindow = 1024
+)
+
+// winAnsiHigh maps the 0x80-0x9F range of WinAnsiEncoding, the only part
+// differing from Latin-1 for text.
+var winAnsiHigh = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// Extract returns up to 'limit' bytes of the text of the PDF file. It is best
+// effort: the file is not parsed into objects, its streams are scanned in file
+// order and the text operators of the content streams are read, which matches
+// the reading order of most documents. Hex strings are decoded with the
+// ToUnicode CMaps of the file, other strings as WinAnsiEncoding.
+func Extract(data []byte, limit int) (string, error) {
+	if !bytes.Contains(data[:min(len(data), headerWindow)], []byte("%PDF-")) {
+		return "", ErrNotPDF
+	}
+
+	if tok, _ := dictValue(data, "Encrypt"); tok.kind != tokenEOF {
+		return "", ErrEncrypted
+	}
+
+	// CMaps often follow the pages using them, so they are all read first
+	unicodeMap := newCMap()
+	err := eachStream(data, func(content []byte) bool {
+		if bytes.Contains(content, []byte("begincmap")) {
+			unicodeMap.parse(content)
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+
+	out := &textWriter{limit: limit}
+	err = eachStream(data, func(content []byte) bool {
+		if bytes.Contains(content, []byte("BT")) && !bytes.Contains(content, []byte("begincmap")) {
+			out.breakParagraph()
+			runContent(content, unicodeMap, out)
+		}
+		return !out.full
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.b.String()), nil
+}
+
+// eachStream calls 'fn' with the decoded data of every stream that may hold
+// text, until 'fn' returns false. Streams are decoded one at a time, so only
+// one is held in memory, and the ones that fail to decode are skipped.
+func eachStream(data []byte, fn func(content []byte) bool) error {
+	s := &streamScanner{data: data}
+	for {
+		st, err := s.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !holdsText(st.dict) {
+			continue
+		}
+
+		content, err := s.decode(st)
+		if err == ErrTooLarge {
+			return err
+		}
+		if err != nil {
+			continue
+		}
+
+		if !fn(content) {
+			return nil
+		}
+	}
+}
+
+// runContent interprets the text operators of a content stream.
+func runContent(content []byte, unicodeMap *cmap, out *textWriter) {
+	eachOperation(content, func(op string, operands []operand) bool {
+		switch op {
+		case "Tj":
+			out.writeOperand(unicodeMap, operands, 0)
+		case "'":
+			out.breakLine()
+			out.writeOperand(unicodeMap, operands, 0)
+		case "\"":
+			out.breakLine()
+			out.writeOperand(unicodeMap, operands, 2)
+		case "TJ":
+			if len(operands) > 0 && operands[0].isArray() {
+				for _, item := range operands[0].items {
+					switch item.kind {
+					case tokenString:
+						out.write(decodeText(unicodeMap, item))
+					case tokenNumber:
+						if item.num < -wordGap {
+							out.breakWord()
+						}
+					}
+				}
+			}
+		case "T*":
+			out.breakLine()
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if operands[1].num != 0 {
+					out.breakLine()
+				} else if operands[0].num > 0 {
+					out.breakWord()
+				}
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				out.moveTo(operands[5].num)
+			}
+		case "BT", "ET":
+			out.breakWord()
+		}
+		return !out.full
+	})
+}
+
+// decodeText turns a string operand into text. Composite fonts are shown with
+// hex strings in practice, so those go through the CMaps when the file has any.
+func decodeText(unicodeMap *cmap, s token) string {
+	if s.hex && len(unicodeMap.chars) > 0 {
+		return unicodeMap.decode(s.str)
+	}
+
+	var b strings.Builder
+	for _, c := range s.str {
+		b.WriteString(simpleText(c))
+	}
+	return b.String()
+}
+
+// simpleText decodes a 1-byte code with WinAnsiEncoding, which is what most
+// simple fonts in the wild use.
+func simpleText(c byte) string {
+	switch {
+	case c >= 0x80 && c < 0xa0:
+		if r := winAnsiHigh[c-0x80]; r != 0 {
+			return string(r)
+		}
+		return ""
+	case c < 0x20 && c != '\t' && c != '\n':
+		return ""
+	}
+	return string(rune(c))
+}
+
+// textWriter assembles the extracted text, turning text positioning into
+// spaces and line breaks. It stops writing once 'limit' bytes are written.
+type textWriter struct {
+	b            strings.Builder
+	limit        int
+	full         bool
+	pendingBreak string
+	hasY         bool
+	lastY        float64
+}
+
+func (w *textWriter) writeOperand(unicodeMap *cmap, operands []operand, index int) {
+	if index < len(operands) && operands[index].kind == tokenString {
+		w.write(decodeText(unicodeMap, operands[index].token))
+	}
+}
+
+func (w *textWriter) write(text string) {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\t' {
+			return -1
+		}
+		return r
+	}, text)
+	if text == "" {
+		return
+	}
+
+	if w.full {
+		return
+	}
+
+	if w.b.Len() > 0 && w.pendingBreak != "" {
+		written := w.b.String()
+		if w.pendingBreak != " " || (!strings.HasSuffix(written, " ") && !strings.HasPrefix(text, " ")) {
+			text = w.pendingBreak + text
+		}
+	}
+	w.pendingBreak = ""
+
+	if room := w.limit - w.b.Len(); len(text) >= room {
+		// Cut on a character boundary
+		for room > 0 && !utf8.RuneStart(text[room]) {
+			room--
+		}
+		text, w.full = text[:room], true
+	}
+	w.b.WriteString(text)
+}
+
+// setBreak records the separator to write before the next text, keeping the strongest one.
+func (w *textWriter) setBreak(sep string) {
+	if len(sep) > len(w.pendingBreak) || (sep != " " && w.pendingBreak == " ") {
+		w.pendingBreak = sep
+	}
+}
+
+func (w *textWriter) breakWord()      { w.setBreak(" ") }
+func (w *textWriter) breakLine()      { w.setBreak("\n") }
+func (w *textWriter) breakParagraph() { w.setBreak("\n\n") }
+
+// moveTo starts a new line when the text matrix moves vertically.
+func (w *textWriter) moveTo(y float64) {
+	if w.hasY && math.Abs(y-w.lastY) > 0.5 {
+		w.breakLine()
+	} else {
+		w.breakWord()
+	}
+	w.hasY, w.lastY = true, y
+}
@@ -0,0 +1,208 @@
+package pdftext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"testing"
+)
+
+// buildPDF lays out the objects as a PDF file, numbered from 1 and skipping
+// empty ones. Cross-reference tables are not needed, Extract scans the file.
+func buildPDF(objects ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		if obj == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+func streamObject(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(data string) []byte {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	_, _ = w.Write([]byte(data))
+	_ = w.Close()
+	return b.Bytes()
+}
+
+func TestExtractReadsSimpleFontsInPageOrder(t *testing.T) {
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 6 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 7 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		streamObject("", []byte("BT /F1 12 Tf 72 712 Td (Quarterly \\(Q3\\)) Tj 0 -14 Td [(re)20(port)-400(draft)] TJ ET")),
+		streamObject("/Filter /FlateDecode", deflate("BT /F1 12 Tf 1 0 0 1 72 700 Tm (Second) Tj 1 0 0 1 72 680 Tm (page) Tj ET")),
+	)
+
+	got, err := Extract(data, 1<<20)
+	if err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+
+	want := "Quarterly (Q3)\nreport draft\n\nSecond\npage"
+	if got != want {
+		t.Fatalf("unexpected text:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestExtractDecodesCompositeFontsThroughToUnicode(t *testing.T) {
+	cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+		"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"1 beginbfchar <0003> <0020> endbfchar\n" +
+		"2 beginbfrange <0010> <0012> <00E9> <0020> <0021> [<0066006C> <006F>] endbfrange\n" +
+		"endcmap CMapName currentdict /CMap defineresource pop end end"
+
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F2 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Inter /Encoding /Identity-H /ToUnicode 6 0 R >>",
+		streamObject("/Filter /FlateDecode", deflate("BT /F2 10 Tf <0020002100030010001100120003> Tj ET")),
+		streamObject("/Filter /FlateDecode", deflate(cmap)),
+	)
+
+	got, err := Extract(data, 1<<20)
+	if err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+
+	if want := "flo éêë"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExtractDoesNotNeedThePageTree(t *testing.T) {
+	pageObj := "<< /Type /Page /Parent 2 0 R /Contents 5 0 R /Resources << /Font << /F1 4 0 R >> >> >>"
+	fontObj := "<< /Type /Font /Subtype /Type1 >>"
+	header := fmt.Sprintf("3 0 4 %d\n", len(pageObj)+1)
+
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"", // Objects 3 and 4 only exist in the object stream, which is not read
+		"",
+		streamObject("", []byte("BT /F1 9 Tf (compressed objects) Tj ET")),
+		streamObject(fmt.Sprintf("/Type /ObjStm /N 2 /First %d /Filter /FlateDecode", len(header)), deflate(header+pageObj+"\n"+fontObj)),
+	)
+
+	got, err := Extract(data, 1<<20)
+	if err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+
+	if want := "compressed objects"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExtractRejectsOtherFiles(t *testing.T) {
+	if _, err := Extract([]byte("MZ\x90\x00 not a pdf"), 1<<20); err != ErrNotPDF {
+		t.Fatalf("expected ErrNotPDF, got %v", err)
+	}
+
+	encrypted := []byte("%PDF-1.4\n1 0 obj << >> endobj\ntrailer << /Root 1 0 R /Encrypt 2 0 R >>")
+	if _, err := Extract(encrypted, 1<<20); err != ErrEncrypted {
+		t.Fatalf("expected ErrEncrypted, got %v", err)
+	}
+}
+
+func TestExtractIgnoresLengthsPastTheEndOfFile(t *testing.T) {
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 4 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 >>",
+		"<< /Length 9223372036854775807 >>\nstream\nBT /F1 9 Tf (still read) Tj ET\nendstream",
+	)
+
+	got, err := Extract(data, 1<<20)
+	if err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+
+	if want := "still read"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExtractStopsAtTheTextLimit(t *testing.T) {
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 6 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 6 0 R >>",
+		"<< /Type /Font /Subtype /Type1 >>",
+		streamObject("", []byte("BT /F1 9 Tf (Caf\351 au lait) Tj ET")),
+	)
+
+	got, err := Extract(data, 4)
+	if err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+
+	// The é takes two bytes, so it does not fit
+	if want := "Caf"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExtractSkipsImageStreams(t *testing.T) {
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 4 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 >>",
+		streamObject("", []byte("BT /F1 9 Tf (caption) Tj ET")),
+		streamObject("/Type /XObject /Subtype /Image /Width 1 /Height 1", []byte("BT (pixels) Tj ET")),
+	)
+
+	got, err := Extract(data, 1<<20)
+	if err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+
+	if want := "caption"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestDecodeStopsAtTheDocumentBudget(t *testing.T) {
+	s := &streamScanner{decoded: maxDecodedDocument - 10}
+	st := &stream{dict: []byte("<< /Filter /FlateDecode >>"), raw: deflate(string(make([]byte, 100)))}
+
+	data, err := s.decode(st)
+	if err != nil || len(data) != 10 {
+		t.Fatalf("expected the 10 bytes left in the budget, got %d (%v)", len(data), err)
+	}
+
+	if _, err = s.decode(st); err != ErrTooLarge {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+}
+
+func FuzzExtract(f *testing.F) {
+	f.Add(buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 4 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 >>",
+		streamObject("/Filter /FlateDecode", deflate("BT /F1 12 Tf 72 712 Td [(a)-400(b)] TJ ET")),
+	))
+	f.Add([]byte("%PDF-1.4\n1 0 obj << /Length 99999999999 >> stream\nx\nendstream endobj"))
+	f.Add([]byte("%PDF-1.5\n1 0 obj << /Type /ObjStm /N 3 /First 2 >> stream\n1 0\nendstream endobj"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = Extract(data, 1<<20)
+	})
+}
@@ -0,0 +1,319 @@
+package pdftext
+
+import (
+	"bytes"
+	"strconv"
+)
+
+const (
+	// maxOperands bounds the operands kept for an operator, and the items kept
+	// for an array. Extra ones are dropped.
+	maxOperands = 512
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenName
+	tokenOperator
+	tokenArrayStart
+	tokenArrayEnd
+)
+
+// token is one lexical element of a content stream or a CMap. Dictionaries
+// are not assembled, their delimiters come out as the "<<" and ">>" operators.
+type token struct {
+	kind tokenKind
+	num  float64
+	str  []byte // Raw bytes of strings, text of names and operators
+	hex  bool   // The string was written as <hex digits>
+}
+
+// operand is a token given to an operator. Arrays hold their items, nested
+// arrays being flattened.
+type operand struct {
+	token
+	items []token
+}
+
+func (o operand) isArray() bool {
+	return o.kind == tokenArrayStart
+}
+
+// scanner splits PDF syntax into tokens. It never looks outside of 'data'
+// and always moves forward, so any input is read in a single pass.
+type scanner struct {
+	data []byte
+	pos  int
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (s *scanner) skipSpace() {
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		if c == '%' {
+			for s.pos < len(s.data) && s.data[s.pos] != '\n' && s.data[s.pos] != '\r' {
+				s.pos++
+			}
+			continue
+		}
+
+		if !isSpace(c) {
+			return
+		}
+		s.pos++
+	}
+}
+
+func (s *scanner) next() token {
+	for {
+		s.skipSpace()
+		if s.pos >= len(s.data) {
+			return token{kind: tokenEOF}
+		}
+
+		c := s.data[s.pos]
+		switch c {
+		case '/':
+			s.pos++
+			return token{kind: tokenName, str: s.readWord()}
+		case '(':
+			s.pos++
+			return token{kind: tokenString, str: s.readLiteralString()}
+		case '<':
+			if s.pos+1 < len(s.data) && s.data[s.pos+1] == '<' {
+				s.pos += 2
+				return token{kind: tokenOperator, str: []byte("<<")}
+			}
+			s.pos++
+			return token{kind: tokenString, str: s.readHexString(), hex: true}
+		case '>':
+			if s.pos+1 < len(s.data) && s.data[s.pos+1] == '>' {
+				s.pos += 2
+				return token{kind: tokenOperator, str: []byte(">>")}
+			}
+			s.pos++ // Stray delimiter
+			continue
+		case ')':
+			s.pos++
+			continue
+		case '[':
+			s.pos++
+			return token{kind: tokenArrayStart}
+		case ']':
+			s.pos++
+			return token{kind: tokenArrayEnd}
+		case '{', '}':
+			s.pos++
+			return token{kind: tokenOperator, str: []byte{c}}
+		}
+
+		word := s.readWord()
+		if word[0] == '.' || word[0] == '-' || word[0] == '+' || (word[0] >= '0' && word[0] <= '9') {
+			if f, err := strconv.ParseFloat(string(word), 64); err == nil {
+				return token{kind: tokenNumber, num: f}
+			}
+		}
+		return token{kind: tokenOperator, str: word}
+	}
+}
+
+// readWord reads up to the next space or delimiter, and at least one byte.
+func (s *scanner) readWord() []byte {
+	start := s.pos
+	for s.pos < len(s.data) && !isSpace(s.data[s.pos]) && !isDelimiter(s.data[s.pos]) {
+		s.pos++
+	}
+	if s.pos == start && s.pos < len(s.data) {
+		s.pos++
+	}
+	return s.data[start:s.pos]
+}
+
+func (s *scanner) readLiteralString() []byte {
+	var b []byte
+	depth := 1
+
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		s.pos++
+
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return b
+			}
+		case '\\':
+			if s.pos >= len(s.data) {
+				return b
+			}
+			c = s.data[s.pos]
+			s.pos++
+
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// Line continuation
+				if s.pos < len(s.data) && s.data[s.pos] == '\n' {
+					s.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && s.pos < len(s.data) && s.data[s.pos] >= '0' && s.data[s.pos] <= '7'; i++ {
+						v = v*8 + int(s.data[s.pos]-'0')
+						s.pos++
+					}
+					c = byte(v)
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+func (s *scanner) readHexString() []byte {
+	var b []byte
+	high, odd := byte(0), false
+
+	for s.pos < len(s.data) && s.data[s.pos] != '>' {
+		v, ok := hexValue(s.data[s.pos])
+		s.pos++
+		if !ok {
+			continue
+		}
+
+		if odd {
+			b = append(b, high<<4|v)
+		} else {
+			high = v
+		}
+		odd = !odd
+	}
+	if s.pos < len(s.data) {
+		s.pos++ // '>'
+	}
+
+	// A missing last digit is read as 0
+	if odd {
+		b = append(b, high<<4)
+	}
+	return b
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// eachOperation calls 'fn' with every operator of the content and its operands,
+// until 'fn' returns false. Inline images are skipped.
+func eachOperation(data []byte, fn func(op string, operands []operand) bool) {
+	s := &scanner{data: data}
+	var operands []operand
+	var items []token
+	depth := 0
+
+	for {
+		tok := s.next()
+		switch tok.kind {
+		case tokenEOF:
+			return
+
+		case tokenArrayStart:
+			if depth == 0 {
+				items = nil
+			}
+			depth++
+
+		case tokenArrayEnd:
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 && len(operands) < maxOperands {
+				operands = append(operands, operand{token: token{kind: tokenArrayStart}, items: items})
+			}
+
+		case tokenOperator:
+			if depth > 0 {
+				continue
+			}
+
+			op := string(tok.str)
+			if op == "ID" {
+				s.skipInlineImage()
+			}
+			if !fn(op, operands) {
+				return
+			}
+			operands = operands[:0]
+
+		default:
+			if depth > 0 {
+				if len(items) < maxOperands {
+					items = append(items, tok)
+				}
+			} else if len(operands) < maxOperands {
+				operands = append(operands, operand{token: tok})
+			}
+		}
+	}
+}
+
+// skipInlineImage moves past the binary data of an inline image, up to its
+// "EI" operator.
+func (s *scanner) skipInlineImage() {
+	end := bytes.Index(s.data[s.pos:], []byte("EI"))
+	for end >= 0 {
+		at := s.pos + end
+		if at > 0 && isSpace(s.data[at-1]) && (at+2 == len(s.data) || isSpace(s.data[at+2])) {
+			s.pos = at + 2
+			return
+		}
+		s.pos = at + 2
+		end = bytes.Index(s.data[s.pos:], []byte("EI"))
+	}
+	s.pos = len(s.data)
+}
@@ -0,0 +1,287 @@
+package pdftext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"errors"
+	"io"
+	"math"
+)
+
+var (
+	ErrNotPDF    = errors.New("not a pdf file")
+	ErrEncrypted = errors.New("encrypted pdf files are not supported")
+	// ErrTooLarge is returned for files with more streams, or streams decoding
+	// to more data, than a text document could need.
+	ErrTooLarge = errors.New("pdf file is too large to extract text from")
+
+	errUnsupportedFilter = errors.New("unsupported stream filter")
+)
+
+const (
+	// maxDecodedStream bounds the size of a decoded stream, against zip bombs.
+	maxDecodedStream = 64 * 1024 * 1024
+	// maxDecodedDocument bounds the bytes decoded from all streams of a file,
+	// against files made of many small bombs.
+	maxDecodedDocument = 256 * 1024 * 1024
+	maxStreams         = 100_000
+	// maxDictLookback bounds how far before the "stream" keyword its
+	// dictionary is looked for.
+	maxDictLookback = 4096
+)
+
+// stream holds the dictionary and the raw data of a stream object.
+type stream struct {
+	dict []byte
+	raw  []byte
+}
+
+// streamScanner finds the streams of a file in order, by looking for their
+// "stream" keyword. Objects are never parsed, so broken cross-reference tables,
+// cycles and deep nesting do not matter.
+type streamScanner struct {
+	data    []byte
+	pos     int
+	count   int
+	decoded int // Bytes decoded so far, up to maxDecodedDocument
+}
+
+// next returns the next stream, or io.EOF once there are no more.
+func (s *streamScanner) next() (*stream, error) {
+	keyword := []byte("stream")
+	for {
+		i := bytes.Index(s.data[s.pos:], keyword)
+		if i < 0 {
+			return nil, io.EOF
+		}
+
+		start := s.pos + i
+		s.pos = start + len(keyword)
+
+		// "endstream" contains the keyword as well
+		if start >= 3 && string(s.data[start-3:start]) == "end" {
+			continue
+		}
+
+		dict := findDict(s.data[:start])
+		if dict == nil {
+			continue
+		}
+
+		if s.count++; s.count > maxStreams {
+			return nil, ErrTooLarge
+		}
+		return &stream{dict: dict, raw: s.readData(dict)}, nil
+	}
+}
+
+// findDict returns the dictionary right before a "stream" keyword, by matching
+// its closing ">>" with its opening "<<".
+func findDict(before []byte) []byte {
+	end := len(bytes.TrimRight(before, "\x00\t\n\f\r "))
+	if end < 2 || before[end-2] != '>' || before[end-1] != '>' {
+		return nil
+	}
+
+	low := max(0, end-maxDictLookback)
+	depth := 0
+	for i := end - 1; i > low; i-- {
+		switch {
+		case before[i] == '>' && before[i-1] == '>':
+			depth++
+			i--
+		case before[i] == '<' && before[i-1] == '<':
+			depth--
+			if depth == 0 {
+				return before[i-1 : end]
+			}
+			i--
+		}
+	}
+	return nil
+}
+
+// readData reads the data following the "stream" keyword, using the /Length
+// when it is direct and correct, and looking for "endstream" otherwise.
+func (s *streamScanner) readData(dict []byte) []byte {
+	if s.pos < len(s.data) && s.data[s.pos] == '\r' {
+		s.pos++
+	}
+	if s.pos < len(s.data) && s.data[s.pos] == '\n' {
+		s.pos++
+	}
+	start := s.pos
+
+	// Compared this way round, since huge lengths overflow start+n
+	if n, ok := dictLength(dict); ok && n <= len(s.data)-start {
+		rest := bytes.TrimLeft(s.data[start+n:], "\r\n\t ")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			s.pos = start + n
+			return s.data[start : start+n]
+		}
+	}
+
+	end := bytes.Index(s.data[start:], []byte("endstream"))
+	if end < 0 {
+		s.pos = len(s.data)
+		return s.data[start:]
+	}
+
+	s.pos = start + end
+	return bytes.TrimRight(s.data[start:start+end], "\r\n")
+}
+
+// dictValue returns the first token following 'key' in the dictionary.
+// Keys of nested dictionaries are found as well, which is good enough for
+// the few keys looked at.
+func dictValue(dict []byte, key string) (token, *scanner) {
+	needle := []byte("/" + key)
+	for pos := 0; pos < len(dict); {
+		i := bytes.Index(dict[pos:], needle)
+		if i < 0 {
+			break
+		}
+
+		end := pos + i + len(needle)
+		if end == len(dict) || isSpace(dict[end]) || isDelimiter(dict[end]) {
+			s := &scanner{data: dict, pos: end}
+			return s.next(), s
+		}
+		pos = end
+	}
+	return token{kind: tokenEOF}, nil
+}
+
+// dictName returns the name value of 'key', or "" when it is not a name.
+func dictName(dict []byte, key string) string {
+	if tok, _ := dictValue(dict, key); tok.kind == tokenName {
+		return string(tok.str)
+	}
+	return ""
+}
+
+// dictLength returns the /Length of the stream, unless it is an indirect reference.
+func dictLength(dict []byte) (int, bool) {
+	tok, s := dictValue(dict, "Length")
+	if tok.kind != tokenNumber {
+		return 0, false
+	}
+
+	// "12 0 R" points to another object
+	if gen := s.next(); gen.kind == tokenNumber {
+		if r := s.next(); r.kind == tokenOperator && string(r.str) == "R" {
+			return 0, false
+		}
+	}
+
+	if tok.num < 0 || tok.num > math.MaxInt32 || tok.num != math.Trunc(tok.num) {
+		return 0, false
+	}
+	return int(tok.num), true
+}
+
+// filters returns the names of the filters of the stream, in order.
+func filters(dict []byte) []string {
+	tok, s := dictValue(dict, "Filter")
+	switch tok.kind {
+	case tokenName:
+		return []string{string(tok.str)}
+	case tokenArrayStart:
+		var names []string
+		for next := s.next(); next.kind == tokenName && len(names) < maxOperands; next = s.next() {
+			names = append(names, string(next.str))
+		}
+		return names
+	}
+	return nil
+}
+
+// holdsText tells if the stream may hold text operators or a CMap. Images,
+// embedded fonts, object and cross-reference streams are not worth decoding.
+func holdsText(dict []byte) bool {
+	switch dictName(dict, "Subtype") {
+	case "Image", "Type1C", "CIDFontType0C", "OpenType", "XML":
+		return false
+	}
+
+	switch dictName(dict, "Type") {
+	case "XRef", "ObjStm", "Metadata", "EmbeddedFile":
+		return false
+	}
+
+	for _, key := range []string{"Length1", "Length2", "Length3"} {
+		if tok, _ := dictValue(dict, key); tok.kind != tokenEOF {
+			return false
+		}
+	}
+	return true
+}
+
+// decode applies the stream filters. Only the ones used by text streams are
+// supported, image filters are not.
+func (s *streamScanner) decode(st *stream) ([]byte, error) {
+	data := st.raw
+	for _, f := range filters(st.dict) {
+		remaining := maxDecodedDocument - s.decoded
+		if remaining <= 0 {
+			return nil, ErrTooLarge
+		}
+
+		var err error
+		switch f {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data, min(remaining, maxDecodedStream))
+		case "ASCIIHexDecode", "AHx":
+			data = decodeASCIIHex(data)
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		default:
+			err = errUnsupportedFilter
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		s.decoded += len(data)
+	}
+	return data, nil
+}
+
+// inflate decompresses up to 'limit' bytes of zlib data. Truncated streams are
+// common in the wild, so whatever could be read before the error is kept.
+func inflate(data []byte, limit int) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)))
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeASCIIHex(data []byte) []byte {
+	if end := bytes.IndexByte(data, '>'); end >= 0 {
+		data = data[:end]
+	}
+	s := &scanner{data: data}
+	return s.readHexString()
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+
+	out := make([]byte, 4*len(data)+4) // "z" stands for 4 bytes
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
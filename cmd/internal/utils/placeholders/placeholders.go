@@ -0,0 +1,52 @@
+// Package placeholders fills the {{name}} placeholders of note templates.
+// Names are made of letters, digits and underscores, start with a letter,
+// and are compared case-insensitively.
+package placeholders
+
+import (
+	"regexp"
+	"strings"
+)
+
+var pattern = regexp.MustCompile(`\{\{\s*([A-Za-z][A-Za-z0-9_]{0,39})\s*\}\}`)
+
+// Names returns the distinct placeholder names of 'content', lowercased,
+// in order of appearance.
+func Names(content string) []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for _, match := range pattern.FindAllStringSubmatch(content, -1) {
+		name := strings.ToLower(match[1])
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Render replaces every placeholder of 'content' by its value. Placeholders
+// without a value are left as written, and their names are returned in order
+// of appearance.
+func Render(content string, values map[string]string) (string, []string) {
+	lowered := make(map[string]string, len(values))
+	for name, value := range values {
+		lowered[strings.ToLower(name)] = value
+	}
+
+	var missing []string
+	seen := make(map[string]bool)
+	rendered := pattern.ReplaceAllStringFunc(content, func(placeholder string) string {
+		name := strings.ToLower(pattern.FindStringSubmatch(placeholder)[1])
+		if value, ok := lowered[name]; ok {
+			return value
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			missing = append(missing, name)
+		}
+		return placeholder
+	})
+	return rendered, missing
+}
@@ -0,0 +1,35 @@
+package placeholders
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamesKeepsDistinctNamesInOrder(t *testing.T) {
+	content := "# {{ Title }}\n{{date}} by {{author}}, {{title}} again.\n" +
+		"Not placeholders: {{}}, {{ 1st }}, {{two words}} and {single}."
+
+	want := []string{"title", "date", "author"}
+	if got := Names(content); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRenderReportsMissingValues(t *testing.T) {
+	content := "{{Service}} is down since {{ since }}, owner: {{owner}}, impact: {{impact}} ({{owner}})"
+
+	rendered, missing := Render(content, map[string]string{"service": "Billing", "SINCE": "09:30"})
+	if want := "Billing is down since 09:30, owner: {{owner}}, impact: {{impact}} ({{owner}})"; rendered != want {
+		t.Fatalf("expected %q, got %q", want, rendered)
+	}
+	if want := []string{"owner", "impact"}; !reflect.DeepEqual(missing, want) {
+		t.Fatalf("expected missing %v, got %v", want, missing)
+	}
+}
+
+func TestRenderDoesNotExpandValues(t *testing.T) {
+	rendered, missing := Render("{{a}} {{b}}", map[string]string{"a": "{{b}}", "b": "x"})
+	if rendered != "{{b}} x" || len(missing) != 0 {
+		t.Fatalf("expected values to be inserted as written, got %q (missing %v)", rendered, missing)
+	}
+}
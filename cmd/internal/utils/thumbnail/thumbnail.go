@@ -0,0 +1,147 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// MaxSize is the largest width and height of a thumbnail.
+	MaxSize = 256
+
+	ContentType = "image/jpeg"
+	Ext         = ".jpg"
+
+	jpegQuality = 80
+
+	// maxSourcePixels keeps huge (or forged) images from being decoded,
+	// a 40 MP RGBA image already takes 160 MB once decoded.
+	maxSourcePixels = 40_000_000
+)
+
+var ErrImageTooLarge = errors.New("image is too large to preview")
+
+var (
+	paperColor  = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	marginColor = color.RGBA{R: 0xe4, G: 0xe6, B: 0xea, A: 0xff}
+	foldColor   = color.RGBA{R: 0xc8, G: 0xcc, B: 0xd2, A: 0xff}
+	lineColor   = color.RGBA{R: 0xd6, G: 0xd9, B: 0xde, A: 0xff}
+	bandColor   = color.RGBA{R: 0xd9, G: 0x30, B: 0x25, A: 0xff}
+)
+
+// FromImage decodes a png, jpeg, gif or webp image and scales it down to fit
+// in MaxSize x MaxSize, encoded as JPEG. Transparent areas become white.
+// Only the first frame of animated images is used.
+func FromImage(r io.ReadSeeker) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Width*cfg.Height > maxSourcePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := image.NewRGBA(fit(src.Bounds().Dx(), src.Bounds().Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(paperColor), image.Point{}, draw.Src)
+	draw.BiLinear.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+	return encode(dst)
+}
+
+// PDFPlaceholder draws a generic first page for PDF attachments, a sheet with
+// a folded corner, a few text lines and a "PDF" label. Rendering the actual
+// page would need a PDF rasterizer, which pure Go does not have.
+func PDFPlaceholder() ([]byte, error) {
+	const (
+		width  = 181 // A4 ratio
+		height = MaxSize
+		fold   = 36
+	)
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(paperColor), image.Point{}, draw.Src)
+
+	// Cut the top right corner, then draw its back folded over the page
+	for y := 0; y < fold; y++ {
+		for x := width - fold; x < width; x++ {
+			if x-(width-fold) >= y {
+				img.SetRGBA(x, y, marginColor)
+			} else {
+				img.SetRGBA(x, y, foldColor)
+			}
+		}
+	}
+
+	for y := 48; y < height-96; y += 14 {
+		right := width - 24
+		if y < fold+8 {
+			right = width - fold - 8
+		}
+		draw.Draw(img, image.Rect(24, y, right, y+4), image.NewUniform(lineColor), image.Point{}, draw.Src)
+	}
+
+	band := image.Rect(24, height-80, width-24, height-36)
+	draw.Draw(img, band, image.NewUniform(bandColor), image.Point{}, draw.Src)
+	drawLabel(img, band, "PDF", 3)
+	return encode(img)
+}
+
+// drawLabel writes 'text' in white at the center of 'area', with the basic
+// bitmap font enlarged 'scale' times.
+func drawLabel(dst draw.Image, area image.Rectangle, text string, scale int) {
+	face := basicfont.Face7x13
+	label := image.NewRGBA(image.Rect(0, 0, len(text)*face.Advance, face.Height))
+	drawer := font.Drawer{
+		Dst:  label,
+		Src:  image.NewUniform(paperColor),
+		Face: face,
+		Dot:  fixed.P(0, face.Ascent),
+	}
+	drawer.DrawString(text)
+
+	size := label.Bounds().Size().Mul(scale)
+	origin := area.Min.Add(area.Size().Sub(size).Div(2))
+	draw.NearestNeighbor.Scale(dst, image.Rectangle{Min: origin, Max: origin.Add(size)}, label, label.Bounds(), draw.Over, nil)
+}
+
+// fit returns the bounds of a thumbnail for a 'width' x 'height' image,
+// keeping its aspect ratio. Images already small enough are not enlarged.
+func fit(width, height int) image.Rectangle {
+	if width <= MaxSize && height <= MaxSize {
+		return image.Rect(0, 0, width, height)
+	}
+
+	if width >= height {
+		return image.Rect(0, 0, MaxSize, max(1, height*MaxSize/width))
+	}
+	return image.Rect(0, 0, max(1, width*MaxSize/height), MaxSize)
+}
+
+func encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
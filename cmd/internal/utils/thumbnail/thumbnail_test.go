@@ -0,0 +1,75 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestFromImageScalesDownKeepingAspectRatio(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 1000, 500))
+	for y := 0; y < 500; y++ {
+		for x := 0; x < 1000; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xff})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatalf("failed to encode source: %v", err)
+	}
+
+	data, err := FromImage(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("FromImage returned error: %v", err)
+	}
+
+	thumb, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("thumbnail is not a jpeg: %v", err)
+	}
+
+	if got := thumb.Bounds().Size(); got != image.Pt(MaxSize, MaxSize/2) {
+		t.Fatalf("expected %dx%d thumbnail, got %v", MaxSize, MaxSize/2, got)
+	}
+}
+
+func TestFromImageKeepsSmallImagesSize(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 40, 30))); err != nil {
+		t.Fatalf("failed to encode source: %v", err)
+	}
+
+	data, err := FromImage(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("FromImage returned error: %v", err)
+	}
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("thumbnail is not a jpeg: %v", err)
+	}
+
+	if cfg.Width != 40 || cfg.Height != 30 {
+		t.Fatalf("expected 40x30 thumbnail, got %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestPDFPlaceholderIsAJPEGPage(t *testing.T) {
+	data, err := PDFPlaceholder()
+	if err != nil {
+		t.Fatalf("PDFPlaceholder returned error: %v", err)
+	}
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("placeholder is not a jpeg: %v", err)
+	}
+
+	if cfg.Height != MaxSize || cfg.Width >= cfg.Height {
+		t.Fatalf("expected a portrait page %d high, got %dx%d", MaxSize, cfg.Width, cfg.Height)
+	}
+}
@@ -0,0 +1,168 @@
+// Package vault reads the markdown files of note vaults (Obsidian and alike):
+// the YAML front matter holding their title and tags, and the media they embed.
+package vault
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	wikiEmbedPattern     = regexp.MustCompile(`!\[\[([^\[\]|#\n]+)(?:[|#][^\[\]\n]*)?\]\]`)
+	markdownEmbedPattern = regexp.MustCompile(`!\[[^\]\n]*\]\(\s*(<[^>\n]+>|[^)\s]+)(?:\s+"[^"\n]*")?\s*\)`)
+	schemePattern        = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// Document is a markdown file split into its front matter and body.
+type Document struct {
+	Title string   // Empty when the front matter has none
+	Tags  []string // Without the leading '#', in order and without duplicates
+	Body  string   // Content after the front matter
+}
+
+// Parse splits the front matter off 'content'. Only the `title` and `tags` (or
+// `tag`) keys are read, as a plain value, an inline [list] or a block of "- item"
+// lines. Files whose front matter is never closed are taken as having none.
+func Parse(content string) *Document {
+	doc := &Document{Body: content}
+
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) == 0 || trimLine(lines[0]) != "---" {
+		return doc
+	}
+
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if line := trimLine(lines[i]); line == "---" || line == "..." {
+			end = i
+			break
+		}
+	}
+
+	if end < 0 {
+		return doc
+	}
+
+	doc.Body = strings.Join(lines[end+1:], "")
+
+	var tags []string
+	listKey := ""
+	for _, raw := range lines[1:end] {
+		line := trimLine(raw)
+		if item, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok && listKey != "" {
+			if listKey == "tags" {
+				tags = append(tags, item)
+			}
+			continue
+		}
+
+		listKey = ""
+		if raw[0] == ' ' || raw[0] == '\t' {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "title":
+			doc.Title = unquote(value)
+		case "tags", "tag":
+			if value == "" {
+				listKey = "tags"
+				continue
+			}
+			tags = append(tags, splitTags(value)...)
+		default:
+			listKey = key
+		}
+	}
+
+	doc.Tags = normalizeTags(tags)
+	return doc
+}
+
+// Embeds returns the local files embedded by 'content' in order of appearance,
+// as written and without duplicates: ![[file.png]] and ![alt](path/file.png).
+// Remote images and data URLs are left out.
+func Embeds(content string) []string {
+	type match struct {
+		start int
+		ref   string
+	}
+
+	var matches []match
+	for _, loc := range wikiEmbedPattern.FindAllStringSubmatchIndex(content, -1) {
+		matches = append(matches, match{loc[0], strings.TrimSpace(content[loc[2]:loc[3]])})
+	}
+
+	for _, loc := range markdownEmbedPattern.FindAllStringSubmatchIndex(content, -1) {
+		ref := strings.Trim(content[loc[2]:loc[3]], "<>")
+		if schemePattern.MatchString(ref) || strings.HasPrefix(ref, "//") {
+			continue
+		}
+
+		if unescaped, err := url.PathUnescape(ref); err == nil {
+			ref = unescaped
+		}
+		matches = append(matches, match{loc[0], strings.TrimSpace(ref)})
+	}
+
+	// Both kinds are collected separately, put them back in document order
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].start < matches[j].start
+	})
+
+	var refs []string
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		if m.ref == "" || seen[m.ref] {
+			continue
+		}
+		seen[m.ref] = true
+		refs = append(refs, m.ref)
+	}
+	return refs
+}
+
+func trimLine(line string) string {
+	return strings.TrimRight(line, " \t\r\n")
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '"' || first == '\'') && first == last {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
+
+// splitTags reads an inline value: "[a, b]", "a, b" or "a b".
+func splitTags(value string) []string {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+func normalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(unquote(strings.TrimSpace(tag))), "#")
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		result = append(result, tag)
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReadsTitleAndTags(t *testing.T) {
+	content := "---\n" +
+		"title: \"Weekly review\"\n" +
+		"aliases:\n" +
+		"  - review\n" +
+		"tags:\n" +
+		"  - planning\n" +
+		"  - \"#Work\"\n" +
+		"  - work\n" +
+		"---\n" +
+		"# Week 12\n"
+
+	doc := Parse(content)
+	if doc.Title != "Weekly review" || doc.Body != "# Week 12\n" {
+		t.Fatalf("unexpected document: %#v", doc)
+	}
+	if want := []string{"planning", "Work"}; !reflect.DeepEqual(doc.Tags, want) {
+		t.Fatalf("expected tags %v, got %v", want, doc.Tags)
+	}
+}
+
+func TestParseReadsInlineTags(t *testing.T) {
+	for _, value := range []string{"[ops, \"release\"]", "ops, release", "#ops #release"} {
+		doc := Parse("---\r\ntags: " + value + "\r\n---\r\nbody")
+		if want := []string{"ops", "release"}; !reflect.DeepEqual(doc.Tags, want) || doc.Body != "body" {
+			t.Fatalf("%q: expected tags %v, got %#v", value, want, doc)
+		}
+	}
+}
+
+func TestParseKeepsContentWithoutFrontMatter(t *testing.T) {
+	for _, content := range []string{"# Title\n---\ntitle: no\n---\n", "---\ntitle: never closed\n"} {
+		doc := Parse(content)
+		if doc.Title != "" || doc.Tags != nil || doc.Body != content {
+			t.Fatalf("expected %q to have no front matter, got %#v", content, doc)
+		}
+	}
+}
+
+func TestEmbedsListsLocalFilesInOrder(t *testing.T) {
+	content := "![[diagram.png|300]] then ![Photo](assets/my%20photo.jpg \"Beach\")\n" +
+		"![remote](https://example.com/a.png) ![inline](data:image/png;base64,AAA)\n" +
+		"[[Not an embed]] ![[diagram.png]] ![alt](<docs/spec sheet.pdf>) ![[Other note#Section]]"
+
+	want := []string{"diagram.png", "assets/my photo.jpg", "docs/spec sheet.pdf", "Other note"}
+	if got := Embeds(content); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %#v, got %#v", want, got)
+	}
+}